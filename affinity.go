@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"hash/crc32"
+)
+
+// affinityTable hashes keys for --key-affinity. It differs from the IEEE
+// table used by --shard so that sharded exports still spread their keys over
+// every worker.
+var affinityTable = crc32.MakeTable(crc32.Castagnoli)
+
+// workerIndex returns the worker a key is routed to with --key-affinity.
+func workerIndex(key string, workers int) int {
+	return int(crc32.Checksum([]byte(key), affinityTable) % uint32(workers))
+}
+
+// routeKeys sends each key from keysChan to the worker channel chosen by
+// workerIndex, so a key, and the shard connection it hashes to, is always
+// handled by the same worker. The worker channels are closed once keysChan
+// is drained or ctx is done.
+func routeKeys(ctx context.Context, keysChan <-chan keyTask, workerChans []chan keyTask) {
+	defer func() {
+		for _, ch := range workerChans {
+			close(ch)
+		}
+	}()
+
+	for task := range keysChan {
+		select {
+		case workerChans[workerIndex(task.key, len(workerChans))] <- task:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerIndex(t *testing.T) {
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		index := workerIndex(key, 8)
+		require.GreaterOrEqual(t, index, 0)
+		require.Less(t, index, 8)
+		assert.Equal(t, index, workerIndex(key, 8), "a key must always map to the same worker")
+		seen[index] = true
+	}
+	assert.Len(t, seen, 8, "keys should spread over every worker")
+}
+
+func TestWorkerIndex_IndependentOfShard(t *testing.T) {
+	// Keys of one --shard share their IEEE hash modulo the shard count, which
+	// must not leave workers idle.
+	exporter := &Exporter{config: Config{Shard: 1, ShardCount: 2}}
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user:%d", i)
+		if exporter.keyInScope(key) {
+			seen[workerIndex(key, 4)] = true
+		}
+	}
+	assert.Len(t, seen, 4)
+}
+
+func TestRouteKeys(t *testing.T) {
+	keysChan := make(chan keyTask)
+	workerChans := make([]chan keyTask, 4)
+	for i := range workerChans {
+		workerChans[i] = make(chan keyTask, 100)
+	}
+
+	go func() {
+		for round := 0; round < 3; round++ {
+			for i := 0; i < 20; i++ {
+				keysChan <- keyTask{key: fmt.Sprintf("key:%d", i)}
+			}
+		}
+		close(keysChan)
+	}()
+	routeKeys(context.Background(), keysChan, workerChans)
+
+	routed := make(map[string]int)
+	total := 0
+	for index, ch := range workerChans {
+		for task := range ch {
+			total++
+			if previous, ok := routed[task.key]; ok {
+				assert.Equal(t, previous, index, "key %s routed to two workers", task.key)
+			}
+			routed[task.key] = index
+			assert.Equal(t, workerIndex(task.key, len(workerChans)), index)
+		}
+	}
+	assert.Equal(t, 60, total)
+	assert.Len(t, routed, 20)
+}
+
+func TestRouteKeys_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	keysChan := make(chan keyTask, 2)
+	workerChans := []chan keyTask{make(chan keyTask)}
+	keysChan <- keyTask{key: "a"}
+
+	done := make(chan struct{})
+	go func() {
+		routeKeys(ctx, keysChan, workerChans)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("routeKeys did not return after cancellation")
+	}
+	_, ok := <-workerChans[0]
+	assert.False(t, ok, "worker channels are closed")
+}
+
+func TestExporter_Export_KeyAffinity(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	config := Config{
+		OutputFile:  filepath.Join(t.TempDir(), "export.json"),
+		Workers:     3,
+		BatchSize:   10,
+		KeyAffinity: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	mock.ExpectScan(0, "*", int64(10)).SetVal(keys, 0)
+	for _, key := range keys {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("value-" + key)
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	exported := make(map[string]interface{})
+	for _, entry := range entries {
+		exported[entry.Key] = entry.Value
+	}
+	assert.Len(t, exported, len(keys))
+	for _, key := range keys {
+		assert.Equal(t, "value-"+key, exported[key])
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// validateArchive checks that --archive has local files to bundle.
+func validateArchive(config Config) error {
+	if config.Archive == "" {
+		return nil
+	}
+	if config.OutputFile == stdoutOutput || config.GCSBucket != "" || config.AzureContainer != "" {
+		return errors.New("--archive requires a local output file")
+	}
+	if config.Watch > 0 {
+		return errors.New("--archive cannot be used with --watch")
+	}
+	return nil
+}
+
+// archiveFiles returns the files the export wrote: the output, the outputs
+// of any extra formats, and the values file, manifest, errors file and
+// statistics next to it. Side files that weren't written are left out.
+func (e *Exporter) archiveFiles() []string {
+	files := []string{e.config.OutputFile}
+	for _, format := range e.config.ExtraFormats {
+		files = append(files, formatPath(e.config.OutputFile, format))
+	}
+
+	candidates := []string{manifestPath(e.config.OutputFile), e.config.ErrorsFile, e.config.StatsJSON}
+	if e.config.DedupeValues {
+		candidates = append(candidates, valuesFilePath(e.config.OutputFile))
+	}
+	for _, path := range candidates {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// writeArchive bundles the files of a completed export into the --archive
+// zip, each streamed into an entry named after the file. Once the archive
+// is complete the output and the files named after it are removed, so the
+// archive takes their place; the --errors-file and --stats-json the user
+// named are copied but kept. A failed archive is removed and the files are
+// kept.
+func (e *Exporter) writeArchive() error {
+	files := e.archiveFiles()
+
+	names := make(map[string]string, len(files))
+	for _, path := range files {
+		if filepath.Clean(path) == filepath.Clean(e.config.Archive) {
+			return fmt.Errorf("--archive would overwrite %s", path)
+		}
+		name := filepath.Base(path)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("cannot archive both %s and %s as %s", other, path, name)
+		}
+		names[name] = path
+	}
+
+	out, err := createFile(e.config.Archive, e.config.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	err = writeZip(out, files)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(e.config.Archive)
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	for _, path := range files {
+		if path == e.config.ErrorsFile || path == e.config.StatsJSON {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			logrus.WithError(err).WithField("file", path).Warn("Failed to remove archived file")
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"archive": e.config.Archive,
+		"files":   len(files),
+	}).Info("Wrote export archive")
+	return nil
+}
+
+// writeZip writes a zip archive of files to w.
+func writeZip(w io.Writer, files []string) error {
+	zw := zip.NewWriter(w)
+	for _, path := range files {
+		if err := addZipEntry(zw, path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// addZipEntry copies the file at path into a compressed entry of zw named
+// after it.
+func addZipEntry(zw *zip.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Method = zip.Deflate
+
+	entry, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateArchive(t *testing.T) {
+	assert.NoError(t, validateArchive(Config{OutputFile: stdoutOutput}))
+	assert.NoError(t, validateArchive(Config{OutputFile: "export.json", Archive: "backup.zip"}))
+	assert.Error(t, validateArchive(Config{OutputFile: stdoutOutput, Archive: "backup.zip"}))
+	assert.Error(t, validateArchive(Config{GCSBucket: "b", GCSObject: "o", Archive: "backup.zip"}))
+	assert.Error(t, validateArchive(Config{OutputFile: "export.json", Archive: "backup.zip", Watch: time.Minute}))
+}
+
+func TestExporter_Export_Archive(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	dir := t.TempDir()
+	config := Config{
+		OutputFile: filepath.Join(dir, "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Manifest:   true,
+		ErrorsFile: filepath.Join(dir, "errors.json"),
+		Archive:    filepath.Join(dir, "backup.zip"),
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"k"}, 0)
+	mock.ExpectType("k").SetVal("string")
+	mock.ExpectGet("k").SetVal("v")
+	mock.ExpectTTL("k").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	archive, err := zip.OpenReader(config.Archive)
+	require.NoError(t, err)
+	defer func() { _ = archive.Close() }()
+
+	contents := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		_ = r.Close()
+		contents[file.Name] = data
+	}
+	require.Len(t, contents, 3)
+
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(contents["export.json"], &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "k", entries[0].Key)
+	assert.Equal(t, "v", entries[0].Value)
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(contents["export.manifest.json"], &manifest))
+	assert.Equal(t, int64(1), manifest.Keys)
+
+	var failed []FailedKey
+	require.NoError(t, json.Unmarshal(contents["errors.json"], &failed))
+	assert.Empty(t, failed)
+
+	for _, path := range []string{config.OutputFile, manifestPath(config.OutputFile)} {
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err), "%s is removed once archived", path)
+	}
+	_, err = os.Stat(config.ErrorsFile)
+	assert.NoError(t, err, "the errors file named by the user is kept")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// autoTuneInterval is how often --auto-workers re-evaluates the pool size.
+var autoTuneInterval = 5 * time.Second
+
+// workerTuner decides the worker count for --auto-workers from the
+// throughput and per-key latency observed over each interval. It grows the
+// pool while throughput keeps improving and shrinks it once latency rises
+// well above the best seen, which means Redis is saturated.
+type workerTuner struct {
+	min, max    int
+	lastRate    float64
+	baseLatency time.Duration
+}
+
+func newWorkerTuner(min, max int) *workerTuner {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &workerTuner{min: min, max: max}
+}
+
+// next returns the worker count to use given the current count, the keys
+// per second and the average per-key latency since the last call.
+func (t *workerTuner) next(workers int, rate float64, latency time.Duration) int {
+	defer func() { t.lastRate = rate }()
+
+	if latency <= 0 {
+		return t.clamp(workers)
+	}
+	if t.baseLatency == 0 || latency < t.baseLatency {
+		t.baseLatency = latency
+	}
+
+	switch {
+	case latency > 2*t.baseLatency:
+		step := workers / 4
+		if step < 1 {
+			step = 1
+		}
+		return t.clamp(workers - step)
+	case rate > t.lastRate*1.05:
+		return t.clamp(workers * 2)
+	default:
+		return t.clamp(workers)
+	}
+}
+
+func (t *workerTuner) clamp(workers int) int {
+	if workers < t.min {
+		return t.min
+	}
+	if workers > t.max {
+		return t.max
+	}
+	return workers
+}
+
+// latencyStats accumulates per-key processing time between tuning rounds.
+type latencyStats struct {
+	total atomic.Int64
+	count atomic.Int64
+}
+
+func (l *latencyStats) observe(d time.Duration) {
+	l.total.Add(int64(d))
+	l.count.Add(1)
+}
+
+// reset returns the average latency since the previous reset.
+func (l *latencyStats) reset() time.Duration {
+	total := l.total.Swap(0)
+	count := l.count.Swap(0)
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(total / count)
+}
+
+// workerPool runs a resizable set of workers. It holds a reference on wg
+// until seal is called, so the pool can grow without racing wg.Wait while
+// keys are still being produced.
+type workerPool struct {
+	e           *Exporter
+	ctx         context.Context
+	keysChan    <-chan keyTask
+	resultsChan chan<- *RedisEntry
+	wg          *sync.WaitGroup
+
+	mu     sync.Mutex
+	stops  []chan struct{}
+	sealed bool
+}
+
+func newWorkerPool(ctx context.Context, e *Exporter, keysChan <-chan keyTask, resultsChan chan<- *RedisEntry, wg *sync.WaitGroup) *workerPool {
+	wg.Add(1)
+	return &workerPool{
+		e:           e,
+		ctx:         ctx,
+		keysChan:    keysChan,
+		resultsChan: resultsChan,
+		wg:          wg,
+	}
+}
+
+// size returns the number of running workers.
+func (p *workerPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.stops)
+}
+
+// resize starts or retires workers until n are running. Retired workers
+// finish the key they are processing before exiting.
+func (p *workerPool) resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sealed {
+		return
+	}
+
+	for len(p.stops) < n {
+		id := len(p.stops)
+		stop := make(chan struct{})
+		p.stops = append(p.stops, stop)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.e.runWorker(p.ctx, id, p.keysChan, p.resultsChan, stop)
+		}()
+	}
+	for len(p.stops) > n {
+		last := len(p.stops) - 1
+		close(p.stops[last])
+		p.stops = p.stops[:last]
+	}
+}
+
+// seal stops further resizing and releases the pool's reference on wg.
+func (p *workerPool) seal() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sealed {
+		return
+	}
+	p.sealed = true
+	p.wg.Done()
+}
+
+// retune resizes pool according to tuner, given the keys per second
+// exported since the previous round.
+func (e *Exporter) retune(pool *workerPool, tuner *workerTuner, rate float64) {
+	latency := e.latency.reset()
+	workers := pool.size()
+	target := tuner.next(workers, rate, latency)
+	if target == workers {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"workers":      target,
+		"keys_per_sec": math.Round(rate),
+		"latency":      latency,
+	}).Debug("Resizing worker pool")
+	pool.resize(target)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerTuner_GrowsWhileThroughputImproves(t *testing.T) {
+	tuner := newWorkerTuner(2, 16)
+
+	workers := 2
+	workers = tuner.next(workers, 1000, time.Millisecond)
+	assert.Equal(t, 4, workers)
+	workers = tuner.next(workers, 2000, time.Millisecond)
+	assert.Equal(t, 8, workers)
+	workers = tuner.next(workers, 4000, time.Millisecond)
+	assert.Equal(t, 16, workers)
+	workers = tuner.next(workers, 8000, time.Millisecond)
+	assert.Equal(t, 16, workers, "never exceeds the maximum")
+
+	workers = tuner.next(workers, 8000, time.Millisecond)
+	assert.Equal(t, 16, workers, "holds when throughput is flat")
+}
+
+func TestWorkerTuner_StopsGrowingAsLatencyRises(t *testing.T) {
+	tuner := newWorkerTuner(2, 64)
+
+	workers := tuner.next(2, 1000, time.Millisecond)
+	assert.Equal(t, 4, workers)
+
+	// Throughput still rises but latency has tripled: Redis is saturated.
+	shrunk := tuner.next(workers, 1500, 3*time.Millisecond)
+	assert.Less(t, shrunk, workers)
+
+	for i := 0; i < 10; i++ {
+		workers = tuner.next(shrunk, 2000+float64(i)*500, time.Duration(4+i)*time.Millisecond)
+		assert.LessOrEqual(t, workers, shrunk, "pool should not grow while latency keeps rising")
+		shrunk = workers
+	}
+	assert.Equal(t, 2, workers, "never drops below the minimum")
+}
+
+func TestWorkerTuner_HoldsWithoutSamples(t *testing.T) {
+	tuner := newWorkerTuner(1, 8)
+	assert.Equal(t, 3, tuner.next(3, 0, 0))
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	exporter := &Exporter{}
+	keysChan := make(chan keyTask)
+	resultsChan := make(chan *RedisEntry)
+
+	var wg sync.WaitGroup
+	pool := newWorkerPool(context.Background(), exporter, keysChan, resultsChan, &wg)
+
+	pool.resize(4)
+	assert.Equal(t, 4, pool.size())
+	pool.resize(1)
+	assert.Equal(t, 1, pool.size())
+
+	pool.seal()
+	pool.resize(8)
+	assert.Equal(t, 1, pool.size(), "a sealed pool does not grow")
+
+	close(keysChan)
+	wg.Wait()
+}
+
+func TestExporter_Export_AutoWorkers(t *testing.T) {
+	previous := autoTuneInterval
+	autoTuneInterval = 5 * time.Millisecond
+	t.Cleanup(func() { autoTuneInterval = previous })
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	config := Config{
+		OutputFile:  "test_export_auto_workers.json",
+		Workers:     4,
+		MinWorkers:  1,
+		AutoWorkers: true,
+		BatchSize:   10,
+		Quiet:       true,
+	}
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	keys := []string{"key1", "key2", "key3", "key4", "key5"}
+	mock.ExpectScan(0, "*", int64(10)).SetVal(keys, 0)
+	for _, key := range keys {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("value")
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	assert.Len(t, entries, len(keys))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
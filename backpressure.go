@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// bufferSampleInterval is how often the results buffer fill level is sampled.
+var bufferSampleInterval = time.Second
+
+// bufferWarnSamples is the number of consecutive samples the results buffer
+// must stay nearly full or nearly empty before a warning is logged.
+var bufferWarnSamples = 10
+
+// bufferMonitor watches the fill level of the channel between the workers
+// and the output writer. A buffer that stays nearly full means the writer
+// can't keep up; one that stays nearly empty means the workers, and so
+// Redis, are the bottleneck. Each condition is reported once per export.
+type bufferMonitor struct {
+	full, empty             int // consecutive samples in each state
+	warnedFull, warnedEmpty bool
+}
+
+// sample records the buffer's current length, logging once a state has
+// lasted bufferWarnSamples samples: a warning for a full buffer, and a debug
+// message for an empty one.
+func (m *bufferMonitor) sample(length, capacity int) {
+	if capacity == 0 {
+		return
+	}
+	fill := float64(length) / float64(capacity)
+
+	if fill >= 0.9 {
+		m.full++
+	} else {
+		m.full = 0
+	}
+	if fill <= 0.1 {
+		m.empty++
+	} else {
+		m.empty = 0
+	}
+
+	fields := logrus.Fields{
+		"buffered": length,
+		"capacity": capacity,
+	}
+	if m.full >= bufferWarnSamples && !m.warnedFull {
+		m.warnedFull = true
+		logrus.WithFields(fields).Warn("Results buffer is nearly full, the output writer is the bottleneck; more workers won't help")
+	}
+	if m.empty >= bufferWarnSamples && !m.warnedEmpty {
+		m.warnedEmpty = true
+		// Normal whenever Redis is slower than the writer, so not a warning
+		logrus.WithFields(fields).Debug("Results buffer is nearly empty, Redis reads are the bottleneck; more workers may help")
+	}
+}
+
+// scanPauseFill is the results buffer fill level at which the scan pauses.
+var scanPauseFill = 0.9
+
+// scanPauseWait is how often a paused scan checks the results buffer again.
+var scanPauseWait = 10 * time.Millisecond
+
+// paceScan holds back the next SCAN while the results buffer is nearly
+// full, which means the output writer is the bottleneck, so the scan and
+// workers don't read ahead of what can be written and hold the values in
+// memory meanwhile. It returns false if ctx is done while paused.
+func (e *Exporter) paceScan(ctx context.Context) bool {
+	results := e.results
+	if results == nil || cap(results) == 0 {
+		return true
+	}
+	paused := false
+	for float64(len(results)) >= scanPauseFill*float64(cap(results)) {
+		if !paused {
+			paused = true
+			e.scanPauses.Add(1)
+		}
+		select {
+		case <-time.After(scanPauseWait):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
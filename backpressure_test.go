@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bufferWarnings(hook *test.Hook) []string {
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			messages = append(messages, entry.Message)
+		}
+	}
+	return messages
+}
+
+func TestBufferMonitor(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	orig := bufferWarnSamples
+	bufferWarnSamples = 3
+	defer func() { bufferWarnSamples = orig }()
+
+	var m bufferMonitor
+
+	// A half-full buffer is healthy, and a short spike doesn't count
+	m.sample(50, 100)
+	m.sample(95, 100)
+	m.sample(95, 100)
+	m.sample(50, 100)
+	assert.Empty(t, bufferWarnings(hook))
+
+	for i := 0; i < 5; i++ {
+		m.sample(100, 100)
+	}
+	warnings := bufferWarnings(hook)
+	require.Len(t, warnings, 1, "each condition is reported once")
+	assert.Contains(t, warnings[0], "nearly full")
+
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetLevel(level)
+	for i := 0; i < 3; i++ {
+		m.sample(0, 100)
+	}
+	assert.Len(t, bufferWarnings(hook), 1, "an empty buffer is normal when Redis is the bottleneck")
+	assert.Equal(t, logrus.DebugLevel, hook.LastEntry().Level)
+	assert.Contains(t, hook.LastEntry().Message, "nearly empty")
+}
+
+// slowWriter sleeps before every write, simulating a congested destination.
+type slowWriter struct {
+	delay time.Duration
+}
+
+func (w slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return len(p), nil
+}
+
+func TestExporter_Export_WarnsOnSlowWriter(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	origInterval, origSamples := bufferSampleInterval, bufferWarnSamples
+	bufferSampleInterval, bufferWarnSamples = time.Millisecond, 5
+	defer func() { bufferSampleInterval, bufferWarnSamples = origInterval, origSamples }()
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{Workers: 1, BatchSize: 2, Quiet: true},
+		output: slowWriter{delay: 2 * time.Millisecond},
+	}
+
+	keys := make([]string, 40)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	mock.ExpectScan(0, "*", int64(2)).SetVal(keys, 0)
+	for _, key := range keys {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("value")
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.Contains(t, bufferWarnings(hook), "Results buffer is nearly full, the output writer is the bottleneck; more workers won't help")
+}
+
+func TestExporter_PaceScan(t *testing.T) {
+	orig := scanPauseWait
+	scanPauseWait = time.Millisecond
+	defer func() { scanPauseWait = orig }()
+
+	results := make(chan *RedisEntry, 10)
+	for i := 0; i < cap(results); i++ {
+		results <- &RedisEntry{}
+	}
+	exporter := &Exporter{results: results}
+
+	// A slow writer takes an entry every 5ms
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			<-results
+		}
+	}()
+
+	start := time.Now()
+	require.True(t, exporter.paceScan(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond, "the scan waits for the writer")
+	assert.LessOrEqual(t, len(results), 8, "the scan resumes once the buffer is below 90%")
+	assert.Equal(t, int64(1), exporter.scanPauses.Load())
+	<-done
+
+	// A buffer with room doesn't hold the scan back
+	require.True(t, exporter.paceScan(context.Background()))
+	assert.Equal(t, int64(1), exporter.scanPauses.Load())
+
+	for len(results) < cap(results) {
+		results <- &RedisEntry{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, exporter.paceScan(ctx), "a paused scan stops when the export is cancelled")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus"
+)
+
+// dictSamples is the number of values --compress-dict trains its dictionary
+// on. They are held back until the dictionary is trained, then written.
+var dictSamples = 1000
+
+// maxDictBytes caps the size of the trained dictionary.
+const maxDictBytes = 64 << 10
+
+// validateCompressDict checks that --compress-dict is used with plain JSON
+// output to a local file, next to which the manifest holding the dictionary
+// is written.
+func validateCompressDict(config Config) error {
+	if !config.CompressDict {
+		return nil
+	}
+	if config.Format != "" && config.Format != "json" || len(config.ExtraFormats) > 0 {
+		return errors.New("--compress-dict requires json output")
+	}
+	if config.OutputFile == stdoutOutput || config.GCSBucket != "" || config.AzureContainer != "" {
+		return errors.New("--compress-dict requires a local output file")
+	}
+	if config.DedupeValues {
+		return errors.New("--compress-dict can't be combined with --dedupe-values")
+	}
+	return nil
+}
+
+// dictCompressor replaces each entry's value with its JSON encoding
+// compressed by zstd against a dictionary trained on the first values of the
+// export. Small values share most of their structure, which a per-value
+// compressor can't exploit but a shared dictionary can.
+type dictCompressor struct {
+	samples int
+	pending []*RedisEntry
+	trained bool
+	dict    []byte
+	encoder *zstd.Encoder
+}
+
+func newDictCompressor(samples int) *dictCompressor {
+	return &dictCompressor{samples: samples}
+}
+
+// add returns the entries that are ready to be written. Entries are held
+// back until enough values were seen to train the dictionary.
+func (c *dictCompressor) add(entry *RedisEntry) []*RedisEntry {
+	if c.trained {
+		c.compress(entry)
+		return []*RedisEntry{entry}
+	}
+	c.pending = append(c.pending, entry)
+	if len(c.pending) < c.samples {
+		return nil
+	}
+	return c.flush()
+}
+
+// flush trains the dictionary on the values seen so far, if that hasn't
+// happened yet, and returns the held back entries.
+func (c *dictCompressor) flush() []*RedisEntry {
+	if !c.trained {
+		c.train()
+	}
+	ready := c.pending
+	c.pending = nil
+	for _, entry := range ready {
+		c.compress(entry)
+	}
+	return ready
+}
+
+// train builds the dictionary from the pending values. When there isn't
+// enough data to train on, the export is written uncompressed.
+func (c *dictCompressor) train() {
+	c.trained = true
+
+	samples := make([][]byte, 0, len(c.pending))
+	for _, entry := range c.pending {
+		if entry.Value == nil {
+			continue
+		}
+		data, err := json.Marshal(entry.Value)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, data)
+	}
+
+	trained, err := buildDict(samples)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to train the compression dictionary, writing values uncompressed")
+		return
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderDict(trained))
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load the compression dictionary, writing values uncompressed")
+		return
+	}
+	c.dict, c.encoder = trained, encoder
+	logrus.WithFields(logrus.Fields{
+		"samples":    len(samples),
+		"dict_bytes": len(trained),
+	}).Info("Trained compression dictionary")
+}
+
+// buildDict trains a zstd dictionary on samples. The trainer panics on some
+// degenerate inputs, which is turned into an error.
+func buildDict(samples [][]byte) (trained []byte, err error) {
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("need at least 2 values to train on, have %d", len(samples))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("dictionary training failed: %v", r)
+		}
+	}()
+	return dict.BuildZstdDict(samples, dict.Options{MaxDictSize: maxDictBytes, HashBytes: 6})
+}
+
+// compress moves entry's value into ValueZstd. Entries are left as they are
+// when no dictionary was trained.
+func (c *dictCompressor) compress(entry *RedisEntry) {
+	if c.encoder == nil || entry.Value == nil {
+		return
+	}
+	data, err := json.Marshal(entry.Value)
+	if err != nil {
+		logrus.WithField("key", entry.Key).Warn("Error compressing value, writing it uncompressed: ", err)
+		return
+	}
+	entry.ValueZstd = c.encoder.EncodeAll(data, nil)
+	entry.Value = nil
+}
+
+// newDictDecoder returns a decoder for values compressed against trained.
+func newDictDecoder(trained []byte) (*zstd.Decoder, error) {
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderDicts(trained))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load compression dictionary: %w", err)
+	}
+	return decoder, nil
+}
+
+// decompressValue replaces entry's ValueZstd with the value it decompresses
+// to.
+func decompressValue(decoder *zstd.Decoder, entry *RedisEntry) error {
+	if decoder == nil {
+		return errors.New("entry value is compressed but the manifest has no dictionary")
+	}
+	raw, err := decoder.DecodeAll(entry.ValueZstd, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decompress value: %w", err)
+	}
+	value, err := decodeValue(raw, entry.Type)
+	if err != nil {
+		return err
+	}
+	entry.Value, entry.ValueZstd = value, nil
+	return nil
+}
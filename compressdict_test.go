@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sessionEntries returns small string values that share most of their
+// structure, the case --compress-dict is meant for.
+func sessionEntries(n int) []*RedisEntry {
+	entries := make([]*RedisEntry, n)
+	for i := range entries {
+		entries[i] = &RedisEntry{
+			Key:   fmt.Sprintf("session:%d", i),
+			Type:  "string",
+			Value: fmt.Sprintf(`{"user_id":%d,"name":"user-%d","roles":["reader","writer"],"active":true}`, i, i),
+		}
+	}
+	return entries
+}
+
+func TestValidateCompressDict(t *testing.T) {
+	assert.NoError(t, validateCompressDict(Config{OutputFile: "export.json"}))
+	assert.NoError(t, validateCompressDict(Config{OutputFile: "export.json", CompressDict: true}))
+	assert.Error(t, validateCompressDict(Config{OutputFile: stdoutOutput, CompressDict: true}))
+	assert.Error(t, validateCompressDict(Config{OutputFile: "export.csv", Format: "csv", CompressDict: true}))
+	assert.Error(t, validateCompressDict(Config{OutputFile: "export.json", ExtraFormats: []string{"csv"}, CompressDict: true}))
+	assert.Error(t, validateCompressDict(Config{OutputFile: "export.json", DedupeValues: true, CompressDict: true}))
+}
+
+func TestDictCompressor_RoundTrip(t *testing.T) {
+	compressor := newDictCompressor(100)
+
+	var written []*RedisEntry
+	for _, entry := range sessionEntries(300) {
+		written = append(written, compressor.add(entry)...)
+	}
+	assert.Len(t, written, 300, "entries after the sample are written as they arrive")
+	written = append(written, compressor.flush()...)
+	require.Len(t, written, 300)
+	require.NotEmpty(t, compressor.dict)
+
+	decoder, err := newDictDecoder(compressor.dict)
+	require.NoError(t, err)
+	defer decoder.Close()
+
+	for i, entry := range sessionEntries(300) {
+		got := written[i]
+		require.Nil(t, got.Value)
+		require.NotEmpty(t, got.ValueZstd)
+		require.NoError(t, decompressValue(decoder, got))
+		assert.Equal(t, entry.Value, got.Value)
+		assert.Nil(t, got.ValueZstd)
+	}
+}
+
+func TestDictCompressor_HoldsBackSample(t *testing.T) {
+	compressor := newDictCompressor(10)
+	entries := sessionEntries(10)
+
+	for _, entry := range entries[:9] {
+		assert.Empty(t, compressor.add(entry))
+	}
+	ready := compressor.add(entries[9])
+	assert.Len(t, ready, 10, "the full sample is released once the dictionary is trained")
+}
+
+func TestDictCompressor_TooFewValues(t *testing.T) {
+	compressor := newDictCompressor(100)
+	entry := &RedisEntry{Key: "k", Type: "string", Value: "v"}
+
+	assert.Empty(t, compressor.add(entry))
+	ready := compressor.flush()
+	require.Len(t, ready, 1)
+	assert.Nil(t, compressor.dict)
+	assert.Equal(t, "v", ready[0].Value, "values are written uncompressed without a dictionary")
+	assert.Nil(t, ready[0].ValueZstd)
+}
+
+func TestExporter_Export_CompressDict(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:   filepath.Join(t.TempDir(), "export.json"),
+		Workers:      1,
+		BatchSize:    100,
+		CompressDict: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	entries := sessionEntries(40)
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	mock.ExpectScan(0, "*", int64(100)).SetVal(keys, 0)
+	for _, entry := range entries {
+		mock.ExpectType(entry.Key).SetVal("string")
+		mock.ExpectGet(entry.Key).SetVal(entry.Value.(string))
+		mock.ExpectTTL(entry.Key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	manifest, err := readManifest(manifestPath(config.OutputFile))
+	require.NoError(t, err, "--compress-dict writes the manifest without --manifest")
+	require.NotEmpty(t, manifest.Dictionary)
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var written []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &written))
+	require.Len(t, written, len(entries))
+
+	decoder, err := newDictDecoder(manifest.Dictionary)
+	require.NoError(t, err)
+	defer decoder.Close()
+	for i := range written {
+		require.NoError(t, decompressValue(decoder, &written[i]))
+		assert.Equal(t, entries[i].Value, written[i].Value)
+	}
+}
+
+func TestImporter_Import_CompressDict(t *testing.T) {
+	compressor := newDictCompressor(50)
+	var entries []*RedisEntry
+	for _, entry := range sessionEntries(50) {
+		entries = append(entries, compressor.add(entry)...)
+	}
+	require.NotEmpty(t, compressor.dict)
+
+	data, err := json.Marshal(entries)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"value":`)
+
+	decoder, err := newDictDecoder(compressor.dict)
+	require.NoError(t, err)
+	defer decoder.Close()
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 100}, dict: decoder}
+
+	for _, entry := range sessionEntries(50) {
+		mock.ExpectSet(entry.Key, entry.Value, 0).SetVal("OK")
+	}
+
+	result, err := importer.Import(context.Background(), strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, 50, result.Imported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_CompressDictWithoutManifest(t *testing.T) {
+	db, _ := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	result, err := importer.Import(context.Background(), strings.NewReader(`[{"key":"k","type":"string","value_zstd":"KLUv/QBYAQAAdg=="}]`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// defaultDatabases is the number of logical databases Redis has unless its
+// databases setting says otherwise.
+const defaultDatabases = 16
+
+var (
+	databasesConfig       Config
+	databasesPasswordFile string
+	databasesJSON         bool
+)
+
+// DatabaseSize is the key count of one logical database.
+type DatabaseSize struct {
+	DB   int   `json:"db"`
+	Keys int64 `json:"keys"`
+}
+
+// databaseCount returns the number of logical databases from CONFIG GET
+// databases. Managed services often disable CONFIG, in which case the Redis
+// default is assumed.
+func (e *Exporter) databaseCount(ctx context.Context) int {
+	values, err := e.client.ConfigGet(ctx, "databases").Result()
+	if err == nil {
+		if n, err := strconv.Atoi(values["databases"]); err == nil && n > 0 {
+			return n
+		}
+	}
+	logrus.WithError(err).Debugf("Failed to read the number of databases, assuming %d", defaultDatabases)
+	return defaultDatabases
+}
+
+// databaseSizes returns the key count of every logical database, from a
+// SELECT and DBSIZE per database pipelined on a connection of its own, so
+// the pool's connections stay on --db. Databases past the first that can't
+// be selected, such as on a cluster or beyond an assumed count, end the list.
+func (e *Exporter) databaseSizes(ctx context.Context) ([]DatabaseSize, error) {
+	count := e.databaseCount(ctx)
+
+	conn := e.client.Conn()
+	defer func() { _ = conn.Close() }()
+
+	pipe := conn.Pipeline()
+	selects := make([]*redis.StatusCmd, count)
+	sizes := make([]*redis.IntCmd, count)
+	for db := 0; db < count; db++ {
+		selects[db] = pipe.Select(ctx, db)
+		sizes[db] = pipe.DBSize(ctx)
+	}
+	_, _ = pipe.Exec(ctx)
+
+	var result []DatabaseSize
+	for db := 0; db < count; db++ {
+		if err := selects[db].Err(); err != nil {
+			if db == 0 {
+				return nil, fmt.Errorf("failed to select database 0: %w", err)
+			}
+			logrus.WithError(err).Debugf("Stopping at database %d", db)
+			break
+		}
+		keys, err := sizes[db].Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get size of database %d: %w", db, err)
+		}
+		result = append(result, DatabaseSize{DB: db, Keys: keys})
+	}
+	return result, nil
+}
+
+// writeDatabaseSizes writes sizes to w as a table, or as a JSON array with
+// asJSON.
+func writeDatabaseSizes(w io.Writer, sizes []DatabaseSize, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(sizes)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "DB\tKEYS")
+	for _, size := range sizes {
+		_, _ = fmt.Fprintf(tw, "%d\t%d\n", size.DB, size.Keys)
+	}
+	return tw.Flush()
+}
+
+var databasesCmd = &cobra.Command{
+	Use:   "databases",
+	Short: "List the key count of each logical database",
+	Long:  "Connect to Redis and report the number of keys in each logical database, to find which --db holds the data before exporting.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		databasesConfig.RedisPassword, err = resolvePassword(databasesConfig.RedisPassword, databasesPasswordFile)
+		if err != nil {
+			return err
+		}
+
+		databasesConfig.Workers = 1
+		exporter := NewExporter(databasesConfig)
+		defer func() { _ = exporter.client.Close() }()
+
+		ctx := cmd.Context()
+		if err := exporter.client.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("failed to connect to Redis: %w", err)
+		}
+
+		sizes, err := exporter.databaseSizes(ctx)
+		if err != nil {
+			return err
+		}
+		return writeDatabaseSizes(cmd.OutOrStdout(), sizes, databasesJSON)
+	},
+}
+
+func init() {
+	databasesCmd.Flags().StringVarP(&databasesConfig.RedisAddr, "addr", "a", "localhost:6379", "Redis server address")
+	databasesCmd.Flags().StringVarP(&databasesConfig.RedisPassword, "password", "p", "", "Redis password (prefer REDIS_PASSWORD or --password-file)")
+	databasesCmd.Flags().StringVar(&databasesPasswordFile, "password-file", "", "Read the Redis password from this file")
+	databasesCmd.Flags().BoolVar(&databasesJSON, "json", false, "Write the key counts as a JSON array instead of a table")
+	databasesCmd.Flags().StringVar(&databasesConfig.ClientName, "client-name", "redis-export/"+version, "Connection name shown in CLIENT LIST")
+	rootCmd.AddCommand(databasesCmd)
+}
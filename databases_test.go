@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_DatabaseSizes(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db}
+
+	mock.ExpectConfigGet("databases").SetVal(map[string]string{"databases": "3"})
+	mock.ExpectDo("select", 0).SetVal("OK")
+	mock.ExpectDBSize().SetVal(120)
+	mock.ExpectDo("select", 1).SetVal("OK")
+	mock.ExpectDBSize().SetVal(0)
+	mock.ExpectDo("select", 2).SetVal("OK")
+	mock.ExpectDBSize().SetVal(7)
+
+	sizes, err := exporter.databaseSizes(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []DatabaseSize{{DB: 0, Keys: 120}, {DB: 1, Keys: 0}, {DB: 2, Keys: 7}}, sizes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	var out bytes.Buffer
+	require.NoError(t, writeDatabaseSizes(&out, sizes, false))
+	assert.Equal(t, "DB  KEYS\n0   120\n1   0\n2   7\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeDatabaseSizes(&out, sizes, true))
+	assert.JSONEq(t, `[{"db":0,"keys":120},{"db":1,"keys":0},{"db":2,"keys":7}]`, out.String())
+}
+
+func TestExporter_DatabaseSizes_NoConfig(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db}
+
+	// Without CONFIG the default of 16 is assumed, and a cluster only has
+	// database 0
+	mock.ExpectConfigGet("databases").SetErr(errors.New("ERR unknown command 'CONFIG'"))
+	mock.ExpectDo("select", 0).SetVal("OK")
+	mock.ExpectDBSize().SetVal(42)
+	mock.ExpectDo("select", 1).SetErr(errors.New("ERR SELECT is not allowed in cluster mode"))
+
+	sizes, err := exporter.databaseSizes(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []DatabaseSize{{DB: 0, Keys: 42}}, sizes)
+}
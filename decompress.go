@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressionExts are the file extensions of compressed exports.
+var compressionExts = []string{".gz", ".gzip", ".zst", ".zstd"}
+
+// decompressReader detects gzip or zstd compression from the magic bytes at
+// the start of r and returns a reader of the decompressed data, along with
+// the name of the compression. Anything else is returned as it is, named
+// "none". Close releases the decompressor but not r.
+func decompressReader(r io.Reader) (io.ReadCloser, string, error) {
+	buffered := bufio.NewReader(r)
+	head, err := buffered.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("failed to read export: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open gzip export: %w", err)
+		}
+		return gz, "gzip", nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open zstd export: %w", err)
+		}
+		return zr.IOReadCloser(), "zstd", nil
+	default:
+		return io.NopCloser(buffered), "none", nil
+	}
+}
+
+// trimCompressionExt removes a compression extension from path, so the side
+// files of export.json.gz are found next to it as export.values.json and
+// export.manifest.json.
+func trimCompressionExt(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, compressed := range compressionExts {
+		if ext == compressed {
+			return strings.TrimSuffix(path, filepath.Ext(path))
+		}
+	}
+	return path
+}
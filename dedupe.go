@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sidecarPath returns the path of a JSON file stored next to an export,
+// named by inserting kind before the extension: export.json with kind
+// "values" becomes export.values.json.
+func sidecarPath(output, kind string) string {
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + "." + kind + ".json"
+}
+
+// valuesFilePath returns the side file that holds the unique values of a
+// --dedupe-values export.
+func valuesFilePath(output string) string {
+	return sidecarPath(output, "values")
+}
+
+// validateDedupe checks that --dedupe-values is used with JSON output to a
+// local file, next to which the values file is written.
+func validateDedupe(config Config) error {
+	if !config.DedupeValues {
+		return nil
+	}
+	if config.Format != "" && config.Format != "json" {
+		return errors.New("--dedupe-values requires json output")
+	}
+	if config.OutputFile == stdoutOutput || config.GCSBucket != "" || config.AzureContainer != "" {
+		return errors.New("--dedupe-values requires a local output file")
+	}
+	return nil
+}
+
+// valueTable writes each distinct value once to a JSON object keyed by the
+// hex SHA-256 of the value's JSON encoding.
+type valueTable struct {
+	file  *os.File
+	w     *bufio.Writer
+	seen  map[string]struct{}
+	first bool
+}
+
+func newValueTable(path string, mode os.FileMode) (*valueTable, error) {
+	file, err := createFile(path, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create values file: %w", err)
+	}
+
+	t := &valueTable{
+		file:  file,
+		w:     bufio.NewWriter(file),
+		seen:  make(map[string]struct{}),
+		first: true,
+	}
+	if _, err := t.w.WriteString("{\n"); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// ref returns the content hash of value, adding the value to the table the
+// first time it is seen.
+func (t *valueTable) ref(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if _, ok := t.seen[hash]; ok {
+		return hash, nil
+	}
+	t.seen[hash] = struct{}{}
+
+	if !t.first {
+		if _, err := t.w.WriteString(",\n"); err != nil {
+			return "", err
+		}
+	}
+	t.first = false
+	if _, err := fmt.Fprintf(t.w, "%q: ", hash); err != nil {
+		return "", err
+	}
+	if _, err := t.w.Write(data); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Close terminates the JSON object and closes the file.
+func (t *valueTable) Close() error {
+	if _, err := t.w.WriteString("\n}\n"); err != nil {
+		_ = t.file.Close()
+		return err
+	}
+	if err := t.w.Flush(); err != nil {
+		_ = t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+// readValueTable reads a values file written by --dedupe-values.
+func readValueTable(r io.Reader) (map[string]json.RawMessage, error) {
+	var values map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+	return values, nil
+}
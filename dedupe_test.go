@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuesFilePath(t *testing.T) {
+	assert.Equal(t, "export.values.json", valuesFilePath("export.json"))
+	assert.Equal(t, "/tmp/dump.values.json", valuesFilePath("/tmp/dump"))
+	assert.Equal(t, "dir.d/export.values.json", valuesFilePath("dir.d/export.json"))
+}
+
+func TestValidateDedupe(t *testing.T) {
+	assert.NoError(t, validateDedupe(Config{OutputFile: "export.json"}))
+	assert.NoError(t, validateDedupe(Config{OutputFile: "export.json", Format: "json", DedupeValues: true}))
+	assert.Error(t, validateDedupe(Config{OutputFile: "export.parquet", Format: "parquet", DedupeValues: true}))
+	assert.Error(t, validateDedupe(Config{OutputFile: stdoutOutput, DedupeValues: true}))
+	assert.Error(t, validateDedupe(Config{GCSBucket: "b", GCSObject: "o", DedupeValues: true}))
+}
+
+func TestExporter_Export_DedupeValues(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:   filepath.Join(t.TempDir(), "export.json"),
+		Workers:      1,
+		BatchSize:    10,
+		DedupeValues: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	blob := `{"theme":"dark","lang":"en"}`
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"user:1", "user:2", "user:3"}, 0)
+	mock.ExpectType("user:1").SetVal("string")
+	mock.ExpectGet("user:1").SetVal(blob)
+	mock.ExpectTTL("user:1").SetVal(-1 * time.Second)
+	mock.ExpectType("user:2").SetVal("string")
+	mock.ExpectGet("user:2").SetVal(blob)
+	mock.ExpectTTL("user:2").SetVal(30 * time.Second)
+	mock.ExpectType("user:3").SetVal("list")
+	mock.ExpectLRange("user:3", 0, -1).SetVal([]string{"a"})
+	mock.ExpectTTL("user:3").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 3)
+
+	encoded, _ := json.Marshal(blob)
+	sum := sha256.Sum256(encoded)
+	blobRef := hex.EncodeToString(sum[:])
+
+	for _, entry := range entries {
+		assert.Nil(t, entry.Value, entry.Key)
+	}
+	assert.Equal(t, blobRef, entries[0].ValueRef)
+	assert.Equal(t, blobRef, entries[1].ValueRef, "duplicate values share a reference")
+	assert.NotEqual(t, blobRef, entries[2].ValueRef)
+
+	valuesData, err := os.ReadFile(valuesFilePath(config.OutputFile))
+	require.NoError(t, err)
+	values, err := readValueTable(bytes.NewReader(valuesData))
+	require.NoError(t, err)
+	assert.Len(t, values, 2, "each distinct value is stored once")
+	assert.JSONEq(t, string(encoded), string(values[blobRef]))
+	assert.JSONEq(t, `["a"]`, string(values[entries[2].ValueRef]))
+
+	// Import resolves the references back to values
+	importDB, importMock := redismock.NewClientMock()
+	defer func() { _ = importDB.Close() }()
+
+	importer := &Importer{client: importDB, config: ImportConfig{BatchSize: 10}, values: values}
+	importMock.ExpectSet("user:1", blob, 0).SetVal("OK")
+	importMock.ExpectSet("user:2", blob, 0).SetVal("OK")
+	importMock.ExpectExpire("user:2", 30*time.Second).SetVal(true)
+	importMock.ExpectDel("user:3").SetVal(0)
+	importMock.ExpectRPush("user:3", "a").SetVal(1)
+
+	result, err := importer.Import(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+	assert.NoError(t, importMock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_MissingValuesFile(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	result, err := importer.Import(context.Background(), bytes.NewReader([]byte(`[{"key":"a","type":"string","value_ref":"abc"}]`)))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed, "a reference without a values file can't be restored")
+	assert.Zero(t, result.Skipped)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// DiffResult lists the differences between two exports. Keys in each list
+// are sorted.
+type DiffResult struct {
+	Added       []string     `json:"added"`
+	Removed     []string     `json:"removed"`
+	Changed     []ChangedKey `json:"changed"`
+	TypeChanged []TypeChange `json:"type_changed"`
+}
+
+// ChangedKey is a key present in both exports with the same type whose value
+// or TTL differs. Fields names what changed ("value", "ttl").
+type ChangedKey struct {
+	Key    string   `json:"key"`
+	Fields []string `json:"fields"`
+}
+
+// TypeChange is a key whose type differs between the two exports.
+type TypeChange struct {
+	Key     string `json:"key"`
+	OldType string `json:"old_type"`
+	NewType string `json:"new_type"`
+}
+
+// readExportEntries decodes the JSON export in r, an array or one object
+// per line, into a map keyed by key name.
+func readExportEntries(r io.Reader) (map[string]*RedisEntry, error) {
+	dec, err := newJSONEntryReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*RedisEntry)
+	for dec.More() {
+		var entry RedisEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to decode export: %w", err)
+		}
+		byKey[entry.Key] = &entry
+	}
+	if err := dec.Close(); err != nil {
+		return nil, err
+	}
+	return byKey, nil
+}
+
+// diffExports compares the entries of an old and a new export.
+func diffExports(oldEntries, newEntries map[string]*RedisEntry) *DiffResult {
+	result := &DiffResult{
+		Added:       []string{},
+		Removed:     []string{},
+		Changed:     []ChangedKey{},
+		TypeChanged: []TypeChange{},
+	}
+
+	for key, before := range oldEntries {
+		after, ok := newEntries[key]
+		if !ok {
+			result.Removed = append(result.Removed, key)
+			continue
+		}
+
+		if before.Type != after.Type {
+			result.TypeChanged = append(result.TypeChanged, TypeChange{Key: key, OldType: before.Type, NewType: after.Type})
+			continue
+		}
+
+		var fields []string
+		if !sameValue(before, after) {
+			fields = append(fields, "value")
+		}
+		if before.TTL != after.TTL {
+			fields = append(fields, "ttl")
+		}
+		if len(fields) > 0 {
+			result.Changed = append(result.Changed, ChangedKey{Key: key, Fields: fields})
+		}
+	}
+
+	for key := range newEntries {
+		if _, ok := oldEntries[key]; !ok {
+			result.Added = append(result.Added, key)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Slice(result.Changed, func(i, j int) bool { return result.Changed[i].Key < result.Changed[j].Key })
+	sort.Slice(result.TypeChanged, func(i, j int) bool { return result.TypeChanged[i].Key < result.TypeChanged[j].Key })
+	return result
+}
+
+// sameValue reports whether two entries of the same type hold equal values.
+// Set members are compared regardless of order since SMEMBERS is unordered.
+// Entries from --dedupe-values exports are compared by value reference.
+// String values compare equal whether or not --numbers json.Number was used.
+func sameValue(a, b *RedisEntry) bool {
+	if a.ValueRef != b.ValueRef || !bytes.Equal(a.ValueZstd, b.ValueZstd) {
+		return false
+	}
+	if a.Type == "set" {
+		return reflect.DeepEqual(sortedMembers(a.Value), sortedMembers(b.Value))
+	}
+	if a.Type == "string" {
+		// Numbers from --numbers json.Number match the same quoted string
+		av, aok := stringValue(a.Value)
+		bv, bok := stringValue(b.Value)
+		if aok && bok {
+			return av == bv
+		}
+	}
+	return reflect.DeepEqual(a.Value, b.Value)
+}
+
+func sortedMembers(value interface{}) interface{} {
+	items, ok := value.([]interface{})
+	if !ok {
+		return value
+	}
+	members := make([]string, 0, len(items))
+	for _, item := range items {
+		member, ok := item.(string)
+		if !ok {
+			return value
+		}
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func readExportFile(path string) (map[string]*RedisEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	entries, err := readExportEntries(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Compare two export files and print the differences as JSON",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldEntries, err := readExportFile(args[0])
+		if err != nil {
+			return err
+		}
+		newEntries, err := readExportFile(args[1])
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diffExports(oldEntries, newEntries))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
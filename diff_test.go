@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diffStrings(t *testing.T, oldExport, newExport string) *DiffResult {
+	t.Helper()
+	before, err := readExportEntries(strings.NewReader(oldExport))
+	require.NoError(t, err)
+	after, err := readExportEntries(strings.NewReader(newExport))
+	require.NoError(t, err)
+	return diffExports(before, after)
+}
+
+func TestDiffExports_AddedAndRemoved(t *testing.T) {
+	result := diffStrings(t,
+		`[{"key":"a","type":"string","value":"1"},{"key":"b","type":"string","value":"2"}]`,
+		`[{"key":"b","type":"string","value":"2"},{"key":"c","type":"string","value":"3"}]`,
+	)
+
+	assert.Equal(t, []string{"c"}, result.Added)
+	assert.Equal(t, []string{"a"}, result.Removed)
+	assert.Empty(t, result.Changed)
+	assert.Empty(t, result.TypeChanged)
+}
+
+func TestDiffExports_Unwrapped(t *testing.T) {
+	result := diffStrings(t,
+		`[{"key":"a","type":"string","value":"1"},{"key":"b","type":"string","value":"2"}]`,
+		"{\"key\":\"b\",\"type\":\"string\",\"value\":\"2\"}\n{\"key\":\"c\",\"type\":\"string\",\"value\":\"3\"}\n",
+	)
+
+	assert.Equal(t, []string{"c"}, result.Added)
+	assert.Equal(t, []string{"a"}, result.Removed)
+	assert.Empty(t, result.Changed)
+}
+
+func TestDiffExports_ValueAndTTLChanges(t *testing.T) {
+	result := diffStrings(t,
+		`[
+{"key":"s","type":"string","value":"old","ttl":60},
+{"key":"h","type":"hash","value":{"f":"1"}},
+{"key":"t","type":"string","value":"same","ttl":60}
+]`,
+		`[
+{"key":"s","type":"string","value":"new","ttl":30},
+{"key":"h","type":"hash","value":{"f":"2"}},
+{"key":"t","type":"string","value":"same","ttl":120}
+]`,
+	)
+
+	assert.Equal(t, []ChangedKey{
+		{Key: "h", Fields: []string{"value"}},
+		{Key: "s", Fields: []string{"value", "ttl"}},
+		{Key: "t", Fields: []string{"ttl"}},
+	}, result.Changed)
+}
+
+func TestDiffExports_SetOrderIgnored(t *testing.T) {
+	result := diffStrings(t,
+		`[{"key":"s","type":"set","value":["a","b","c"]}]`,
+		`[{"key":"s","type":"set","value":["c","a","b"]}]`,
+	)
+	assert.Empty(t, result.Changed)
+}
+
+func TestDiffExports_TypeChanges(t *testing.T) {
+	result := diffStrings(t,
+		`[{"key":"k","type":"string","value":"1"}]`,
+		`[{"key":"k","type":"list","value":["1"]}]`,
+	)
+
+	assert.Equal(t, []TypeChange{{Key: "k", OldType: "string", NewType: "list"}}, result.TypeChanged)
+	assert.Empty(t, result.Changed, "type changes are reported separately")
+}
+
+func TestReadExportEntries_Invalid(t *testing.T) {
+	_, err := readExportEntries(strings.NewReader(`[{"key":"a"`))
+	assert.Error(t, err)
+}
+
+func TestDiffExports_ValueRefs(t *testing.T) {
+	oldEntries := map[string]*RedisEntry{
+		"same":    {Key: "same", Type: "string", ValueRef: "aaa"},
+		"changed": {Key: "changed", Type: "string", ValueRef: "bbb"},
+	}
+	newEntries := map[string]*RedisEntry{
+		"same":    {Key: "same", Type: "string", ValueRef: "aaa"},
+		"changed": {Key: "changed", Type: "string", ValueRef: "ccc"},
+	}
+
+	result := diffExports(oldEntries, newEntries)
+	require.Len(t, result.Changed, 1)
+	assert.Equal(t, "changed", result.Changed[0].Key)
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-redis/redismock/v9"
 	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
 )
 
 func TestExporter_GetValueByType_String(t *testing.T) {
@@ -124,6 +138,72 @@ func TestExporter_GetValueByType_ZSet(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestExporter_ProcessKey_ZsetScoreRange(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{ZsetMinScore: "(100"},
+	}
+
+	top := []redis.Z{{Score: 250, Member: "alice"}, {Score: 180, Member: "bob"}}
+	mock.ExpectType("leaderboard").SetVal("zset")
+	mock.ExpectZRangeByScoreWithScores("leaderboard", &redis.ZRangeBy{Min: "(100", Max: "+inf"}).SetVal(top)
+	mock.ExpectTTL("leaderboard").SetVal(-1 * time.Second)
+	mock.ExpectType("name").SetVal("string")
+	mock.ExpectGet("name").SetVal("v")
+	mock.ExpectTTL("name").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "leaderboard")
+	require.NoError(t, err)
+	assert.Equal(t, top, entry.Value)
+	assert.Equal(t, &ScoreRange{Min: "(100", Max: "+inf"}, entry.ScoreRange)
+
+	entry, err = exporter.processKey(context.Background(), "name")
+	require.NoError(t, err)
+	assert.Nil(t, entry.ScoreRange, "other types ignore the score range")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_ZsetScoreRangeNoMembers(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:   filepath.Join(t.TempDir(), "export.json"),
+		Workers:      1,
+		BatchSize:    10,
+		SkipEmpty:    true,
+		ZsetMinScore: "(1000",
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"leaderboard"}, 0)
+	mock.ExpectType("leaderboard").SetVal("zset")
+	mock.ExpectZRangeByScoreWithScores("leaderboard", &redis.ZRangeBy{Min: "(1000", Max: "+inf"}).SetVal([]redis.Z{})
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Zero(t, exporter.vanished.Load(), "a live key outside the window hasn't vanished")
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	assert.Empty(t, entries)
+}
+
+func TestValidScoreBound(t *testing.T) {
+	for _, bound := range []string{"100", "(100", "-1.5", "-inf", "+inf", "(+inf", "1e3"} {
+		assert.True(t, validScoreBound(bound), bound)
+	}
+	for _, bound := range []string{"", "(", "top", "[100", "100)"} {
+		assert.False(t, validScoreBound(bound), bound)
+	}
+}
+
 func TestExporter_ProcessKey_WithTTL(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 	defer func() { _ = db.Close() }()
@@ -189,7 +269,7 @@ func TestExporter_Worker(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	keysChan := make(chan string, 2)
+	keysChan := make(chan keyTask, 2)
 	resultsChan := make(chan *RedisEntry, 2)
 	var wg sync.WaitGroup
 
@@ -200,12 +280,12 @@ func TestExporter_Worker(t *testing.T) {
 		mock.ExpectTTL(key).SetVal(-1 * time.Second)
 	}
 
-	keysChan <- "key1"
-	keysChan <- "key2"
+	keysChan <- keyTask{key: "key1"}
+	keysChan <- keyTask{key: "key2", seq: 1}
 	close(keysChan)
 
 	wg.Add(1)
-	go exporter.worker(ctx, keysChan, resultsChan, &wg)
+	go exporter.worker(ctx, 0, keysChan, resultsChan, &wg)
 
 	wg.Wait()
 	close(resultsChan)
@@ -229,15 +309,15 @@ func TestExporter_Worker_ContextCanceled(t *testing.T) {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	keysChan := make(chan string, 1)
+	keysChan := make(chan keyTask, 1)
 	resultsChan := make(chan *RedisEntry, 1)
 	var wg sync.WaitGroup
 
-	keysChan <- "key1"
+	keysChan <- keyTask{key: "key1"}
 	cancel()
 
 	wg.Add(1)
-	go exporter.worker(ctx, keysChan, resultsChan, &wg)
+	go exporter.worker(ctx, 0, keysChan, resultsChan, &wg)
 
 	wg.Wait()
 	close(resultsChan)
@@ -289,3 +369,1684 @@ func TestExporter_Export_MockRedis(t *testing.T) {
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestExporter_ProcessKey_RewritePrefix(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{
+			PrefixRules: []PrefixRule{{Old: "prod:", New: "staging:"}},
+		},
+	}
+
+	ctx := context.Background()
+
+	mock.ExpectType("prod:key").SetVal("string")
+	mock.ExpectGet("prod:key").SetVal("value")
+	mock.ExpectTTL("prod:key").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(ctx, "prod:key")
+	require.NoError(t, err)
+	assert.Equal(t, "staging:key", entry.Key)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Worker_StaggeredStart(t *testing.T) {
+	hook := test.NewGlobal()
+	logrus.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+		logrus.SetLevel(logrus.InfoLevel)
+	})
+
+	exporter := &Exporter{
+		config: Config{WorkerJitter: 100 * time.Millisecond},
+	}
+
+	keysChan := make(chan keyTask)
+	resultsChan := make(chan *RedisEntry)
+	close(keysChan)
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go exporter.worker(context.Background(), i, keysChan, resultsChan, &wg)
+	}
+	wg.Wait()
+
+	var starts []time.Time
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Worker started" {
+			starts = append(starts, entry.Time)
+		}
+	}
+	require.Len(t, starts, workers)
+
+	earliest, latest := starts[0], starts[0]
+	for _, ts := range starts {
+		if ts.Before(earliest) {
+			earliest = ts
+		}
+		if ts.After(latest) {
+			latest = ts
+		}
+	}
+	assert.Greater(t, latest.Sub(earliest), 10*time.Millisecond, "workers should not all start at once")
+}
+
+func TestExporter_Warmup(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+
+	// The mock client has no MinIdleConns, so a single ping warms the pool.
+	mock.ExpectPing().SetVal("PONG")
+
+	require.NoError(t, exporter.warmup(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_GetValueByType_RedisJSON(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+
+	ctx := context.Background()
+	key := "test:json"
+
+	mock.ExpectType(key).SetVal("ReJSON-RL")
+	mock.ExpectDo("JSON.GET", key).SetVal(`{"name":"redis","tags":["a","b"]}`)
+	mock.ExpectTTL(key).SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "ReJSON-RL", entry.Type)
+
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"test:json","type":"ReJSON-RL","value":{"name":"redis","tags":["a","b"]}}`, string(data))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_GetValueByType_ModuleDumpFallback(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+
+	mock.ExpectDump("test:bloom").SetVal("\x00\x01raw")
+
+	value, err := exporter.getValueByType(context.Background(), "test:bloom", "MBbloom--")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x00\x01raw"), value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_DetectsModuleTypes(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"bloom:1", "bloom:2", "name"}, 0)
+	for _, key := range []string{"bloom:1", "bloom:2"} {
+		mock.ExpectType(key).SetVal("MBbloom--")
+		mock.ExpectDump(key).SetVal("\x00\x01raw")
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+	mock.ExpectType("name").SetVal("string")
+	mock.ExpectGet("name").SetVal("redis")
+	mock.ExpectTTL("name").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 3)
+	assert.Equal(t, "MBbloom--", entries[0].Type)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("\x00\x01raw")), entries[0].Value)
+
+	var summary *logrus.Entry
+	found := 0
+	for _, entry := range hook.AllEntries() {
+		switch entry.Message {
+		case "Exported module types":
+			summary = entry
+		case "Exporting keys of a module type":
+			found++
+		}
+	}
+	require.NotNil(t, summary)
+	assert.Equal(t, logrus.Fields{"MBbloom--": int64(2)}, summary.Data)
+	assert.Equal(t, 1, found, "each module type is announced once")
+}
+
+func TestExporter_GetValueByType_ModulesDisabled(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{NoModules: true},
+	}
+
+	_, err := exporter.getValueByType(context.Background(), "test:json", "ReJSON-RL")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported key type")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ScanKeys_SingleTypeUsesScanType(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{BatchSize: 10, Types: []string{"hash"}},
+	}
+
+	mock.ExpectScanType(0, "*", int64(10), "hash").SetVal([]string{"h1", "h2"}, 0)
+
+	keysChan := make(chan keyTask, 10)
+	exporter.scanKeys(context.Background(), keysChan)
+
+	var keys []string
+	for task := range keysChan {
+		keys = append(keys, task.key)
+	}
+	assert.Equal(t, []string{"h1", "h2"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ScanKeys_ScanTypeFallback(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{BatchSize: 10, Types: []string{"hash"}},
+	}
+
+	mock.ExpectScanType(0, "*", int64(10), "hash").SetErr(errors.New("ERR syntax error"))
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"h1", "s1"}, 0)
+
+	keysChan := make(chan keyTask, 10)
+	exporter.scanKeys(context.Background(), keysChan)
+
+	var keys []string
+	for task := range keysChan {
+		keys = append(keys, task.key)
+	}
+	assert.Equal(t, []string{"h1", "s1"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_MultipleTypesFilteredClientSide(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_types.json",
+		Workers:    1,
+		BatchSize:  10,
+		Types:      []string{"string", "list"},
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"s1", "h1", "l1"}, 0)
+	mock.ExpectType("s1").SetVal("string")
+	mock.ExpectGet("s1").SetVal("value")
+	mock.ExpectTTL("s1").SetVal(-1 * time.Second)
+	mock.ExpectType("h1").SetVal("hash")
+	mock.ExpectType("l1").SetVal("list")
+	mock.ExpectLRange("l1", 0, -1).SetVal([]string{"a"})
+	mock.ExpectTTL("l1").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	content, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(content, &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "s1", entries[0].Key)
+	assert.Equal(t, "l1", entries[1].Key)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_ExcludeTypes(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:   filepath.Join(t.TempDir(), "export.json"),
+		Workers:      1,
+		BatchSize:    10,
+		ExcludeTypes: []string{"stream", "set"},
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"s1", "events", "h1", "tags"}, 0)
+	mock.ExpectType("s1").SetVal("string")
+	mock.ExpectGet("s1").SetVal("value")
+	mock.ExpectTTL("s1").SetVal(-1 * time.Second)
+	// Excluded keys are dropped after TYPE, without fetching their values
+	mock.ExpectType("events").SetVal("stream")
+	mock.ExpectType("h1").SetVal("hash")
+	mock.ExpectHGetAll("h1").SetVal(map[string]string{"f": "v"})
+	mock.ExpectTTL("h1").SetVal(-1 * time.Second)
+	mock.ExpectType("tags").SetVal("set")
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	content, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(content, &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "s1", entries[0].Key)
+	assert.Equal(t, "h1", entries[1].Key)
+}
+
+func TestExporter_Export_WarnsOnEviction(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:   filepath.Join(t.TempDir(), "export.json"),
+		Workers:      1,
+		BatchSize:    10,
+		VanishedWarn: 0.1,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	// Three of four keys are evicted between SCAN and TYPE
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a", "b", "c", "d"}, 0)
+	mock.ExpectType("a").SetVal("none")
+	mock.ExpectType("b").SetVal("string")
+	mock.ExpectGet("b").SetVal("value")
+	mock.ExpectTTL("b").SetVal(-1 * time.Second)
+	mock.ExpectType("c").SetVal("none")
+	mock.ExpectType("d").SetVal("none")
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	var warning *logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warning = entry
+		}
+	}
+	require.NotNil(t, warning, "the export should warn that it is likely incomplete")
+	assert.Contains(t, warning.Message, "likely incomplete")
+	assert.Equal(t, int64(3), warning.Data["vanished_keys"])
+	assert.Equal(t, 75.0, warning.Data["vanished_pct"])
+}
+
+func TestExporter_WarnVanished_BelowRatio(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	exporter := &Exporter{config: Config{VanishedWarn: 0.01}}
+	exporter.vanished.Store(1)
+	exporter.warnVanished(999)
+	assert.Empty(t, hook.AllEntries(), "one key in a thousand is normal expiry")
+
+	exporter.config.VanishedWarn = 0
+	exporter.vanished.Store(500)
+	exporter.warnVanished(500)
+	assert.Empty(t, hook.AllEntries(), "a ratio of 0 disables the warning")
+}
+
+func TestExporter_Export_ErrorsFile(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_with_errors.json",
+		ErrorsFile: "test_export_errors.json",
+		Workers:    1,
+		BatchSize:  10,
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+	defer func() { _ = os.Remove(config.ErrorsFile) }()
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"good", "bad-type", "bad-value"}, 0)
+	mock.ExpectType("good").SetVal("string")
+	mock.ExpectGet("good").SetVal("value")
+	mock.ExpectTTL("good").SetVal(-1 * time.Second)
+	mock.ExpectType("bad-type").SetErr(errors.New("connection reset"))
+	mock.ExpectType("bad-value").SetVal("hash")
+	mock.ExpectHGetAll("bad-value").SetErr(errors.New("read timeout"))
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	content, err := os.ReadFile(config.ErrorsFile)
+	require.NoError(t, err)
+
+	var failed []FailedKey
+	require.NoError(t, json.Unmarshal(content, &failed))
+	require.Len(t, failed, 2)
+	assert.Equal(t, "bad-type", failed[0].Key)
+	assert.Empty(t, failed[0].Type)
+	assert.Contains(t, failed[0].Error, "connection reset")
+	assert.Equal(t, "bad-value", failed[1].Key)
+	assert.Equal(t, "hash", failed[1].Type)
+	assert.Contains(t, failed[1].Error, "read timeout")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollectTouchedKeys(t *testing.T) {
+	messages := make(chan *redis.Message, 10)
+	messages <- &redis.Message{Channel: "__keyevent@0__:set", Payload: "user:1"}
+	messages <- &redis.Message{Channel: "__keyevent@0__:hset", Payload: "user:2"}
+	messages <- &redis.Message{Channel: "__keyevent@0__:set", Payload: "user:1"}
+	messages <- &redis.Message{Channel: "__keyevent@0__:del", Payload: "user:3"}
+	messages <- &redis.Message{Channel: "__keyevent@0__:expired", Payload: "session:1"}
+	messages <- &redis.Message{Channel: "__keyevent@0__:lpush", Payload: "queue"}
+
+	keys := collectTouchedKeys(context.Background(), messages, 50*time.Millisecond)
+	assert.Equal(t, []string{"user:1", "user:2", "queue"}, keys)
+}
+
+func TestExporter_SendKeys_OnlyTouchedKeysExported(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+
+	messages := make(chan *redis.Message, 2)
+	messages <- &redis.Message{Channel: "__keyevent@0__:set", Payload: "touched"}
+	close(messages)
+	keys := collectTouchedKeys(context.Background(), messages, time.Second)
+
+	// Only the touched key is fetched; any other command fails the mock.
+	mock.ExpectType("touched").SetVal("string")
+	mock.ExpectGet("touched").SetVal("value")
+	mock.ExpectTTL("touched").SetVal(-1 * time.Second)
+
+	keysChan := make(chan keyTask, 1)
+	resultsChan := make(chan *RedisEntry, 1)
+	go exporter.sendKeys(context.Background(), keys, keysChan)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	exporter.worker(context.Background(), 0, keysChan, resultsChan, &wg)
+	close(resultsChan)
+
+	var exported []string
+	for entry := range resultsChan {
+		exported = append(exported, entry.Key)
+	}
+	assert.Equal(t, []string{"touched"}, exported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_KeysOnly(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_keys_only.json",
+		Workers:    1,
+		BatchSize:  10,
+		KeysOnly:   true,
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	// No value-fetch commands are expected
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"s1", "h1"}, 0)
+	mock.ExpectType("s1").SetVal("string")
+	mock.ExpectTTL("s1").SetVal(120 * time.Second)
+	mock.ExpectType("h1").SetVal("hash")
+	mock.ExpectTTL("h1").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	content, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), `"value"`)
+
+	var entries []map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &entries))
+	assert.Equal(t, []map[string]interface{}{
+		{"key": "s1", "type": "string", "ttl": float64(120)},
+		{"key": "h1", "type": "hash"},
+	}, entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_SkipValuesFor(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:    filepath.Join(t.TempDir(), "export.json"),
+		Workers:       1,
+		BatchSize:     10,
+		SkipValuesFor: []string{"stream", "list"},
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	// The stream's value is never fetched, the string's is
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"events", "name"}, 0)
+	mock.ExpectType("events").SetVal("stream")
+	mock.ExpectTTL("events").SetVal(300 * time.Second)
+	mock.ExpectType("name").SetVal("string")
+	mock.ExpectGet("name").SetVal("redis")
+	mock.ExpectTTL("name").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	content, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &entries))
+	assert.Equal(t, []map[string]interface{}{
+		{"key": "events", "type": "stream", "ttl": float64(300)},
+		{"key": "name", "type": "string", "value": "redis"},
+	}, entries)
+}
+
+func TestExporter_ProcessKey_VanishedKey(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+
+	ctx := context.Background()
+
+	mock.ExpectType("gone").SetVal("none")
+	_, err := exporter.processKey(ctx, "gone")
+	assert.ErrorIs(t, err, errKeyVanished)
+
+	mock.ExpectType("deleted").SetVal("string")
+	mock.ExpectGet("deleted").RedisNil()
+	_, err = exporter.processKey(ctx, "deleted")
+	assert.ErrorIs(t, err, errKeyVanished)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_TypeChanged(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+
+	ctx := context.Background()
+	wrongType := errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+	// Recreated as a list between TYPE and GET
+	mock.ExpectType("churn").SetVal("string")
+	mock.ExpectGet("churn").SetErr(wrongType)
+	mock.ExpectType("churn").SetVal("list")
+	mock.ExpectLRange("churn", 0, -1).SetVal([]string{"a", "b"})
+	mock.ExpectTTL("churn").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(ctx, "churn")
+	require.NoError(t, err)
+	assert.Equal(t, "list", entry.Type)
+	assert.Equal(t, []string{"a", "b"}, entry.Value)
+
+	// Only one retry is made
+	mock.ExpectType("flappy").SetVal("string")
+	mock.ExpectGet("flappy").SetErr(wrongType)
+	mock.ExpectType("flappy").SetVal("list")
+	mock.ExpectLRange("flappy", 0, -1).SetErr(wrongType)
+
+	_, err = exporter.processKey(ctx, "flappy")
+	assert.ErrorContains(t, err, "WRONGTYPE")
+
+	// Deleted before the type is read again
+	mock.ExpectType("gone").SetVal("string")
+	mock.ExpectGet("gone").SetErr(wrongType)
+	mock.ExpectType("gone").SetVal("none")
+
+	_, err = exporter.processKey(ctx, "gone")
+	assert.ErrorIs(t, err, errKeyVanished)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_TypeChangedToSkippedValue(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{SkipValuesFor: []string{"list"}},
+	}
+
+	// Recreated as a list, whose values aren't exported, between TYPE and GET
+	mock.ExpectType("churn").SetVal("string")
+	mock.ExpectGet("churn").SetErr(errors.New("WRONGTYPE Operation against a key holding the wrong kind of value"))
+	mock.ExpectType("churn").SetVal("list")
+	mock.ExpectTTL("churn").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "churn")
+	require.NoError(t, err)
+	assert.Equal(t, "list", entry.Type)
+	assert.Nil(t, entry.Value)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_PersistentOnly(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{PersistentOnly: true},
+	}
+
+	ctx := context.Background()
+
+	mock.ExpectType("session").SetVal("string")
+	mock.ExpectGet("session").SetVal("abc")
+	mock.ExpectTTL("session").SetVal(300 * time.Second)
+	_, err := exporter.processKey(ctx, "session")
+	assert.ErrorIs(t, err, errKeySkipped, "keys with a TTL are skipped")
+
+	mock.ExpectType("leak").SetVal("hash")
+	mock.ExpectHGetAll("leak").SetVal(map[string]string{"f": "v"})
+	mock.ExpectTTL("leak").SetVal(-1 * time.Second)
+	entry, err := exporter.processKey(ctx, "leak")
+	require.NoError(t, err)
+	assert.Equal(t, "leak", entry.Key)
+	assert.Zero(t, entry.TTL)
+
+	// Deleted between HGETALL and TTL
+	mock.ExpectType("gone").SetVal("hash")
+	mock.ExpectHGetAll("gone").SetVal(map[string]string{"f": "v"})
+	mock.ExpectTTL("gone").SetVal(ttlNoKey)
+	_, err = exporter.processKey(ctx, "gone")
+	assert.ErrorIs(t, err, errKeyVanished, "a missing key isn't mistaken for a persistent one")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_SkipEmpty(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{SkipEmpty: true},
+	}
+
+	ctx := context.Background()
+
+	mock.ExpectType("tags").SetVal("set")
+	mock.ExpectSMembers("tags").SetVal([]string{})
+	_, err := exporter.processKey(ctx, "tags")
+	assert.ErrorIs(t, err, errKeyVanished, "an empty set is skipped")
+
+	mock.ExpectType("events").SetVal("stream")
+	mock.ExpectXRange("events", "-", "+").SetVal([]redis.XMessage{})
+	mock.ExpectTTL("events").SetVal(-1 * time.Second)
+	entry, err := exporter.processKey(ctx, "events")
+	require.NoError(t, err, "empty streams are real keys")
+	assert.Equal(t, "stream", entry.Type)
+
+	mock.ExpectType("members").SetVal("set")
+	mock.ExpectSMembers("members").SetVal([]string{"a"})
+	mock.ExpectTTL("members").SetVal(-1 * time.Second)
+	entry, err = exporter.processKey(ctx, "members")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, entry.Value)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsEmptyCollection(t *testing.T) {
+	assert.True(t, isEmptyCollection([]string{}))
+	assert.True(t, isEmptyCollection([]redis.Z{}))
+	assert.True(t, isEmptyCollection(map[string]string{}))
+	assert.True(t, isEmptyCollection([]HashField{}))
+	assert.False(t, isEmptyCollection(""), "strings are not collections")
+	assert.False(t, isEmptyCollection([]redis.XMessage{}))
+	assert.False(t, isEmptyCollection([]string{"a"}))
+}
+
+func TestExporter_Worker_CountsVanishedKeys(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+	failures := make(chan FailedKey, 1)
+	exporter.failures = failures
+
+	mock.ExpectType("gone").SetVal("none")
+	mock.ExpectType("kept").SetVal("string")
+	mock.ExpectGet("kept").SetVal("value")
+	mock.ExpectTTL("kept").SetVal(-1 * time.Second)
+
+	keysChan := make(chan keyTask, 2)
+	resultsChan := make(chan *RedisEntry, 2)
+	keysChan <- keyTask{key: "gone"}
+	keysChan <- keyTask{key: "kept", seq: 1}
+	close(keysChan)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	exporter.worker(context.Background(), 0, keysChan, resultsChan, &wg)
+	close(resultsChan)
+	close(failures)
+
+	assert.Len(t, resultsChan, 1)
+	assert.Equal(t, int64(1), exporter.vanished.Load())
+	assert.Empty(t, failures, "vanished keys are not failures")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Worker_RetriesPoolTimeout(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	origWait := poolRetryWait
+	poolRetryWait = time.Millisecond
+	defer func() { poolRetryWait = origWait }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{PoolRetries: 2},
+	}
+	failures := make(chan FailedKey, 2)
+	exporter.failures = failures
+
+	// "busy" times out twice and then exports; "starved" never gets a
+	// connection and fails once its retries are used up.
+	mock.ExpectType("busy").SetErr(redis.ErrPoolTimeout)
+	mock.ExpectType("busy").SetErr(redis.ErrPoolTimeout)
+	mock.ExpectType("busy").SetVal("string")
+	mock.ExpectGet("busy").SetVal("value")
+	mock.ExpectTTL("busy").SetVal(-1 * time.Second)
+	mock.ExpectType("starved").SetErr(redis.ErrPoolTimeout)
+	mock.ExpectType("starved").SetErr(redis.ErrPoolTimeout)
+	mock.ExpectType("starved").SetErr(redis.ErrPoolTimeout)
+
+	keysChan := make(chan keyTask, 2)
+	resultsChan := make(chan *RedisEntry, 2)
+	keysChan <- keyTask{key: "busy"}
+	keysChan <- keyTask{key: "starved", seq: 1}
+	close(keysChan)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	exporter.worker(context.Background(), 0, keysChan, resultsChan, &wg)
+	close(resultsChan)
+	close(failures)
+
+	require.Len(t, resultsChan, 1)
+	entry := <-resultsChan
+	assert.Equal(t, "busy", entry.Key)
+	assert.Equal(t, "value", entry.Value)
+	assert.Equal(t, int64(4), exporter.poolRetries.Load())
+
+	failed := <-failures
+	assert.Equal(t, "starved", failed.Key)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_GetValueByType_OrderedHash(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{OrderedHashes: true},
+	}
+
+	fields := map[string]string{"zeta": "3", "alpha": "1", "mid": "2"}
+	for i := 0; i < 3; i++ {
+		mock.ExpectHGetAll("test:hash").SetVal(fields)
+	}
+
+	// Repeated fetches always produce the same field order
+	for i := 0; i < 3; i++ {
+		value, err := exporter.getValueByType(context.Background(), "test:hash", "hash")
+		require.NoError(t, err)
+		assert.Equal(t, []HashField{
+			{Field: "alpha", Value: "1"},
+			{Field: "mid", Value: "2"},
+			{Field: "zeta", Value: "3"},
+		}, value)
+	}
+
+	data, err := json.Marshal(sortedHashFields(fields))
+	require.NoError(t, err)
+	assert.Equal(t, `[{"field":"alpha","value":"1"},{"field":"mid","value":"2"},{"field":"zeta","value":"3"}]`, string(data))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ScanKeys_KeyRange(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{BatchSize: 10, KeyStart: "user:100", KeyEnd: "user:200"},
+	}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"user:050", "user:100", "user:150", "user:200", "user:250"}, 0)
+
+	keysChan := make(chan keyTask, 10)
+	exporter.scanKeys(context.Background(), keysChan)
+
+	var keys []string
+	for task := range keysChan {
+		keys = append(keys, task.key)
+	}
+	assert.Equal(t, []string{"user:100", "user:150"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// exportWithSlowKey exports a single key that takes 50ms to fetch, logging
+// progress every interval.
+func exportWithSlowKey(t *testing.T, quiet bool, interval time.Duration) []*logrus.Entry {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:    "test_export_quiet.json",
+		Workers:       1,
+		BatchSize:     10,
+		Quiet:         quiet,
+		StatsInterval: interval,
+	}
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"slow"}, 0)
+	mock.CustomMatch(func(expected, actual []interface{}) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}).ExpectType("slow").SetVal("string")
+	mock.ExpectGet("slow").SetVal("value")
+	mock.ExpectTTL("slow").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+	return hook.AllEntries()
+}
+
+func countMessages(entries []*logrus.Entry, message string) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.Message == message {
+			count++
+		}
+	}
+	return count
+}
+
+func TestExporter_Export_Progress(t *testing.T) {
+	entries := exportWithSlowKey(t, false, 10*time.Millisecond)
+	assert.Positive(t, countMessages(entries, "Export progress"))
+}
+
+func TestExporter_Export_QuietSkipsProgress(t *testing.T) {
+	entries := exportWithSlowKey(t, true, 10*time.Millisecond)
+	assert.Zero(t, countMessages(entries, "Export progress"))
+}
+
+func TestExporter_Export_StatsInterval(t *testing.T) {
+	// The 50ms export spans about five 10ms ticks but only one 40ms tick
+	frequent := countMessages(exportWithSlowKey(t, false, 10*time.Millisecond), "Export progress")
+	sparse := countMessages(exportWithSlowKey(t, false, 40*time.Millisecond), "Export progress")
+	assert.GreaterOrEqual(t, frequent, 2)
+	assert.LessOrEqual(t, sparse, 1)
+
+	disabled := exportWithSlowKey(t, false, 0)
+	assert.Zero(t, countMessages(disabled, "Export progress"), "an interval of 0 disables progress")
+}
+
+func TestExporter_ProcessKey_WithFreq(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{WithFreq: true},
+	}
+
+	mock.ExpectType("hot").SetVal("string")
+	mock.ExpectGet("hot").SetVal("value")
+	mock.ExpectTTL("hot").SetVal(-1 * time.Second)
+	mock.ExpectDo("object", "freq", "hot").SetVal(int64(42))
+
+	entry, err := exporter.processKey(context.Background(), "hot")
+	require.NoError(t, err)
+	assert.Equal(t, 42, entry.Freq)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_WithFreqNotLFU(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{WithFreq: true},
+	}
+
+	mock.ExpectType("a").SetVal("string")
+	mock.ExpectGet("a").SetVal("value")
+	mock.ExpectTTL("a").SetVal(-1 * time.Second)
+	mock.ExpectDo("object", "freq", "a").SetErr(errors.New("ERR An LFU maxmemory policy is not selected, access frequency not tracked"))
+
+	entry, err := exporter.processKey(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Zero(t, entry.Freq)
+
+	// Once disabled, OBJECT FREQ is no longer sent.
+	mock.ExpectType("b").SetVal("string")
+	mock.ExpectGet("b").SetVal("value")
+	mock.ExpectTTL("b").SetVal(-1 * time.Second)
+
+	_, err = exporter.processKey(context.Background(), "b")
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_WithRefcount(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{WithFreq: true, WithRefcount: true},
+	}
+
+	// OBJECT FREQ and OBJECT REFCOUNT are pipelined with TTL
+	mock.ExpectType("session").SetVal("string")
+	mock.ExpectGet("session").SetVal("abc")
+	mock.ExpectTTL("session").SetVal(-1 * time.Second)
+	mock.ExpectDo("object", "freq", "session").SetVal(int64(3))
+	mock.ExpectDo("object", "refcount", "session").SetVal(int64(1))
+
+	entry, err := exporter.processKey(context.Background(), "session")
+	require.NoError(t, err)
+	assert.Equal(t, 3, entry.Freq)
+	assert.Equal(t, int64(1), entry.Refcount)
+	assert.False(t, entry.Shared)
+
+	// Small integers are shared objects with a sentinel refcount
+	exporter.config.WithFreq = false
+	mock.ExpectType("counter").SetVal("string")
+	mock.ExpectGet("counter").SetVal("7")
+	mock.ExpectTTL("counter").SetVal(-1 * time.Second)
+	mock.ExpectDo("object", "refcount", "counter").SetVal(int64(math.MaxInt32))
+
+	entry, err = exporter.processKey(context.Background(), "counter")
+	require.NoError(t, err)
+	assert.Zero(t, entry.Refcount)
+	assert.True(t, entry.Shared)
+
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"counter","type":"string","value":"7","shared":true}`, string(data))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_CancelledOutputIsValidJSON(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_cancelled.json",
+		Workers:    1,
+		BatchSize:  10,
+		Quiet:      true,
+	}
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"key1", "key2"}, 0)
+	mock.ExpectType("key1").SetVal("string")
+	mock.ExpectGet("key1").SetVal("value")
+	mock.ExpectTTL("key1").SetVal(-1 * time.Second)
+	mock.CustomMatch(func(expected, actual []interface{}) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}).ExpectType("key2").SetVal("string")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := exporter.Export(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries), "partial export should be valid JSON")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "key1", entries[0].Key)
+}
+
+func TestExporter_Export_MaxDuration(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:  filepath.Join(t.TempDir(), "export.json"),
+		Workers:     1,
+		BatchSize:   10,
+		Quiet:       true,
+		MaxDuration: 50 * time.Millisecond,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"key1", "key2"}, 0)
+	mock.ExpectType("key1").SetVal("string")
+	mock.ExpectGet("key1").SetVal("value")
+	mock.ExpectTTL("key1").SetVal(-1 * time.Second)
+	mock.CustomMatch(func(expected, actual []interface{}) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}).ExpectType("key2").SetVal("string")
+
+	// main applies --max-duration as a timeout on the export's context
+	ctx, cancel := context.WithTimeout(context.Background(), config.MaxDuration)
+	defer cancel()
+
+	err := exporter.Export(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "--max-duration elapsed after 1 keys were written")
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries), "time-limited export should be valid JSON")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "key1", entries[0].Key)
+}
+
+func TestExporter_Export_MilestoneLogging(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	saved := firstMilestone
+	firstMilestone = 2
+	t.Cleanup(func() { firstMilestone = saved })
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Milestones: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	// Milestones at 2 and 20 keys: 5 keys cross only the first
+	keys := []string{"k1", "k2", "k3", "k4", "k5"}
+	mock.ExpectScan(0, "*", int64(10)).SetVal(keys, 0)
+	for _, key := range keys {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("v")
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var logged []interface{}
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Export progress" {
+			logged = append(logged, entry.Data["processed_keys"])
+		}
+	}
+	assert.Equal(t, []interface{}{int64(2)}, logged, "progress is logged on crossing a milestone and not between them")
+}
+
+func TestExporter_ProcessKeySafely_RecoversPanic(t *testing.T) {
+	// A nil client makes processKey panic.
+	exporter := &Exporter{}
+
+	entry, err := exporter.processKeySafely(context.Background(), keyTask{key: "key"})
+	assert.Nil(t, entry)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "panic processing key key")
+}
+
+func TestExporter_Export_KeysFile(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	keysFile := filepath.Join(t.TempDir(), "keys.txt")
+	require.NoError(t, os.WriteFile(keysFile, []byte("user:1\n\nmissing\r\nuser:2\n"), 0o644))
+
+	config := Config{
+		OutputFile: "test_export_keys_file.json",
+		Workers:    1,
+		BatchSize:  10,
+		KeysFile:   keysFile,
+	}
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	mock.ExpectType("user:1").SetVal("string")
+	mock.ExpectGet("user:1").SetVal("alice")
+	mock.ExpectTTL("user:1").SetVal(-1 * time.Second)
+	mock.ExpectType("missing").SetVal("none")
+	mock.ExpectType("user:2").SetVal("string")
+	mock.ExpectGet("user:2").SetVal("bob")
+	mock.ExpectTTL("user:2").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "user:1", entries[0].Key)
+	assert.Equal(t, "user:2", entries[1].Key)
+	assert.Equal(t, int64(1), exporter.vanished.Load())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_SummaryIncludesPoolStats(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_pool_stats.json",
+		Workers:    1,
+		BatchSize:  10,
+	}
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"key1"}, 0)
+	mock.ExpectType("key1").SetVal("string")
+	mock.ExpectGet("key1").SetVal("value")
+	mock.ExpectTTL("key1").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	var summary *logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Export completed successfully" {
+			summary = entry
+		}
+	}
+	require.NotNil(t, summary)
+	for _, field := range []string{"pool_hits", "pool_misses", "pool_timeouts", "pool_total_conns", "pool_idle_conns"} {
+		assert.Contains(t, summary.Data, field)
+	}
+}
+
+func TestExporter_Export_ReportBinary(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:   "test_export_report_binary.json",
+		Workers:      1,
+		BatchSize:    10,
+		Quiet:        true,
+		ReportBinary: true,
+	}
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"text", "image", "tags"}, 0)
+	mock.ExpectType("text").SetVal("string")
+	mock.ExpectGet("text").SetVal("hello")
+	mock.ExpectTTL("text").SetVal(-1 * time.Second)
+	mock.ExpectType("image").SetVal("string")
+	mock.ExpectGet("image").SetVal("\x89PNG\r\n\x1a\n\xff")
+	mock.ExpectTTL("image").SetVal(-1 * time.Second)
+	mock.ExpectType("tags").SetVal("set")
+	mock.ExpectSMembers("tags").SetVal([]string{"a", "b\xc3"})
+	mock.ExpectTTL("tags").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	var flagged []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Non-UTF-8 value" {
+			flagged = append(flagged, entry.Data["key"].(string))
+		}
+	}
+	assert.ElementsMatch(t, []string{"image", "tags"}, flagged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ScanKeys_AdaptiveCountGrows(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{BatchSize: 1000, AdaptiveScan: true},
+	}
+
+	// Mocked SCANs return instantly, so COUNT doubles after every call.
+	mock.ExpectScan(0, "*", 10).SetVal([]string{"a"}, 7)
+	mock.ExpectScan(7, "*", 20).SetVal([]string{"b"}, 3)
+	mock.ExpectScan(3, "*", 40).SetVal([]string{}, 9)
+	mock.ExpectScan(9, "*", 80).SetVal([]string{"c"}, 0)
+
+	keysChan := make(chan keyTask, 10)
+	exporter.scanKeys(context.Background(), keysChan)
+
+	var keys []string
+	for task := range keysChan {
+		keys = append(keys, task.key)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ScanKeys_SampleRate(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	origSample := sampleFloat
+	sampleFloat = rand.New(rand.NewSource(1)).Float64
+	defer func() { sampleFloat = origSample }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{BatchSize: 10000, SampleRate: 0.1},
+	}
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+	mock.ExpectScan(0, "*", 10000).SetVal(keys, 0)
+
+	keysChan := make(chan keyTask, len(keys))
+	exporter.scanKeys(context.Background(), keysChan)
+
+	sampled := 0
+	for task := range keysChan {
+		assert.Equal(t, int64(sampled), task.seq, "sampled keys are numbered consecutively")
+		sampled++
+	}
+	assert.InDelta(t, 1000, sampled, 100, "about 10%% of keys should be sampled")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_SlowKeys(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:    filepath.Join(t.TempDir(), "export.json"),
+		Workers:       1,
+		BatchSize:     10,
+		TopKeys:       5,
+		SlowThreshold: 20 * time.Millisecond,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"fast", "huge"}, 0)
+	mock.ExpectType("fast").SetVal("string")
+	mock.ExpectGet("fast").SetVal("v")
+	mock.ExpectTTL("fast").SetVal(-1 * time.Second)
+	mock.ExpectType("huge").SetVal("hash")
+	// The mock replies to HGETALL only after a delay
+	mock.CustomMatch(func(expected, actual []interface{}) error {
+		time.Sleep(50 * time.Millisecond)
+		if !assert.ObjectsAreEqual(expected, actual) {
+			return fmt.Errorf("expected %v, got %v", expected, actual)
+		}
+		return nil
+	}).ExpectHGetAll("huge").SetVal(map[string]string{"f": "v"})
+	mock.ExpectTTL("huge").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var flagged, slowest []string
+	var slowCount interface{}
+	for _, entry := range hook.AllEntries() {
+		switch entry.Message {
+		case "Slow key":
+			flagged = append(flagged, entry.Data["key"].(string))
+			assert.GreaterOrEqual(t, entry.Data["duration"].(time.Duration), 50*time.Millisecond)
+		case "Slowest key":
+			slowest = append(slowest, entry.Data["key"].(string))
+		case "Keys slower than the threshold":
+			slowCount = entry.Data["slow_keys"]
+		}
+	}
+	assert.Equal(t, []string{"huge"}, flagged)
+	assert.Equal(t, []string{"huge"}, slowest)
+	assert.Equal(t, int64(1), slowCount)
+}
+
+func TestExporter_Export_EarlyErrorDoesNotLeak(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	db, _ := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		ErrorsFile: filepath.Join(t.TempDir(), "errors.json"),
+		Format:     "bogus",
+		Workers:    4,
+		BatchSize:  10,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	// The writer fails after the workers and errors writer are running
+	err := exporter.Export(context.Background())
+	assert.ErrorContains(t, err, "unsupported output format")
+}
+
+func TestExporter_Export_NumbersJSON(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Numbers:    numbersJSON,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	big := "12345678901234567890123"
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"counter", "zip", "name"}, 0)
+	mock.ExpectType("counter").SetVal("string")
+	mock.ExpectGet("counter").SetVal(big)
+	mock.ExpectTTL("counter").SetVal(-1 * time.Second)
+	mock.ExpectType("zip").SetVal("string")
+	mock.ExpectGet("zip").SetVal("00501")
+	mock.ExpectTTL("zip").SetVal(-1 * time.Second)
+	mock.ExpectType("name").SetVal("string")
+	mock.ExpectGet("name").SetVal("alice")
+	mock.ExpectTTL("name").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"value":`+big+`}`, "numbers are written bare")
+	assert.Contains(t, string(data), `"value":"00501"`, "leading zeros keep a value a string")
+
+	entries, err := readExportEntries(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, json.Number(big), entries["counter"].Value)
+
+	// Importing writes back the exact digits
+	importDB, importMock := redismock.NewClientMock()
+	defer func() { _ = importDB.Close() }()
+
+	importer := &Importer{client: importDB, config: ImportConfig{BatchSize: 10}}
+	importMock.ExpectSet("counter", big, 0).SetVal("OK")
+	importMock.ExpectSet("zip", "00501", 0).SetVal("OK")
+	importMock.ExpectSet("name", "alice", 0).SetVal("OK")
+
+	result, err := importer.Import(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+	assert.NoError(t, importMock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_WorkerStats(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	config := Config{
+		OutputFile:  filepath.Join(t.TempDir(), "export.json"),
+		Workers:     4,
+		BatchSize:   10,
+		WorkerStats: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+	}
+	mock.ExpectScan(0, "*", int64(10)).SetVal(keys, 0)
+	for _, key := range keys {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("v")
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	var logged int64
+	var workers []int
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Worker statistics" {
+			workers = append(workers, entry.Data["worker"].(int))
+			logged += entry.Data["keys"].(int64)
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2, 3}, workers)
+	assert.Equal(t, int64(len(keys)), logged, "per-worker counts add up to the exported keys")
+	assert.Equal(t, int64(len(keys)), exporter.workerStats.total())
+}
+
+func TestExporter_Export_StatsJSON(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	dir := t.TempDir()
+	config := Config{
+		OutputFile: filepath.Join(dir, "export.json"),
+		Workers:    2,
+		BatchSize:  10,
+		StatsJSON:  filepath.Join(dir, "stats.json"),
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a", "b", "h", "broken"}, 0)
+	for _, key := range []string{"a", "b"} {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("v")
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+	mock.ExpectType("h").SetVal("hash")
+	mock.ExpectHGetAll("h").SetVal(map[string]string{"f": "v"})
+	mock.ExpectTTL("h").SetVal(-1 * time.Second)
+	mock.ExpectType("broken").SetVal("string")
+	mock.ExpectGet("broken").SetErr(errors.New("ERR something went wrong"))
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	data, err := os.ReadFile(config.StatsJSON)
+	require.NoError(t, err)
+	var stats RunStats
+	require.NoError(t, json.Unmarshal(data, &stats))
+	assert.Equal(t, int64(3), stats.TotalKeys)
+	assert.Equal(t, map[string]int64{"string": 2, "hash": 1}, stats.Types)
+	assert.Equal(t, int64(1), stats.Errors)
+	assert.False(t, stats.StartedAt.IsZero())
+	assert.False(t, stats.CompletedAt.Before(stats.StartedAt))
+	assert.GreaterOrEqual(t, stats.DurationSeconds, 0.0)
+}
+
+func TestExporter_Export_TTLPrecisionMillis(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:   filepath.Join(t.TempDir(), "export.json"),
+		Workers:      1,
+		BatchSize:    10,
+		TTLPrecision: ttlPrecisionMillis,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"lock", "name"}, 0)
+	mock.ExpectType("lock").SetVal("string")
+	mock.ExpectGet("lock").SetVal("owner-1")
+	mock.ExpectPTTL("lock").SetVal(1500 * time.Millisecond)
+	mock.ExpectType("name").SetVal("string")
+	mock.ExpectGet("name").SetVal("alice")
+	mock.ExpectPTTL("name").SetVal(-1 * time.Millisecond)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, int64(1500), entries[0].TTLMillis)
+	assert.Equal(t, int64(1), entries[0].TTL, "whole seconds are still written for older readers")
+	assert.Zero(t, entries[1].TTLMillis)
+
+	importDB, importMock := redismock.NewClientMock()
+	defer func() { _ = importDB.Close() }()
+
+	importer := &Importer{client: importDB, config: ImportConfig{BatchSize: 10}}
+	importMock.ExpectSet("lock", "owner-1", 0).SetVal("OK")
+	importMock.ExpectPExpire("lock", 1500*time.Millisecond).SetVal(true)
+	importMock.ExpectSet("name", "alice", 0).SetVal("OK")
+
+	result, err := importer.Import(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.NoError(t, importMock.ExpectationsWereMet())
+}
+
+// writeRecorder serves a single connection from client, answering every
+// command with reply and recording the commands of each write. The
+// connection is a net.Pipe, so each write the client makes, such as a whole
+// pipeline, is read in one go.
+func writeRecorder(t *testing.T, reply func(cmd []string) string) (*redis.Client, func() [][]string) {
+	server, conn := net.Pipe()
+	var mu sync.Mutex
+	var writes [][]string
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			var names []string
+			var replies strings.Builder
+			for _, cmd := range parseRESPCommands(string(buf[:n])) {
+				names = append(names, strings.ToLower(cmd[0]))
+				replies.WriteString(reply(cmd))
+			}
+			mu.Lock()
+			writes = append(writes, names)
+			mu.Unlock()
+			if _, err := server.Write([]byte(replies.String())); err != nil {
+				return
+			}
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{
+		Protocol:        2,
+		DisableIdentity: true,
+		PoolSize:        1,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return conn, nil
+		},
+	})
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return writes
+	}
+}
+
+// parseRESPCommands splits data into the arrays of bulk strings clients send
+// commands as.
+func parseRESPCommands(data string) [][]string {
+	var cmds [][]string
+	lines := strings.Split(data, "\r\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "*") {
+			continue
+		}
+		n, _ := strconv.Atoi(lines[i][1:])
+		cmd := make([]string, 0, n)
+		for j := 0; j < n && i+2 < len(lines); j++ {
+			cmd = append(cmd, lines[i+2])
+			i += 2
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+func TestExporter_ProcessKey_TTLWithValue(t *testing.T) {
+	client, writes := writeRecorder(t, func(cmd []string) string {
+		switch strings.ToLower(cmd[0]) {
+		case "type":
+			return "+hash\r\n"
+		case "hgetall":
+			return "*2\r\n$4\r\nuser\r\n$3\r\nada\r\n"
+		case "pttl":
+			return ":2500\r\n"
+		case "hello":
+			// Fall back to RESP2, as servers before Redis 6 do
+			return "-ERR unknown command 'HELLO'\r\n"
+		default:
+			return "+OK\r\n"
+		}
+	})
+
+	exporter := &Exporter{client: client, config: Config{TTLWithValue: true, TTLPrecision: ttlPrecisionMillis}}
+
+	entry, err := exporter.processKey(context.Background(), "session")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"user": "ada"}, entry.Value)
+	assert.Equal(t, int64(2500), entry.TTLMillis)
+
+	// The connection handshake comes first
+	sent := writes()
+	require.GreaterOrEqual(t, len(sent), 2)
+	assert.Equal(t, [][]string{{"type"}, {"hgetall", "pttl"}}, sent[len(sent)-2:],
+		"the value and TTL are fetched in one pipeline")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// loadFilterScript reads the Lua predicate of --filter-script from path.
+func loadFilterScript(path string) (*redis.Script, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filter script: %w", err)
+	}
+	return redis.NewScript(string(src)), nil
+}
+
+// filterMatches runs the --filter-script predicate for key on the server,
+// with the key as KEYS[1] and its type as ARGV[1]. A true or non-zero reply
+// includes the key; nil, false or 0 leaves it out. The script is sent by
+// EVALSHA, falling back to EVAL if the server no longer has it cached.
+func (e *Exporter) filterMatches(ctx context.Context, key, keyType string) (bool, error) {
+	match, err := e.filter.Run(ctx, e.client, []string{key}, keyType).Bool()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return match, err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_Export_FilterScript(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	src := `return redis.call("HGET", KEYS[1], "status") == "active"`
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "filter.lua")
+	require.NoError(t, os.WriteFile(scriptPath, []byte(src), 0o600))
+
+	config := Config{
+		OutputFile:   filepath.Join(dir, "export.json"),
+		ErrorsFile:   filepath.Join(dir, "errors.json"),
+		FilterScript: scriptPath,
+		Workers:      1,
+		BatchSize:    10,
+	}
+	exporter := &Exporter{client: db, config: config}
+	sha := redis.NewScript(src).Hash()
+
+	mock.ExpectScriptLoad(src).SetVal(sha)
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"user:1", "user:2", "user:3"}, 0)
+	mock.ExpectType("user:1").SetVal("hash")
+	mock.ExpectEvalSha(sha, []string{"user:1"}, "hash").SetVal(int64(1))
+	mock.ExpectHGetAll("user:1").SetVal(map[string]string{"status": "active"})
+	mock.ExpectTTL("user:1").SetVal(-1 * time.Second)
+	// Lua false is a nil reply
+	mock.ExpectType("user:2").SetVal("hash")
+	mock.ExpectEvalSha(sha, []string{"user:2"}, "hash").RedisNil()
+	mock.ExpectType("user:3").SetVal("hash")
+	mock.ExpectEvalSha(sha, []string{"user:3"}, "hash").SetErr(errors.New("ERR user_script:1: Script attempted to access nonexistent global variable"))
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "user:1", entries[0].Key)
+
+	data, err = os.ReadFile(config.ErrorsFile)
+	require.NoError(t, err)
+	var failed []FailedKey
+	require.NoError(t, json.Unmarshal(data, &failed))
+	require.Len(t, failed, 1, "a script error fails only that key")
+	assert.Equal(t, "user:3", failed[0].Key)
+}
+
+func TestExporter_Export_FilterScriptInvalid(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	src := `return (`
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "filter.lua")
+	require.NoError(t, os.WriteFile(scriptPath, []byte(src), 0o600))
+
+	exporter := &Exporter{client: db, config: Config{
+		OutputFile:   filepath.Join(dir, "export.json"),
+		FilterScript: scriptPath,
+		Workers:      1,
+		BatchSize:    10,
+	}}
+
+	mock.ExpectScriptLoad(src).SetErr(errors.New("ERR Error compiling script"))
+
+	err := exporter.Export(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid --filter-script")
+}
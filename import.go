@@ -0,0 +1,679 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ImportConfig holds the options for the import subcommand.
+type ImportConfig struct {
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	BatchSize     int
+	ValuesFile    string
+	ManifestFile  string
+	Atomic        bool   // wrap each batch in MULTI/EXEC
+	OnConflict    string // what to do with keys already in the target, one of the conflict* modes
+	KeyPrefix     string // prepended to every key written to the target
+	AllowSampled  bool   // import collections cut down by --sample-members
+}
+
+// Modes for keys of the export that already exist in the target database.
+const (
+	conflictOverwrite = "overwrite"
+	conflictSkip      = "skip"
+	conflictError     = "error"
+)
+
+// conflictMode returns the mode selected by the --overwrite, --skip-existing
+// and --error-on-conflict flags, of which at most one may be set.
+func conflictMode(overwrite, skipExisting, errorOnConflict bool) (string, error) {
+	mode := conflictOverwrite
+	set := 0
+	if overwrite {
+		set++
+	}
+	if skipExisting {
+		mode = conflictSkip
+		set++
+	}
+	if errorOnConflict {
+		mode = conflictError
+		set++
+	}
+	if set > 1 {
+		return "", fmt.Errorf("--overwrite, --skip-existing and --error-on-conflict cannot be used together")
+	}
+	return mode, nil
+}
+
+// Importer loads a JSON export back into Redis.
+type Importer struct {
+	client *redis.Client
+	config ImportConfig
+	values map[string]json.RawMessage // values file of a --dedupe-values export
+	dict   *zstd.Decoder              // manifest dictionary of a --compress-dict export
+}
+
+// ImportResult summarises an import.
+type ImportResult struct {
+	Imported int
+	Skipped  int // entries without a full value, from --keys-only or --max-value-bytes
+	Existing int // keys left as they were because they already existed, with --skip-existing
+	Failed   int
+}
+
+func NewImporter(config ImportConfig) *Importer {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:         config.RedisAddr,
+		Password:     config.RedisPassword,
+		DB:           config.RedisDB,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		Protocol:     2,
+		ClientName:   "redis-export/" + version,
+	})
+
+	return &Importer{
+		client: rdb,
+		config: config,
+	}
+}
+
+// pendingEntry is an entry queued in the current pipeline, with the commands
+// that restore it.
+type pendingEntry struct {
+	key  string
+	cmds int
+}
+
+// Import streams the JSON export in r, an array or one object per line as
+// written with --array-wrapper=false, and writes every entry to Redis,
+// pipelining BatchSize entries at a time. Existing keys are replaced, unless
+// OnConflict says to skip them or to stop the import at the first one.
+// Entries that can't be restored are logged and counted as failed. With
+// Atomic, each batch runs as a MULTI/EXEC transaction, so other clients see
+// all of its keys restored or none. Exports compressed with gzip or zstd are
+// detected and decompressed.
+func (im *Importer) Import(ctx context.Context, r io.Reader) (*ImportResult, error) {
+	result := &ImportResult{}
+	input, compression, err := decompressReader(r)
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = input.Close() }()
+	if compression != "none" {
+		logrus.WithField("compression", compression).Info("Decompressing export")
+	}
+	dec, err := newJSONEntryReader(input)
+	if err != nil {
+		return result, err
+	}
+
+	batchSize := im.config.BatchSize
+	if batchSize < 1 {
+		batchSize = 1000
+	}
+
+	var pipe redis.Pipeliner
+	if im.config.Atomic {
+		pipe = im.client.TxPipeline()
+	} else {
+		pipe = im.client.Pipeline()
+	}
+	var batch []RedisEntry
+	var pending []pendingEntry
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch = batch[:0] }()
+
+		exists, err := im.existingKeys(ctx, batch)
+		if err != nil {
+			return err
+		}
+		if im.config.OnConflict == conflictError {
+			for i, found := range exists {
+				if found {
+					return fmt.Errorf("key %s already exists in the target database", batch[i].Key)
+				}
+			}
+		}
+
+		for i := range batch {
+			entry := &batch[i]
+			if exists != nil && exists[i] {
+				logrus.WithField("key", entry.Key).Debug("Skipping existing key")
+				result.Existing++
+				continue
+			}
+			before := pipe.Len()
+			if err := im.restoreEntry(ctx, pipe, entry); err != nil {
+				logrus.WithField("key", entry.Key).Error("Error importing key: ", err)
+				result.Failed++
+				continue
+			}
+			pending = append(pending, pendingEntry{key: entry.Key, cmds: pipe.Len() - before})
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		cmds, _ := pipe.Exec(ctx)
+		for _, p := range pending {
+			var failed error
+			for _, cmd := range cmds[:p.cmds] {
+				if err := cmd.Err(); err != nil {
+					failed = err
+					break
+				}
+			}
+			cmds = cmds[p.cmds:]
+
+			if failed != nil {
+				logrus.WithField("key", p.key).Error("Error importing key: ", failed)
+				result.Failed++
+				continue
+			}
+			result.Imported++
+		}
+		pending = pending[:0]
+		return nil
+	}
+
+	for index := 0; dec.More(); index++ {
+		var entry RedisEntry
+		if err := dec.Decode(&entry); err != nil {
+			if flushErr := flush(); flushErr != nil {
+				return result, flushErr
+			}
+			return result, fmt.Errorf("failed to decode entry %d: %w", index, err)
+		}
+
+		if entry.ValueRef != "" && entry.Value == nil {
+			if err := im.resolveValue(&entry); err != nil {
+				logrus.WithField("key", entry.Key).Error("Error importing key: ", err)
+				result.Failed++
+				continue
+			}
+		}
+		if entry.ValueZstd != nil && entry.Value == nil {
+			if err := decompressValue(im.dict, &entry); err != nil {
+				logrus.WithField("key", entry.Key).Error("Error importing key: ", err)
+				result.Failed++
+				continue
+			}
+		}
+
+		if entry.Value == nil {
+			result.Skipped++
+			continue
+		}
+		if entry.Truncated {
+			logrus.WithField("key", entry.Key).Warn("Skipping truncated value")
+			result.Skipped++
+			continue
+		}
+		if entry.Sampled && !im.config.AllowSampled {
+			logrus.WithField("key", entry.Key).Warn("Skipping sampled collection, pass --allow-sampled to import it")
+			result.Skipped++
+			continue
+		}
+
+		// Everything from the conflict checks on sees the key the target
+		// gets
+		entry.Key = im.config.KeyPrefix + entry.Key
+		batch = append(batch, entry)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	if err := dec.Close(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// existingKeys reports which entries of batch already exist in the target
+// database, with one pipelined EXISTS per key. It returns nil without a round
+// trip when existing keys are overwritten. The check is not atomic with the
+// writes that follow, so a key created in between is still replaced.
+func (im *Importer) existingKeys(ctx context.Context, batch []RedisEntry) ([]bool, error) {
+	if im.config.OnConflict == "" || im.config.OnConflict == conflictOverwrite {
+		return nil, nil
+	}
+
+	pipe := im.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(batch))
+	for i := range batch {
+		cmds[i] = pipe.Exists(ctx, batch[i].Key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to check for existing keys: %w", err)
+	}
+
+	exists := make([]bool, len(batch))
+	for i, cmd := range cmds {
+		exists[i] = cmd.Val() > 0
+	}
+	return exists, nil
+}
+
+// resolveValue replaces entry's ValueRef with the value it references in the
+// values file.
+func (im *Importer) resolveValue(entry *RedisEntry) error {
+	if im.values == nil {
+		return fmt.Errorf("entry references value %s but no values file was loaded", entry.ValueRef)
+	}
+	raw, ok := im.values[entry.ValueRef]
+	if !ok {
+		return fmt.Errorf("value %s is missing from the values file", entry.ValueRef)
+	}
+	value, err := decodeValue(raw, entry.Type)
+	if err != nil {
+		return err
+	}
+	entry.Value = value
+	return nil
+}
+
+// restoreEntry queues the commands that recreate entry on pipe. The value is
+// checked before anything is queued, so a malformed entry leaves the pipeline
+// untouched.
+func (im *Importer) restoreEntry(ctx context.Context, pipe redis.Pipeliner, entry *RedisEntry) error {
+	key := entry.Key
+
+	switch entry.Type {
+	case "string":
+		value, ok := stringValue(entry.Value)
+		if !ok {
+			return fmt.Errorf("string value is not a string")
+		}
+		pipe.Set(ctx, key, value, 0)
+	case "list", "set":
+		members, err := importStrings(entry.Value)
+		if err != nil {
+			return fmt.Errorf("%s value %w", entry.Type, err)
+		}
+		pipe.Del(ctx, key)
+		if len(members) > 0 {
+			if entry.Type == "list" {
+				pipe.RPush(ctx, key, members...)
+			} else {
+				pipe.SAdd(ctx, key, members...)
+			}
+		}
+	case "hash":
+		fields, err := importHashFields(entry.Value)
+		if err != nil {
+			return err
+		}
+		pipe.Del(ctx, key)
+		if len(fields) > 0 {
+			pipe.HSet(ctx, key, fields...)
+		}
+	case "zset":
+		members, err := importZMembers(entry.Value)
+		if err != nil {
+			return err
+		}
+		pipe.Del(ctx, key)
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, key, members...)
+		}
+	case "stream":
+		messages, err := importStreamMessages(entry.Value)
+		if err != nil {
+			return err
+		}
+		// The stream is recreated from scratch so every explicit ID is
+		// greater than the current top item.
+		pipe.Del(ctx, key)
+		for _, message := range messages {
+			pipe.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: message.ID, Values: message.Values})
+		}
+	case rejsonType:
+		doc, err := json.Marshal(entry.Value)
+		if err != nil {
+			return err
+		}
+		pipe.Do(ctx, "JSON.SET", key, "$", string(doc))
+	default:
+		dump, ok := entry.Value.(string)
+		if !ok {
+			return fmt.Errorf("unknown key type: %s", entry.Type)
+		}
+		payload, err := base64.StdEncoding.DecodeString(dump)
+		if err != nil {
+			return fmt.Errorf("%s value is not a base64 DUMP payload", entry.Type)
+		}
+		pipe.RestoreReplace(ctx, key, 0, string(payload))
+	}
+
+	if entry.TTLMillis > 0 {
+		pipe.PExpire(ctx, key, time.Duration(entry.TTLMillis)*time.Millisecond)
+	} else if entry.TTL > 0 {
+		pipe.Expire(ctx, key, time.Duration(entry.TTL)*time.Second)
+	}
+	return nil
+}
+
+// stringValue returns a string entry's value, which --numbers json.Number
+// may have written as a bare number and --parse-json-values as a nested
+// object or array. Nested JSON is encoded back compactly, with object keys
+// sorted.
+func stringValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	default:
+		return "", false
+	}
+}
+
+func importStrings(value interface{}) ([]interface{}, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("is not an array of strings")
+	}
+	for _, item := range items {
+		if _, ok := item.(string); !ok {
+			return nil, fmt.Errorf("is not an array of strings")
+		}
+	}
+	return items, nil
+}
+
+// importHashFields returns field/value pairs for HSET from either the map or
+// the --ordered-hashes array form.
+func importHashFields(value interface{}) ([]interface{}, error) {
+	switch fields := value.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		pairs := make([]interface{}, 0, 2*len(fields))
+		for _, name := range names {
+			v, ok := stringValue(fields[name])
+			if !ok {
+				return nil, fmt.Errorf("hash field %q is not a string", name)
+			}
+			pairs = append(pairs, name, v)
+		}
+		return pairs, nil
+	case []interface{}:
+		pairs := make([]interface{}, 0, 2*len(fields))
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("hash field is not an object")
+			}
+			name, ok := field["field"].(string)
+			if !ok {
+				return nil, fmt.Errorf("hash field has no name")
+			}
+			v, ok := stringValue(field["value"])
+			if !ok {
+				return nil, fmt.Errorf("hash field %q is not a string", name)
+			}
+			pairs = append(pairs, name, v)
+		}
+		return pairs, nil
+	default:
+		return nil, fmt.Errorf("hash value is not an object")
+	}
+}
+
+func importZMembers(value interface{}) ([]redis.Z, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("zset value is not an array")
+	}
+	members := make([]redis.Z, 0, len(items))
+	for _, m := range items {
+		member, ok := m.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zset member is not an object")
+		}
+		score, ok := member["Score"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("zset member has no numeric score")
+		}
+		name, ok := member["Member"].(string)
+		if !ok {
+			return nil, fmt.Errorf("zset member has no member")
+		}
+		members = append(members, redis.Z{Score: score, Member: name})
+	}
+	return members, nil
+}
+
+// streamMessage is a stream entry to restore with XADD. Values holds
+// field/value pairs sorted by field so commands are deterministic.
+type streamMessage struct {
+	ID     string
+	Values []interface{}
+}
+
+// importStreamMessages decodes exported stream messages and checks that
+// their IDs are strictly increasing, since XADD rejects an explicit ID that
+// is not greater than the stream's current top item.
+func importStreamMessages(value interface{}) ([]streamMessage, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("stream value is not an array")
+	}
+
+	messages := make([]streamMessage, 0, len(items))
+	var lastMs, lastSeq uint64
+	for i, m := range items {
+		message, ok := m.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("stream message is not an object")
+		}
+		id, ok := message["ID"].(string)
+		if !ok {
+			return nil, fmt.Errorf("stream message has no ID")
+		}
+
+		ms, seq, err := parseStreamID(id)
+		if err != nil {
+			return nil, err
+		}
+		if ms == 0 && seq == 0 {
+			return nil, fmt.Errorf("stream ID 0-0 cannot be restored")
+		}
+		if i > 0 && (ms < lastMs || (ms == lastMs && seq <= lastSeq)) {
+			return nil, fmt.Errorf("stream ID %s is not greater than the previous ID %d-%d", id, lastMs, lastSeq)
+		}
+		lastMs, lastSeq = ms, seq
+
+		fields, _ := message["Values"].(map[string]interface{})
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		values := make([]interface{}, 0, 2*len(fields))
+		for _, name := range names {
+			values = append(values, name, fields[name])
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("stream message %s has no fields", id)
+		}
+
+		messages = append(messages, streamMessage{ID: id, Values: values})
+	}
+	return messages, nil
+}
+
+// parseStreamID splits a stream ID of the form <ms>-<seq>.
+func parseStreamID(id string) (uint64, uint64, error) {
+	msPart, seqPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid stream ID %q", id)
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream ID %q", id)
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream ID %q", id)
+	}
+	return ms, seq, nil
+}
+
+var (
+	importConfig          ImportConfig
+	importPasswordFile    string
+	importOverwrite       bool
+	importSkipExisting    bool
+	importErrorOnConflict bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Load an export file into Redis",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open export file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+
+		importConfig.OnConflict, err = conflictMode(importOverwrite, importSkipExisting, importErrorOnConflict)
+		if err != nil {
+			return err
+		}
+
+		importConfig.RedisPassword, err = resolvePassword(importConfig.RedisPassword, importPasswordFile)
+		if err != nil {
+			return err
+		}
+
+		// Side files sit next to the uncompressed name of the export
+		exportPath := trimCompressionExt(args[0])
+		valuesFile := importConfig.ValuesFile
+		if valuesFile == "" {
+			if _, err := os.Stat(valuesFilePath(exportPath)); err == nil {
+				valuesFile = valuesFilePath(exportPath)
+			}
+		}
+		var values map[string]json.RawMessage
+		if valuesFile != "" {
+			f, err := os.Open(valuesFile)
+			if err != nil {
+				return fmt.Errorf("failed to open values file: %w", err)
+			}
+			values, err = readValueTable(f)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		manifestFile := importConfig.ManifestFile
+		if manifestFile == "" {
+			if _, err := os.Stat(manifestPath(exportPath)); err == nil {
+				manifestFile = manifestPath(exportPath)
+			}
+		}
+		var dict *zstd.Decoder
+		if manifestFile != "" {
+			manifest, err := readManifest(manifestFile)
+			if err != nil {
+				return err
+			}
+			if manifest.Dictionary != nil {
+				if dict, err = newDictDecoder(manifest.Dictionary); err != nil {
+					return err
+				}
+				defer dict.Close()
+			}
+		}
+
+		importer := NewImporter(importConfig)
+		importer.values = values
+		importer.dict = dict
+		defer func() { _ = importer.client.Close() }()
+
+		ctx := context.Background()
+
+		logrus.WithField("redis_addr", importConfig.RedisAddr).Info("Connecting to Redis")
+		if err := importer.client.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("failed to connect to Redis: %w", err)
+		}
+
+		start := time.Now()
+		result, err := importer.Import(ctx, file)
+		logrus.WithFields(logrus.Fields{
+			"imported_keys":  result.Imported,
+			"skipped_keys":   result.Skipped,
+			"existing_keys":  result.Existing,
+			"failed_keys":    result.Failed,
+			"total_duration": time.Since(start).Round(time.Second),
+		}).Info("Import finished")
+		if err != nil {
+			return err
+		}
+
+		if result.Failed > 0 {
+			return fmt.Errorf("%d keys failed to import", result.Failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVarP(&importConfig.RedisAddr, "addr", "a", "localhost:6379", "Redis server address")
+	importCmd.Flags().StringVarP(&importConfig.RedisPassword, "password", "p", "", "Redis password (prefer REDIS_PASSWORD or --password-file)")
+	importCmd.Flags().StringVar(&importPasswordFile, "password-file", "", "Read the Redis password from this file")
+	importCmd.Flags().IntVarP(&importConfig.RedisDB, "db", "d", 0, "Redis database number")
+	importCmd.Flags().IntVarP(&importConfig.BatchSize, "batch", "b", 1000, "Number of keys written per pipeline")
+	importCmd.Flags().BoolVar(&importConfig.Atomic, "atomic", false, "Apply each batch of keys atomically in a MULTI/EXEC transaction")
+	importCmd.Flags().BoolVar(&importOverwrite, "overwrite", false, "Replace keys that already exist in the target database (the default)")
+	importCmd.Flags().BoolVar(&importSkipExisting, "skip-existing", false, "Leave keys that already exist in the target database as they are, so a partial import can be re-run")
+	importCmd.Flags().BoolVar(&importErrorOnConflict, "error-on-conflict", false, "Stop the import at the first key that already exists in the target database")
+	importCmd.Flags().BoolVar(&importConfig.AllowSampled, "allow-sampled", false, "Import collections cut down by --sample-members as they are instead of skipping them")
+	importCmd.Flags().StringVar(&importConfig.KeyPrefix, "add-prefix", "", "Prepend this prefix to every imported key, such as tenant-a: to load an export into a shared instance")
+	importCmd.Flags().StringVar(&importConfig.ValuesFile, "values-file", "", "Values file of a --dedupe-values export (default: export.values.json next to export.json, if present)")
+	importCmd.Flags().StringVar(&importConfig.ManifestFile, "manifest-file", "", "Manifest holding the dictionary of a --compress-dict export (default: export.manifest.json next to export.json, if present)")
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/klauspost/compress/zstd"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImporter_Import_Types(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	export := `[
+{"key":"s","type":"string","value":"hello","ttl":60},
+{"key":"l","type":"list","value":["a","b"]},
+{"key":"set","type":"set","value":["x"]},
+{"key":"h","type":"hash","value":{"f2":"2","f1":"1"}},
+{"key":"oh","type":"hash","value":[{"field":"b","value":"2"},{"field":"a","value":"1"}]},
+{"key":"z","type":"zset","value":[{"Score":1.5,"Member":"m"}]},
+{"key":"meta","type":"string"}
+]`
+
+	mock.ExpectSet("s", "hello", 0).SetVal("OK")
+	mock.ExpectExpire("s", 60*time.Second).SetVal(true)
+	mock.ExpectDel("l").SetVal(0)
+	mock.ExpectRPush("l", "a", "b").SetVal(2)
+	mock.ExpectDel("set").SetVal(0)
+	mock.ExpectSAdd("set", "x").SetVal(1)
+	mock.ExpectDel("h").SetVal(0)
+	mock.ExpectHSet("h", "f1", "1", "f2", "2").SetVal(2)
+	mock.ExpectDel("oh").SetVal(0)
+	mock.ExpectHSet("oh", "b", "2", "a", "1").SetVal(2)
+	mock.ExpectDel("z").SetVal(0)
+	mock.ExpectZAdd("z", redis.Z{Score: 1.5, Member: "m"}).SetVal(1)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 6, result.Imported)
+	assert.Equal(t, 1, result.Skipped, "keys-only entries have nothing to restore")
+	assert.Zero(t, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_StreamRoundTrip(t *testing.T) {
+	exportDB, exportMock := redismock.NewClientMock()
+	defer func() { _ = exportDB.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_stream_roundtrip.json",
+		Workers:    1,
+		BatchSize:  10,
+		Quiet:      true,
+	}
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	exporter := &Exporter{client: exportDB, config: config}
+
+	messages := []redis.XMessage{
+		{ID: "1526919030474-55", Values: map[string]interface{}{"event": "login", "user": "alice"}},
+		{ID: "1526919030474-56", Values: map[string]interface{}{"event": "logout"}},
+		{ID: "1526985054069-0", Values: map[string]interface{}{"event": "login", "user": "bob"}},
+	}
+	exportMock.ExpectScan(0, "*", int64(10)).SetVal([]string{"events"}, 0)
+	exportMock.ExpectType("events").SetVal("stream")
+	exportMock.ExpectXRange("events", "-", "+").SetVal(messages)
+	exportMock.ExpectTTL("events").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, exportMock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	importDB, importMock := redismock.NewClientMock()
+	defer func() { _ = importDB.Close() }()
+
+	importer := &Importer{client: importDB, config: ImportConfig{BatchSize: 10}}
+
+	importMock.ExpectDel("events").SetVal(0)
+	importMock.ExpectXAdd(&redis.XAddArgs{Stream: "events", ID: "1526919030474-55", Values: []interface{}{"event", "login", "user", "alice"}}).SetVal("1526919030474-55")
+	importMock.ExpectXAdd(&redis.XAddArgs{Stream: "events", ID: "1526919030474-56", Values: []interface{}{"event", "logout"}}).SetVal("1526919030474-56")
+	importMock.ExpectXAdd(&redis.XAddArgs{Stream: "events", ID: "1526985054069-0", Values: []interface{}{"event", "login", "user", "bob"}}).SetVal("1526985054069-0")
+
+	result, err := importer.Import(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.NoError(t, importMock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_StreamIDsOutOfOrder(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	export := `[
+{"key":"events","type":"stream","value":[{"ID":"5-0","Values":{"a":"1"}},{"ID":"4-9","Values":{"a":"2"}}]},
+{"key":"s","type":"string","value":"ok"}
+]`
+
+	mock.ExpectSet("s", "ok", 0).SetVal("OK")
+
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Failed, "a stream with decreasing IDs is rejected before any write")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_CommandFailure(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	export := `[
+{"key":"a","type":"string","value":"1"},
+{"key":"b","type":"string","value":"2"}
+]`
+
+	mock.ExpectSet("a", "1", 0).SetErr(assert.AnError)
+	mock.ExpectSet("b", "2", 0).SetVal("OK")
+
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Failed)
+}
+
+func TestImporter_Import_Atomic(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 2, Atomic: true}}
+
+	export := `[
+{"key":"a","type":"string","value":"1","ttl":30},
+{"key":"l","type":"list","value":["x"]},
+{"key":"b","type":"string","value":"2"}
+]`
+
+	// Each batch of two keys is one MULTI/EXEC transaction
+	mock.ExpectTxPipeline()
+	mock.ExpectSet("a", "1", 0).SetVal("OK")
+	mock.ExpectExpire("a", 30*time.Second).SetVal(true)
+	mock.ExpectDel("l").SetVal(0)
+	mock.ExpectRPush("l", "x").SetVal(1)
+	mock.ExpectTxPipelineExec()
+	mock.ExpectTxPipeline()
+	mock.ExpectSet("b", "2", 0).SetVal("OK")
+	mock.ExpectTxPipelineExec()
+
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+	assert.Zero(t, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_NestedJSONValues(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	// Values nested by --parse-json-values are written back as JSON strings,
+	// with large numbers kept exact
+	export := `[
+{"key":"user","type":"string","value":{"name":"ada","id":12345678901234567890}},
+{"key":"h","type":"hash","value":{"tags":["a","b"],"n":"1"}}
+]`
+
+	mock.ExpectSet("user", `{"id":12345678901234567890,"name":"ada"}`, 0).SetVal("OK")
+	mock.ExpectDel("h").SetVal(0)
+	mock.ExpectHSet("h", "n", "1", "tags", `["a","b"]`).SetVal(2)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Zero(t, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConflictMode(t *testing.T) {
+	mode, err := conflictMode(false, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, conflictOverwrite, mode)
+
+	mode, err = conflictMode(true, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, conflictOverwrite, mode)
+
+	mode, err = conflictMode(false, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, conflictSkip, mode)
+
+	mode, err = conflictMode(false, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, conflictError, mode)
+
+	_, err = conflictMode(false, true, true)
+	assert.Error(t, err)
+}
+
+const conflictExport = `[
+{"key":"a","type":"string","value":"1"},
+{"key":"b","type":"string","value":"2"},
+{"key":"c","type":"string","value":"3"}
+]`
+
+func TestImporter_Import_Overwrite(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10, OnConflict: conflictOverwrite}}
+
+	// No EXISTS checks, every key is written
+	mock.ExpectSet("a", "1", 0).SetVal("OK")
+	mock.ExpectSet("b", "2", 0).SetVal("OK")
+	mock.ExpectSet("c", "3", 0).SetVal("OK")
+
+	result, err := importer.Import(context.Background(), strings.NewReader(conflictExport))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+	assert.Zero(t, result.Existing)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_SkipExisting(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 2, OnConflict: conflictSkip}}
+
+	// Each batch is checked with one pipeline of EXISTS before it is written
+	mock.ExpectExists("a").SetVal(1)
+	mock.ExpectExists("b").SetVal(0)
+	mock.ExpectSet("b", "2", 0).SetVal("OK")
+	mock.ExpectExists("c").SetVal(1)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(conflictExport))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 2, result.Existing)
+	assert.Zero(t, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_ErrorOnConflict(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 2, OnConflict: conflictError}}
+
+	mock.ExpectExists("a").SetVal(0)
+	mock.ExpectExists("b").SetVal(0)
+	mock.ExpectSet("a", "1", 0).SetVal("OK")
+	mock.ExpectSet("b", "2", 0).SetVal("OK")
+	mock.ExpectExists("c").SetVal(1)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(conflictExport))
+	assert.ErrorContains(t, err, "key c already exists")
+	assert.Equal(t, 2, result.Imported, "batches before the conflict are still written")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_AddPrefix(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10, OnConflict: conflictSkip, KeyPrefix: "tenant-a:"}}
+
+	export := `[
+{"key":"user:1","type":"string","value":"ada","ttl":60},
+{"key":"tags","type":"list","value":["x","y"]},
+{"key":"prefs","type":"hash","value":{"theme":"dark"}}
+]`
+
+	// The existence checks and writes all use the prefixed keys
+	mock.ExpectExists("tenant-a:user:1").SetVal(0)
+	mock.ExpectExists("tenant-a:tags").SetVal(0)
+	mock.ExpectExists("tenant-a:prefs").SetVal(0)
+	mock.ExpectSet("tenant-a:user:1", "ada", 0).SetVal("OK")
+	mock.ExpectExpire("tenant-a:user:1", 60*time.Second).SetVal(true)
+	mock.ExpectDel("tenant-a:tags").SetVal(0)
+	mock.ExpectRPush("tenant-a:tags", "x", "y").SetVal(2)
+	mock.ExpectDel("tenant-a:prefs").SetVal(0)
+	mock.ExpectHSet("tenant-a:prefs", "theme", "dark").SetVal(1)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+	assert.Zero(t, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_ExistsFailure(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10, OnConflict: conflictSkip}}
+
+	mock.ExpectExists("a").SetErr(assert.AnError)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(conflictExport))
+	assert.ErrorContains(t, err, "failed to check for existing keys")
+	assert.Zero(t, result.Imported, "nothing is written without knowing which keys exist")
+}
+
+func TestImporter_Import_Truncated(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	mock.ExpectSet("a", "1", 0).SetVal("OK")
+
+	result, err := importer.Import(context.Background(), strings.NewReader(`[{"key":"a","type":"string","value":"1"},{"key":"b"`))
+	assert.Error(t, err)
+	assert.Equal(t, 1, result.Imported, "entries before the truncation are still written")
+}
+
+func TestParseStreamID(t *testing.T) {
+	ms, seq, err := parseStreamID("1526919030474-55")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1526919030474), ms)
+	assert.Equal(t, uint64(55), seq)
+
+	for _, id := range []string{"", "12", "a-1", "1-b"} {
+		_, _, err := parseStreamID(id)
+		assert.Error(t, err, id)
+	}
+}
+
+func TestImporter_Import_Sampled(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	export := `[{"key":"members","type":"set","value":["a","b"],"sampled":true}]`
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Zero(t, result.Imported)
+	assert.Equal(t, 1, result.Skipped, "a partial collection isn't written by default")
+
+	importer.config.AllowSampled = true
+	mock.ExpectDel("members").SetVal(0)
+	mock.ExpectSAdd("members", "a", "b").SetVal(2)
+	result, err = importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_SkipsTruncated(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	result, err := importer.Import(context.Background(), strings.NewReader(`[{"key":"big","type":"string","value":"xxx","truncated":true}]`))
+	require.NoError(t, err)
+	assert.Zero(t, result.Imported)
+	assert.Equal(t, 1, result.Skipped)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImporter_Import_DetectsCompression(t *testing.T) {
+	const export = `[
+{"key":"a","type":"string","value":"1"},
+{"key":"b","type":"string","value":"2"}
+]`
+
+	gzipped := func(t *testing.T) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write([]byte(export))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+	zstded := func(t *testing.T) []byte {
+		w, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		defer func() { _ = w.Close() }()
+		return w.EncodeAll([]byte(export), nil)
+	}
+	plain := func(t *testing.T) []byte { return []byte(export) }
+
+	for name, encode := range map[string]func(*testing.T) []byte{"gzip": gzipped, "zstd": zstded, "plain": plain} {
+		t.Run(name, func(t *testing.T) {
+			db, mock := redismock.NewClientMock()
+			defer func() { _ = db.Close() }()
+			importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+			mock.ExpectSet("a", "1", 0).SetVal("OK")
+			mock.ExpectSet("b", "2", 0).SetVal("OK")
+
+			result, err := importer.Import(context.Background(), bytes.NewReader(encode(t)))
+			require.NoError(t, err)
+			assert.Equal(t, 2, result.Imported)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestImporter_Import_CorruptGzip(t *testing.T) {
+	db, _ := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10}}
+
+	_, err := importer.Import(context.Background(), bytes.NewReader([]byte{0x1f, 0x8b, 0x00, 0x00}))
+	assert.Error(t, err)
+}
+
+func TestTrimCompressionExt(t *testing.T) {
+	assert.Equal(t, "export.json", trimCompressionExt("export.json.gz"))
+	assert.Equal(t, "dir/export.json", trimCompressionExt("dir/export.json.zst"))
+	assert.Equal(t, "export.json", trimCompressionExt("export.json"))
+	assert.Equal(t, "export.manifest.json", manifestPath(trimCompressionExt("export.json.zstd")))
+}
+
+func TestImporter_Import_Unwrapped(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	importer := &Importer{client: db, config: ImportConfig{BatchSize: 10, OnConflict: conflictSkip}}
+
+	// As written with --array-wrapper=false
+	export := `{"key":"user:1","type":"string","value":"ada"}
+{"key":"tags","type":"list","value":["x","y"]}
+`
+
+	mock.ExpectExists("user:1").SetVal(0)
+	mock.ExpectExists("tags").SetVal(0)
+	mock.ExpectSet("user:1", "ada", 0).SetVal("OK")
+	mock.ExpectDel("tags").SetVal(0)
+	mock.ExpectRPush("tags", "x", "y").SetVal(2)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
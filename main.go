@@ -1,48 +1,536 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"math"
+	"math/rand"
+	"net"
 	"os"
+	"os/signal"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/template"
 	"time"
 
+	"github.com/itchyny/gojq"
 	"github.com/redis/go-redis/v9"
+	"github.com/schollz/progressbar/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var version = "dev"
 
 type Config struct {
 	RedisAddr     string
+	Sources       []string // every --addr when several are merged into one export
+	Collisions    bool     // mark keys of a merged export written from more than one source
 	RedisPassword string
 	RedisDB       int
 	OutputFile    string
 	Workers       int
 	BatchSize     int
 	LogLevel      string
+
+	ConnectRetries int
+	PoolRetries    int
+	Reconnects     int // retries after a lost connection
+	Preview        bool
+	PoolSize       int         // Redis connections, 0 for twice the workers
+	FileMode       os.FileMode // mode of created export files, 0 for defaultFileMode
+	ConnectWait    time.Duration
+	FieldMap       map[string]string
+	Ordered        bool
+	ReorderWindow  int
+	RESP3          bool
+	KeepAlive      time.Duration // TCP keepalive period, negative to disable
+	Nagle          bool          // --tcp-nodelay=false: leave Nagle's algorithm on
+	TLS            *tls.Config   // --tls settings, nil for plain TCP
+	PrefixRules    []PrefixRule
+	Renames        map[string]string // --rename-command, keyed by lowercase original name
+	WorkerJitter   time.Duration
+	Warmup         bool
+	Format         string
+	Unwrapped      bool // --array-wrapper=false: JSON objects one per line, without array framing
+	NoHTMLEscape   bool // write <, > and & in JSON output verbatim
+	RowGroupSize   int
+	NoModules      bool // --modules=false: module types fail as unsupported instead of being exported
+	SizeHistogram  bool
+	TTLHistogram   bool
+	TopKeys        int
+	SlowThreshold  time.Duration
+	WorkerStats    bool
+	StatsJSON      string // file the final statistics are written to as JSON
+	Archive        string // zip bundling the output and its side files
+	Types          []string
+	ExcludeTypes   []string
+	SkipValuesFor  []string // types exported with key, type and TTL only
+	ErrorsFile     string
+	Watch          time.Duration
+	MaxDuration    time.Duration // wall-clock limit after which the export stops with a partial output
+	KeysOnly       bool
+	WriteBuffer    int
+	ReplicaRead    bool
+	OrderedHashes  bool
+	OTelEndpoint   string
+	KeyStart       string
+	KeyEnd         string
+	Quiet          bool
+	StatsInterval  time.Duration // progress log cadence, 0 for none
+	Milestones     bool          // log progress at 1k, 10k, 100k... keys instead of on an interval
+	WithFreq       bool
+	WithRefcount   bool
+	KeysFile       string
+	RDBFile        string // dump to export from instead of a live server
+	AutoWorkers    bool
+	MinWorkers     int
+	KeyAffinity    bool
+	TypeWorkers    map[string]int // dedicated workers per key type
+	WithSlot       bool
+	JQ             string
+	JQDropNull     bool
+	Template       string
+	FilterScript   string // Lua predicate deciding which keys are exported
+	ClientName     string
+	TTLFormat      string
+	TTLPrecision   string
+	Shard          int // 1-based shard handled by this process, with ShardCount
+	ShardCount     int
+	ProgressBar    bool
+	MaxValueBytes  int
+	ListLimit      int
+	SampleMembers  int    // random members fetched from each set, sorted set and hash
+	ZsetMinScore   string // Redis score bound, such as 100 or (100 for exclusive
+	ZsetMaxScore   string
+	SkipEmpty      bool
+	ExtraFormats   []string // written in the same pass, next to OutputFile
+	SampleRate     float64  // fraction of scanned keys to export, 0 or 1 for all
+	VanishedWarn   float64  // fraction of vanished keys that triggers a warning, 0 to disable
+	Profile        string
+	ProfileFile    string
+	ReportBinary   bool
+	NamespaceStats bool
+	NamespaceDepth int
+	GCSBucket      string
+	GCSObject      string
+	AzureContainer string
+	AzureBlob      string
+	DedupeValues   bool
+	Match          string
+	PersistentOnly bool
+	Numbers        string
+	ParseJSON      bool // embed string and hash values holding JSON objects or arrays
+	TTLWithValue   bool // read each TTL in the same pipeline as the value
+	Manifest       bool
+	SinceFile      string
+	AdaptiveScan   bool
+	TagRouting     bool // scan only the cluster node owning a --match hash tag
+	Consistent     bool // record the replication offset window in the manifest
+	CompressDict   bool
+}
+
+// PrefixRule rewrites keys starting with Old to start with New instead.
+type PrefixRule struct {
+	Old string
+	New string
+}
+
+// parsePrefixRules parses old=new pairs from --rewrite-prefix.
+func parsePrefixRules(values []string) ([]PrefixRule, error) {
+	rules := make([]PrefixRule, 0, len(values))
+	for _, value := range values {
+		old, replacement, ok := strings.Cut(value, "=")
+		if !ok || old == "" {
+			return nil, fmt.Errorf("invalid prefix rule %q, expected old=new", value)
+		}
+		rules = append(rules, PrefixRule{Old: old, New: replacement})
+	}
+	return rules, nil
 }
 
 type RedisEntry struct {
 	Key   string      `json:"key"`
 	Type  string      `json:"type"`
-	Value interface{} `json:"value"`
+	Value interface{} `json:"value,omitempty"`
 	TTL   int64       `json:"ttl,omitempty"`
+	Freq  int         `json:"freq,omitempty"`
+	Slot  *int        `json:"slot,omitempty"`
+	Node  string      `json:"node,omitempty"`
+
+	// TTLMillis is the TTL in milliseconds with --ttl-precision ms, for keys
+	// whose expiry would be cut short by whole seconds.
+	TTLMillis int64 `json:"ttl_ms,omitempty"`
+
+	// Refcount is the OBJECT REFCOUNT of the value with --with-refcount.
+	// Shared is set instead for Redis's shared objects, whose refcount is a
+	// sentinel rather than a count.
+	Refcount int64 `json:"refcount,omitempty"`
+	Shared   bool  `json:"shared,omitempty"`
+
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Source is the --addr a key was read from when several are merged into
+	// one export. Collision is set on a key already written from another
+	// source.
+	Source    string `json:"source,omitempty"`
+	Collision bool   `json:"collision,omitempty"`
+
+	// Sampled is set on sets, sorted sets and hashes cut down to a random
+	// subset of their members by --sample-members.
+	Sampled bool `json:"sampled,omitempty"`
+
+	// ScoreRange is set on sorted sets limited to a score window; members
+	// outside it were not exported.
+	ScoreRange *ScoreRange `json:"score_range,omitempty"`
+
+	// ValueRef replaces Value with --dedupe-values: the SHA-256 of the value
+	// in the export's values file.
+	ValueRef string `json:"value_ref,omitempty"`
+
+	// ValueZstd replaces Value with --compress-dict: the value's JSON
+	// encoding compressed against the dictionary in the export's manifest.
+	ValueZstd []byte `json:"value_zstd,omitempty"`
+
+	seq     int64         // scan order of the key, used by --ordered
+	size    int           // serialized value size, used by --size-histogram
+	elapsed time.Duration // processing time, set for keys over --slow-key-threshold
+	ttlText string        // TTL rendered for --ttl-format, replaces ttl in JSON output
+	binary  bool          // value contains invalid UTF-8, used by --report-binary
+}
+
+// UnmarshalJSON decodes an entry, accepting a ttl written with any
+// --ttl-format and keeping a value written by --numbers json.Number exact.
+func (r *RedisEntry) UnmarshalJSON(data []byte) error {
+	type plain RedisEntry
+	aux := struct {
+		*plain
+		Value json.RawMessage `json:"value,omitempty"`
+		TTL   json.RawMessage `json:"ttl,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	value, err := decodeValue(aux.Value, r.Type)
+	if err != nil {
+		return err
+	}
+	r.Value = value
+
+	ttl, err := parseTTL(aux.TTL)
+	if err != nil {
+		return err
+	}
+	r.TTL = ttl
+	return nil
+}
+
+// decodeValue decodes an exported value of keyType. A bare number, which
+// only a string exported with --numbers json.Number produces, is returned as
+// a json.Number holding the exact digits rather than a lossy float64. Numbers
+// in string and hash values, which hold JSON nested by --parse-json-values,
+// are kept exact the same way.
+func decodeValue(raw json.RawMessage, keyType string) (interface{}, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if raw[0] == '-' || (raw[0] >= '0' && raw[0] <= '9') {
+		if !isJSONNumber(string(raw)) {
+			return nil, fmt.Errorf("invalid number value %s", raw)
+		}
+		return json.Number(raw), nil
+	}
+
+	var value interface{}
+	if keyType == "string" || keyType == "hash" {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// parseTTL converts a JSON ttl in seconds, duration or RFC3339 form to
+// seconds.
+func parseTTL(raw json.RawMessage) (int64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var seconds int64
+	if err := json.Unmarshal(raw, &seconds); err == nil {
+		return seconds, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return 0, fmt.Errorf("invalid ttl %s", raw)
+	}
+	if d, err := time.ParseDuration(text); err == nil {
+		return int64(d.Seconds()), nil
+	}
+	expiry, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl %q", text)
+	}
+	remaining := int64(expiry.Sub(timeNow()).Seconds())
+	if remaining < 1 {
+		// Already expired; keep it expiring rather than persistent.
+		remaining = 1
+	}
+	return remaining, nil
+}
+
+// keyTask is a scanned key queued for a worker.
+type keyTask struct {
+	key     string
+	seq     int64
+	keyType string // set by classifyKeys, empty when not yet looked up
+}
+
+// reorderBuffer re-sequences entries that arrive out of scan order. At most
+// window entries are held back waiting for a missing sequence number; past
+// that, the gap is skipped so a single slow or failed key can't stall output.
+type reorderBuffer struct {
+	next    int64
+	window  int
+	pending map[int64]*RedisEntry
+}
+
+func newReorderBuffer(window int) *reorderBuffer {
+	if window < 1 {
+		window = 1
+	}
+	return &reorderBuffer{
+		window:  window,
+		pending: make(map[int64]*RedisEntry),
+	}
+}
+
+// push adds entry to the buffer and returns the entries that are now ready
+// to be written, in sequence order. Entries whose slot was already skipped
+// are returned immediately.
+func (b *reorderBuffer) push(entry *RedisEntry) []*RedisEntry {
+	if entry.seq < b.next {
+		return []*RedisEntry{entry}
+	}
+	b.pending[entry.seq] = entry
+
+	var ready []*RedisEntry
+	for {
+		for {
+			next, ok := b.pending[b.next]
+			if !ok {
+				break
+			}
+			delete(b.pending, b.next)
+			ready = append(ready, next)
+			b.next++
+		}
+
+		if len(b.pending) <= b.window {
+			return ready
+		}
+		b.next = b.minPending()
+	}
+}
+
+// flush returns all buffered entries in sequence order.
+func (b *reorderBuffer) flush() []*RedisEntry {
+	ready := make([]*RedisEntry, 0, len(b.pending))
+	for len(b.pending) > 0 {
+		b.next = b.minPending()
+		ready = append(ready, b.pending[b.next])
+		delete(b.pending, b.next)
+		b.next++
+	}
+	return ready
+}
+
+func (b *reorderBuffer) minPending() int64 {
+	first := true
+	var lowest int64
+	for seq := range b.pending {
+		if first || seq < lowest {
+			lowest = seq
+			first = false
+		}
+	}
+	return lowest
+}
+
+// entryFields lists the JSON field names of RedisEntry that may be renamed
+// with --field-map.
+var entryFields = []string{"key", "type", "value", "ttl"}
+
+// --ttl-format values.
+const (
+	ttlFormatSeconds  = "seconds"
+	ttlFormatISO8601  = "iso8601"
+	ttlFormatDuration = "duration"
+)
+
+// --ttl-precision values.
+const (
+	ttlPrecisionSeconds = "s"
+	ttlPrecisionMillis  = "ms"
+)
+
+// --numbers values.
+const (
+	numbersString = "string"
+	numbersJSON   = "json.Number"
+)
+
+// jsonNumberPattern matches the JSON number grammar.
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// isJSONNumber reports whether s is a valid JSON number literal. Strings such
+// as "007" or "+1" are not, and stay strings with --numbers json.Number.
+func isJSONNumber(s string) bool {
+	return jsonNumberPattern.MatchString(s)
+}
+
+// validateNumbers checks that mode is a supported --numbers value.
+func validateNumbers(mode string) error {
+	switch mode {
+	case "", numbersString, numbersJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid --numbers %q, expected %s or %s", mode, numbersString, numbersJSON)
+	}
+}
+
+// timeNow is the clock used to compute absolute expiry times.
+var timeNow = time.Now
+
+// validateTTLFormat checks that format is a supported --ttl-format value.
+func validateTTLFormat(format string) error {
+	switch format {
+	case "", ttlFormatSeconds, ttlFormatISO8601, ttlFormatDuration:
+		return nil
+	default:
+		return fmt.Errorf("unsupported TTL format %q (want seconds, iso8601 or duration)", format)
+	}
+}
+
+// validateTTLPrecision checks that precision is a supported --ttl-precision
+// value.
+func validateTTLPrecision(precision string) error {
+	switch precision {
+	case "", ttlPrecisionSeconds, ttlPrecisionMillis:
+		return nil
+	default:
+		return fmt.Errorf("unsupported TTL precision %q (want s or ms)", precision)
+	}
+}
+
+// formatTTL renders ttl for the iso8601 and duration formats. It returns an
+// empty string for the default seconds format.
+func formatTTL(ttl time.Duration, format string) string {
+	switch format {
+	case ttlFormatISO8601:
+		return timeNow().Add(ttl).UTC().Format(time.RFC3339)
+	case ttlFormatDuration:
+		return ttl.String()
+	default:
+		return ""
+	}
+}
+
+// validateFieldMap checks that every mapped field exists and that the
+// resulting output field names are unique.
+func validateFieldMap(fieldMap map[string]string) error {
+	known := make(map[string]bool, len(entryFields))
+	for _, name := range entryFields {
+		known[name] = true
+	}
+
+	seen := make(map[string]string, len(entryFields))
+	for _, name := range entryFields {
+		out := name
+		if mapped, ok := fieldMap[name]; ok {
+			out = mapped
+		}
+		if out == "" {
+			return fmt.Errorf("field %q cannot be mapped to an empty name", name)
+		}
+		if other, ok := seen[out]; ok {
+			return fmt.Errorf("fields %q and %q both map to %q", other, name, out)
+		}
+		seen[out] = name
+	}
+
+	for name := range fieldMap {
+		if !known[name] {
+			return fmt.Errorf("unknown field %q in field map", name)
+		}
+	}
+
+	return nil
 }
 
 type Exporter struct {
 	client *redis.Client
 	config Config
+
+	failures    chan<- FailedKey // set during Export when --errors-file is used
+	vanished    atomic.Int64     // keys deleted between SCAN and fetch
+	poolRetries atomic.Int64     // keys retried after a connection pool timeout
+	reconnects  atomic.Int64     // commands retried after a lost connection
+	scanPauses  atomic.Int64     // times the scan waited for the output writer
+	failed      atomic.Int64     // keys that could not be exported
+	noFreq      atomic.Bool      // set once the server rejects OBJECT FREQ
+	modules     moduleTypes      // keys exported per module type
+	latency     latencyStats     // per-key processing time, used by --auto-workers
+	workerStats workerStats      // per-worker counters, set during Export with --worker-stats
+
+	slotNodes []string           // primary node address per cluster slot, for --with-slot
+	jq        *gojq.Code         // compiled --jq expression
+	tmpl      *template.Template // compiled --template
+	filter    *redis.Script      // --filter-script predicate, loaded on the server
+	sources   []*Exporter        // extra --addr sources, opened by export unless set
+
+	output io.Writer // replaces the configured output destination, used by serve
+
+	scanErr error // set by scanKeys before it closes keysChan if the scan failed
+
+	results chan<- *RedisEntry // set during export, its fill level paces the scan
+
+	since     time.Time // --since-file cutoff, keys idle since before it are skipped
+	sinceBase string    // output of the export the --since-file manifest describes
+
+	dictionary  []byte               // trained by --compress-dict, stored in the manifest
+	replication *ManifestReplication // --consistent offset window, stored in the manifest
 }
 
-func NewExporter(config Config) *Exporter {
-	rdb := redis.NewClient(&redis.Options{
+// newRedisOptions builds the client options for config.
+func newRedisOptions(config Config) *redis.Options {
+	opts := &redis.Options{
 		Addr:         config.RedisAddr,
 		Password:     config.RedisPassword,
 		DB:           config.RedisDB,
@@ -51,7 +539,168 @@ func NewExporter(config Config) *Exporter {
 		PoolTimeout:  30 * time.Second,   // Longer pool timeout
 		ReadTimeout:  10 * time.Second,   // Longer read timeout for large values
 		WriteTimeout: 10 * time.Second,   // Longer write timeout
-	})
+		Protocol:     2,
+		ClientName:   config.ClientName,
+		Dialer:       newDialer(config),
+	}
+
+	// An explicit pool size decouples connections from workers; workers
+	// beyond it queue for a connection.
+	if config.PoolSize > 0 {
+		opts.PoolSize = config.PoolSize
+		opts.MinIdleConns = min(config.Workers, config.PoolSize)
+	}
+
+	if config.RESP3 {
+		opts.Protocol = 3
+	}
+
+	if config.ReplicaRead {
+		opts.OnConnect = readOnlyOnConnect
+	}
+
+	return opts
+}
+
+// newDialer returns the function that opens connections to Redis. TCP
+// keepalives are sent every --keepalive, so load balancers with aggressive
+// idle timeouts don't drop connections that sit idle during a slow export.
+// Go disables Nagle's algorithm on TCP connections; --tcp-nodelay=false turns
+// it back on.
+func newDialer(config Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: config.KeepAlive,
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if tcp, ok := conn.(*net.TCPConn); ok && config.Nagle {
+			if err := tcp.SetNoDelay(false); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+		}
+		if config.TLS == nil {
+			return conn, nil
+		}
+
+		// go-redis only sets up TLS in its own dialer
+		tlsConfig := config.TLS
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// readOnlyOnConnect issues READONLY on each new connection so reads may be
+// served by a cluster replica. Standalone replicas are read-only already and
+// reject the command, which is ignored.
+func readOnlyOnConnect(ctx context.Context, cn *redis.Conn) error {
+	err := cn.ReadOnly(ctx).Err()
+	if err != nil && strings.Contains(err.Error(), "cluster support disabled") {
+		return nil
+	}
+	return err
+}
+
+// checkReplica warns when --replica-read is set but the server is a primary.
+func (e *Exporter) checkReplica(ctx context.Context) {
+	info, err := e.client.Info(ctx, "replication").Result()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get replication info")
+		return
+	}
+	if strings.Contains(info, "role:master") {
+		logrus.Warn("--replica-read is set but the server is a primary; the export will load the primary")
+		return
+	}
+	logrus.Info("Reading from replica; keys written very recently may be missing due to replication lag")
+}
+
+// infoField returns the value of field in the output of INFO.
+func infoField(info, field string) (string, bool) {
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), field+":"); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// logServerInfo logs the server's key count and memory use before the
+// export starts, so operators can see its scope and whether the server is
+// short of memory. INFO memory and DBSIZE are sent in one round trip, and a
+// failure only skips the log.
+func (e *Exporter) logServerInfo(ctx context.Context) {
+	pipe := e.client.Pipeline()
+	infoCmd := pipe.Info(ctx, "memory")
+	sizeCmd := pipe.DBSize(ctx)
+	_, _ = pipe.Exec(ctx)
+
+	fields := logrus.Fields{}
+	if keys, err := sizeCmd.Result(); err == nil {
+		fields["db_keys"] = keys
+	} else {
+		logrus.WithError(err).Debug("Failed to get database size")
+	}
+
+	if info, err := infoCmd.Result(); err != nil {
+		logrus.WithError(err).Debug("Failed to get memory info")
+	} else if value, ok := infoField(info, "used_memory"); ok {
+		used, _ := strconv.ParseInt(value, 10, 64)
+		fields["used_memory"] = formatBytes(used)
+		value, _ = infoField(info, "maxmemory")
+		maxMemory, _ := strconv.ParseInt(value, 10, 64)
+		if maxMemory > 0 {
+			fields["maxmemory"] = formatBytes(maxMemory)
+			fields["memory_used_pct"] = math.Round(float64(used)*1000/float64(maxMemory)) / 10
+		} else {
+			fields["maxmemory"] = "unlimited"
+		}
+		if policy, ok := infoField(info, "maxmemory_policy"); ok {
+			fields["maxmemory_policy"] = policy
+		}
+	}
+
+	if len(fields) > 0 {
+		logrus.WithFields(fields).Info("Redis server info")
+	}
+}
+
+// warnVanished warns that the export is likely incomplete when more than
+// the --vanished-warn-ratio fraction of the keys it fetched had vanished
+// since SCAN. A few vanished keys are normal expiry; many more usually mean
+// the server is evicting keys because it is at maxmemory.
+func (e *Exporter) warnVanished(processed int64) {
+	vanished := e.vanished.Load()
+	if e.config.VanishedWarn <= 0 || vanished == 0 {
+		return
+	}
+	ratio := float64(vanished) / float64(processed+vanished)
+	if ratio <= e.config.VanishedWarn {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"vanished_keys": vanished,
+		"vanished_pct":  math.Round(ratio*1000) / 10,
+	}).Warn("Export is likely incomplete: many keys vanished between SCAN and fetch, which usually means the server is evicting keys under maxmemory")
+}
+
+func NewExporter(config Config) *Exporter {
+	rdb := redis.NewClient(newRedisOptions(config))
+	if len(config.Renames) > 0 {
+		rdb.AddHook(renameHook(config.Renames))
+	}
 
 	return &Exporter{
 		client: rdb,
@@ -59,220 +708,1959 @@ func NewExporter(config Config) *Exporter {
 	}
 }
 
+// connect pings Redis, retrying up to ConnectRetries times with exponential
+// backoff starting at ConnectWait.
+func (e *Exporter) connect(ctx context.Context) (string, error) {
+	wait := e.config.ConnectWait
+	for attempt := 0; ; attempt++ {
+		pong, err := e.client.Ping(ctx).Result()
+		if err == nil {
+			return pong, nil
+		}
+		if attempt >= e.config.ConnectRetries {
+			return "", err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"attempt":     attempt + 1,
+			"max_retries": e.config.ConnectRetries,
+			"wait":        wait,
+		}).Warn("Redis not reachable, retrying: ", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// warmup pre-establishes the connection pool by issuing concurrent pings, one
+// per idle connection the pool is configured to keep.
+func (e *Exporter) warmup(ctx context.Context) error {
+	conns := e.client.Options().MinIdleConns
+	if conns < 1 {
+		conns = 1
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, conns)
+	for i := 0; i < conns; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.client.Ping(ctx).Err(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	if err, ok := <-errs; ok {
+		return fmt.Errorf("failed to warm up connection pool: %w", err)
+	}
+
+	logrus.WithField("connections", e.client.PoolStats().TotalConns).Debug("Connection pool warmed up")
+	return nil
+}
+
 func (e *Exporter) getValueByType(ctx context.Context, key string, keyType string) (interface{}, error) {
+	if value := e.queueValue(ctx, e.client, key, keyType); value != nil {
+		return value()
+	}
+	if !e.config.NoModules {
+		return e.getModuleValue(ctx, key, keyType)
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", keyType)
+}
+
+// queueValue sends the command that fetches the value of key on c and
+// returns a function reading the value from its reply. On a pipeline, the
+// reply is only there once the pipeline has run. It returns nil for module
+// types, which take more than one command.
+func (e *Exporter) queueValue(ctx context.Context, c redis.Cmdable, key, keyType string) func() (interface{}, error) {
 	switch keyType {
 	case "string":
-		return e.client.Get(ctx, key).Result()
+		cmd := c.Get(ctx, key)
+		return func() (interface{}, error) { return cmd.Result() }
 	case "list":
-		return e.client.LRange(ctx, key, 0, -1).Result()
+		cmd := c.LRange(ctx, key, 0, e.listStop())
+		return func() (interface{}, error) { return cmd.Result() }
 	case "set":
-		return e.client.SMembers(ctx, key).Result()
+		var cmd *redis.StringSliceCmd
+		if e.config.SampleMembers > 0 {
+			cmd = c.SRandMemberN(ctx, key, int64(e.config.SampleMembers))
+		} else {
+			cmd = c.SMembers(ctx, key)
+		}
+		return func() (interface{}, error) { return cmd.Result() }
 	case "zset":
-		return e.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+		var cmd *redis.ZSliceCmd
+		if e.config.SampleMembers > 0 {
+			cmd = c.ZRandMemberWithScores(ctx, key, e.config.SampleMembers)
+		} else if r := e.scoreRange(); r != nil {
+			cmd = c.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: r.Min, Max: r.Max})
+		} else {
+			cmd = c.ZRangeWithScores(ctx, key, 0, -1)
+		}
+		return func() (interface{}, error) { return cmd.Result() }
 	case "hash":
-		return e.client.HGetAll(ctx, key).Result()
+		var fields func() (map[string]string, error)
+		if e.config.SampleMembers > 0 {
+			// HRANDFIELD returns the sampled fields as pairs
+			cmd := c.HRandFieldWithValues(ctx, key, e.config.SampleMembers)
+			fields = func() (map[string]string, error) {
+				pairs, err := cmd.Result()
+				if err != nil {
+					return nil, err
+				}
+				sampled := make(map[string]string, len(pairs))
+				for _, pair := range pairs {
+					sampled[pair.Key] = pair.Value
+				}
+				return sampled, nil
+			}
+		} else {
+			fields = c.HGetAll(ctx, key).Result
+		}
+		return func() (interface{}, error) {
+			f, err := fields()
+			if err != nil || !e.config.OrderedHashes {
+				return f, err
+			}
+			return sortedHashFields(f), nil
+		}
 	case "stream":
-		return e.client.XRange(ctx, key, "-", "+").Result()
+		cmd := c.XRange(ctx, key, "-", "+")
+		return func() (interface{}, error) { return cmd.Result() }
+	default:
+		return nil
+	}
+}
+
+// getValueWithTTL fetches the value of key and its TTL in one pipeline, for
+// --ttl-with-value, so the TTL is read straight after the value rather than
+// a round trip later and the expiry matches the value captured. ok is false
+// for module types, whose TTL is left to be read separately.
+func (e *Exporter) getValueWithTTL(ctx context.Context, key, keyType string) (value interface{}, ttl time.Duration, ok bool, err error) {
+	pipe := e.client.Pipeline()
+	read := e.queueValue(ctx, pipe, key, keyType)
+	if read == nil {
+		value, err = e.getValueByType(ctx, key, keyType)
+		return value, 0, false, err
+	}
+	var ttlCmd *redis.DurationCmd
+	if e.config.TTLPrecision == ttlPrecisionMillis {
+		ttlCmd = pipe.PTTL(ctx, key)
+	} else {
+		ttlCmd = pipe.TTL(ctx, key)
+	}
+	_, _ = pipe.Exec(ctx)
+
+	if value, err = read(); err != nil {
+		return nil, 0, false, err
+	}
+	if ttl, err = ttlCmd.Result(); err != nil {
+		return nil, 0, false, fmt.Errorf("failed to get TTL: %w", err)
+	}
+	return value, ttl, true, nil
+}
+
+// isEmptyCollection reports whether value is a list, set, sorted set or hash
+// with no elements. Streams are not included: an empty stream is a real key.
+func isEmptyCollection(value interface{}) bool {
+	switch v := value.(type) {
+	case []string:
+		return len(v) == 0
+	case []redis.Z:
+		return len(v) == 0
+	case map[string]string:
+		return len(v) == 0
+	case []HashField:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// listStop returns the LRANGE stop index for list values: the last element,
+// or element N-1 with --list-limit N.
+func (e *Exporter) listStop() int64 {
+	if e.config.ListLimit > 0 {
+		return int64(e.config.ListLimit) - 1
+	}
+	return -1
+}
+
+// listTruncated reports whether a list fetched with --list-limit has more
+// elements than were exported. LLEN is only needed when the limit was
+// reached.
+func (e *Exporter) listTruncated(ctx context.Context, key string, items []string) (bool, error) {
+	if e.config.ListLimit <= 0 || len(items) < e.config.ListLimit {
+		return false, nil
+	}
+	length, err := e.client.LLen(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return length > int64(len(items)), nil
+}
+
+// collectionSampled reports whether a set, sorted set or hash fetched with
+// --sample-members has more members than were exported. The cardinality is
+// only needed when the sample is full, since a collection smaller than the
+// sample size is returned whole.
+func (e *Exporter) collectionSampled(ctx context.Context, key, keyType string, value interface{}) (bool, error) {
+	if e.config.SampleMembers <= 0 {
+		return false, nil
+	}
+	var n int
+	switch v := value.(type) {
+	case []string:
+		n = len(v)
+	case []redis.Z:
+		n = len(v)
+	case map[string]string:
+		n = len(v)
+	case []HashField:
+		n = len(v)
+	default:
+		return false, nil
+	}
+	if n < e.config.SampleMembers {
+		return false, nil
+	}
+
+	var card *redis.IntCmd
+	switch keyType {
+	case "set":
+		card = e.client.SCard(ctx, key)
+	case "zset":
+		card = e.client.ZCard(ctx, key)
+	case "hash":
+		card = e.client.HLen(ctx, key)
 	default:
-		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+		return false, nil
+	}
+	size, err := card.Result()
+	if err != nil {
+		return false, err
+	}
+	return size > int64(n), nil
+}
+
+// ScoreRange is the score window sorted sets are limited to with
+// --zset-min-score and --zset-max-score, in Redis's ZRANGEBYSCORE syntax.
+type ScoreRange struct {
+	Min string `json:"min"`
+	Max string `json:"max"`
+}
+
+// scoreRange returns the configured sorted set score window, or nil when
+// whole sorted sets are exported. A missing bound is unbounded.
+func (e *Exporter) scoreRange() *ScoreRange {
+	if e.config.ZsetMinScore == "" && e.config.ZsetMaxScore == "" {
+		return nil
+	}
+	r := &ScoreRange{Min: e.config.ZsetMinScore, Max: e.config.ZsetMaxScore}
+	if r.Min == "" {
+		r.Min = "-inf"
+	}
+	if r.Max == "" {
+		r.Max = "+inf"
+	}
+	return r
+}
+
+// validScoreBound reports whether bound is a ZRANGEBYSCORE bound: a number
+// or -inf/+inf, optionally prefixed with ( to exclude it.
+func validScoreBound(bound string) bool {
+	bound = strings.TrimPrefix(bound, "(")
+	switch strings.ToLower(bound) {
+	case "-inf", "+inf", "inf":
+		return true
+	}
+	_, err := strconv.ParseFloat(bound, 64)
+	return err == nil
+}
+
+// HashField is a single hash field, used by --ordered-hashes.
+type HashField struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// sortedHashFields returns the fields of a hash sorted by field name, so the
+// output is stable across runs.
+func sortedHashFields(fields map[string]string) []HashField {
+	ordered := make([]HashField, 0, len(fields))
+	for field, value := range fields {
+		ordered = append(ordered, HashField{Field: field, Value: value})
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Field < ordered[j].Field })
+	return ordered
+}
+
+// rejsonType is the type reported by keys created by the RedisJSON module.
+const rejsonType = "ReJSON-RL"
+
+// getModuleValue fetches the value of a module-backed key. RedisJSON documents
+// are fetched with JSON.GET and embedded as JSON; any other module type falls
+// back to DUMP, which is exported as base64-encoded bytes.
+func (e *Exporter) getModuleValue(ctx context.Context, key string, keyType string) (interface{}, error) {
+	if keyType == rejsonType {
+		doc, err := e.client.Do(ctx, "JSON.GET", key).Text()
+		if err != nil {
+			return nil, err
+		}
+		e.modules.observe(keyType)
+		return json.RawMessage(doc), nil
+	}
+
+	dump, err := e.client.Dump(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	e.modules.observe(keyType)
+	return []byte(dump), nil
+}
+
+// moduleTypes counts the exported keys of each module type, which are
+// detected as any type other than the six native ones.
+type moduleTypes struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// observe counts an exported key of keyType, logging the first of each
+// type so it is clear which modules the data depends on.
+func (m *moduleTypes) observe(keyType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]int64)
+	}
+	if m.counts[keyType] == 0 {
+		how := "DUMP"
+		if keyType == rejsonType {
+			how = "JSON.GET"
+		}
+		logrus.WithFields(logrus.Fields{
+			"type":    keyType,
+			"command": how,
+		}).Info("Exporting keys of a module type")
+	}
+	m.counts[keyType]++
+}
+
+// merge adds the counts of other to m.
+func (m *moduleTypes) merge(other *moduleTypes) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for keyType, n := range other.counts {
+		if m.counts == nil {
+			m.counts = make(map[string]int64)
+		}
+		m.counts[keyType] += n
+	}
+}
+
+// log logs the number of keys exported of each module type, if there were
+// any.
+func (m *moduleTypes) log() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.counts) == 0 {
+		return
+	}
+	fields := make(logrus.Fields, len(m.counts))
+	for keyType, n := range m.counts {
+		fields[keyType] = n
+	}
+	logrus.WithFields(fields).Info("Exported module types")
+}
+
+// rewriteKey applies the first matching prefix rule to key.
+func (e *Exporter) rewriteKey(key string) string {
+	for _, rule := range e.config.PrefixRules {
+		if strings.HasPrefix(key, rule.Old) {
+			return rule.New + key[len(rule.Old):]
+		}
+	}
+	return key
+}
+
+// keyError is a processKey failure that occurred after the key's type was
+// known.
+type keyError struct {
+	keyType string
+	err     error
+}
+
+func (k *keyError) Error() string { return k.err.Error() }
+func (k *keyError) Unwrap() error { return k.err }
+
+// recordFailure sends a failed key to the errors file, if one is configured.
+func (e *Exporter) recordFailure(key string, err error) {
+	e.failed.Add(1)
+	if e.failures == nil {
+		return
+	}
+	failed := FailedKey{Key: key, Error: err.Error()}
+	var kerr *keyError
+	if errors.As(err, &kerr) {
+		failed.Type = kerr.keyType
+	}
+	e.failures <- failed
+}
+
+// isWrongType reports whether err is a WRONGTYPE reply, returned when a
+// command is run against a key holding another type.
+func isWrongType(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+// errKeySkipped is returned by processKey for keys that are intentionally
+// left out of the export.
+var errKeySkipped = errors.New("key skipped")
+
+// errKeyVanished is returned by processKey for keys that were deleted or
+// expired after SCAN returned them.
+var errKeyVanished = errors.New("key expired during export")
+
+// ttlNoKey is the TTL go-redis reports for a key that doesn't exist. Like
+// the -1 of keys without an expiry it isn't scaled to the command's unit.
+const ttlNoKey = time.Duration(-2)
+
+// typeAllowed reports whether keys of keyType pass the --type and
+// --exclude-type filters.
+func (e *Exporter) typeAllowed(keyType string) bool {
+	for _, t := range e.config.ExcludeTypes {
+		if t == keyType {
+			return false
+		}
+	}
+	if len(e.config.Types) == 0 {
+		return true
+	}
+	for _, t := range e.config.Types {
+		if t == keyType {
+			return true
+		}
+	}
+	return false
+}
+
+// valueWanted reports whether the value of a key of keyType is fetched. It
+// isn't with --keys-only, nor for types in --skip-values-for, whose keys are
+// exported as with --keys-only.
+func (e *Exporter) valueWanted(keyType string) bool {
+	if e.config.KeysOnly {
+		return false
+	}
+	for _, t := range e.config.SkipValuesFor {
+		if t == keyType {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Exporter) processKey(ctx context.Context, key string) (*RedisEntry, error) {
+	return e.processKeyOfType(ctx, key, "")
+}
+
+// processKeyOfType is processKey for a key whose type is already known, as
+// it is for keys routed to a per-type pool. An empty keyType is looked up.
+func (e *Exporter) processKeyOfType(ctx context.Context, key, keyType string) (*RedisEntry, error) {
+	var err error
+	if keyType == "" {
+		keyType, err = e.client.Type(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get type for key %s: %w", key, err)
+		}
+	}
+
+	if keyType == "none" {
+		return nil, errKeyVanished
+	}
+
+	if !e.typeAllowed(keyType) {
+		return nil, errKeySkipped
+	}
+
+	if !e.since.IsZero() {
+		touched, err := e.touchedSince(ctx, key)
+		if errors.Is(err, redis.Nil) {
+			return nil, errKeyVanished
+		}
+		if err != nil {
+			return nil, &keyError{keyType: keyType, err: fmt.Errorf("failed to get idle time for key %s: %w", key, err)}
+		}
+		if !touched {
+			return nil, errKeySkipped
+		}
+	}
+
+	if e.filter != nil {
+		match, err := e.filterMatches(ctx, key, keyType)
+		if err != nil {
+			return nil, &keyError{keyType: keyType, err: fmt.Errorf("filter script failed for key %s: %w", key, err)}
+		}
+		if !match {
+			return nil, errKeySkipped
+		}
+	}
+
+	var value interface{}
+	var truncated, sampled bool
+	var ttl time.Duration
+	var haveTTL bool
+	fetch := func(keyType string) (value interface{}, err error) {
+		if e.config.TTLWithValue {
+			value, ttl, haveTTL, err = e.getValueWithTTL(ctx, key, keyType)
+			return value, err
+		}
+		return e.getValueByType(ctx, key, keyType)
+	}
+	wanted := e.valueWanted(keyType)
+	if wanted {
+		value, err = fetch(keyType)
+		if isWrongType(err) {
+			// The key was replaced by one of another type after TYPE; read
+			// the new type and fetch once more.
+			keyType, err = e.client.Type(ctx, key).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get type for key %s: %w", key, err)
+			}
+			if keyType == "none" {
+				return nil, errKeyVanished
+			}
+			if !e.typeAllowed(keyType) {
+				return nil, errKeySkipped
+			}
+			logrus.WithFields(logrus.Fields{
+				"key":  key,
+				"type": keyType,
+			}).Debug("Key changed type during export, retrying")
+			// The new type may be one exported without its value
+			wanted = e.valueWanted(keyType)
+			value = nil
+			if wanted {
+				value, err = fetch(keyType)
+			}
+		}
+		if errors.Is(err, redis.Nil) {
+			return nil, errKeyVanished
+		}
+		if err != nil {
+			return nil, &keyError{keyType: keyType, err: fmt.Errorf("failed to get value for key %s: %w", key, err)}
+		}
+	}
+	if wanted {
+		if e.config.SkipEmpty && isEmptyCollection(value) {
+			if keyType == "zset" && e.scoreRange() != nil {
+				// No members in the score window, but the key is still there
+				return nil, errKeySkipped
+			}
+			// Redis deletes collections when their last element is removed,
+			// so the key went away after TYPE.
+			return nil, errKeyVanished
+		}
+
+		if items, ok := value.([]string); ok && keyType == "list" {
+			truncated, err = e.listTruncated(ctx, key, items)
+			if err != nil {
+				return nil, &keyError{keyType: keyType, err: fmt.Errorf("failed to get length of list %s: %w", key, err)}
+			}
+		}
+		sampled, err = e.collectionSampled(ctx, key, keyType, value)
+		if err != nil {
+			return nil, &keyError{keyType: keyType, err: fmt.Errorf("failed to get size of %s %s: %w", keyType, key, err)}
+		}
+
+		var cut bool
+		value, cut, err = e.finishValue(key, keyType, value)
+		if err != nil {
+			return nil, err
+		}
+		truncated = truncated || cut
+	}
+
+	var meta keyMetadata
+	switch {
+	case (e.config.WithFreq && !e.noFreq.Load()) || e.config.WithRefcount:
+		meta, err = e.getKeyMetadata(ctx, key)
+		if !haveTTL {
+			ttl = meta.ttl
+		}
+	case haveTTL:
+		// Read in the same pipeline as the value
+	case e.config.TTLPrecision == ttlPrecisionMillis:
+		ttl, err = e.client.PTTL(ctx, key).Result()
+	default:
+		ttl, err = e.client.TTL(ctx, key).Result()
+	}
+	if err != nil {
+		return nil, &keyError{keyType: keyType, err: fmt.Errorf("failed to get TTL for key %s: %w", key, err)}
+	}
+
+	if ttl == ttlNoKey {
+		// Deleted or expired after its value was read
+		return nil, errKeyVanished
+	}
+	// TTL is -1 for keys without an expiry
+	if e.config.PersistentOnly && ttl >= 0 {
+		return nil, errKeySkipped
+	}
+
+	entry := &RedisEntry{
+		Key:   e.rewriteKey(key),
+		Type:  keyType,
+		Value: value,
+		Freq:  int(meta.freq),
+
+		Truncated: truncated,
+		Sampled:   sampled,
+	}
+
+	if len(e.config.Sources) > 1 {
+		entry.Source = e.config.RedisAddr
+	}
+
+	if keyType == "zset" && e.valueWanted(keyType) {
+		entry.ScoreRange = e.scoreRange()
+	}
+
+	if e.config.WithRefcount {
+		if meta.refcount >= sharedRefcount {
+			entry.Shared = true
+		} else {
+			entry.Refcount = meta.refcount
+		}
+	}
+
+	if ttl > 0 {
+		e.setTTL(entry, ttl)
+	}
+
+	if e.config.WithSlot {
+		slot := keySlot(key)
+		entry.Slot = &slot
+		if e.slotNodes != nil {
+			entry.Node = e.slotNodes[slot]
+		}
+	}
+
+	return entry, nil
+}
+
+// finishValue applies --jq, --max-value-bytes and --numbers to a fetched
+// value, reporting whether it was truncated.
+func (e *Exporter) finishValue(key, keyType string, value interface{}) (interface{}, bool, error) {
+	if e.jq != nil {
+		var err error
+		value, err = e.transformValue(value)
+		if err != nil {
+			return nil, false, &keyError{keyType: keyType, err: fmt.Errorf("failed to transform value for key %s: %w", key, err)}
+		}
+		if value == nil && e.config.JQDropNull {
+			return nil, false, errKeySkipped
+		}
+	}
+
+	value, cut := truncateValue(value, e.config.MaxValueBytes)
+
+	if s, ok := value.(string); ok && keyType == "string" && e.config.Numbers == numbersJSON && isJSONNumber(s) {
+		value = json.Number(s)
+	}
+	if e.config.ParseJSON {
+		value = embedJSONValues(value)
+	}
+	return value, cut, nil
+}
+
+// setTTL sets the TTL of entry in whole seconds and, with --ttl-precision
+// ms, in milliseconds.
+func (e *Exporter) setTTL(entry *RedisEntry, ttl time.Duration) {
+	entry.TTL = int64(ttl.Seconds())
+	entry.ttlText = formatTTL(ttl.Truncate(time.Second), e.config.TTLFormat)
+	if e.config.TTLPrecision == ttlPrecisionMillis {
+		entry.TTLMillis = ttl.Milliseconds()
+	}
+}
+
+// sharedRefcount is the OBJECT REFCOUNT reported for Redis's shared objects,
+// such as the small integers every key holding that value points at.
+const sharedRefcount = math.MaxInt32
+
+// keyMetadata is what getKeyMetadata reads about a key besides its value.
+type keyMetadata struct {
+	ttl      time.Duration
+	freq     int64
+	refcount int64
+}
+
+// getKeyMetadata pipelines TTL with OBJECT FREQ for --with-freq and OBJECT
+// REFCOUNT for --with-refcount. If the server is not using an LFU eviction
+// policy, frequency export is disabled with a warning and a zero frequency
+// is returned.
+func (e *Exporter) getKeyMetadata(ctx context.Context, key string) (keyMetadata, error) {
+	pipe := e.client.Pipeline()
+	var ttlCmd *redis.DurationCmd
+	if e.config.TTLPrecision == ttlPrecisionMillis {
+		ttlCmd = pipe.PTTL(ctx, key)
+	} else {
+		ttlCmd = pipe.TTL(ctx, key)
+	}
+	var freqCmd, refcountCmd *redis.IntCmd
+	if e.config.WithFreq && !e.noFreq.Load() {
+		freqCmd = pipe.ObjectFreq(ctx, key)
+	}
+	if e.config.WithRefcount {
+		refcountCmd = pipe.ObjectRefCount(ctx, key)
+	}
+	_, _ = pipe.Exec(ctx)
+
+	var meta keyMetadata
+	var err error
+	meta.ttl, err = ttlCmd.Result()
+	if err != nil {
+		return meta, err
+	}
+
+	if freqCmd != nil {
+		meta.freq, err = freqCmd.Result()
+		if err != nil {
+			if !strings.Contains(err.Error(), "LFU") {
+				return meta, fmt.Errorf("failed to get access frequency: %w", err)
+			}
+			if e.noFreq.CompareAndSwap(false, true) {
+				logrus.WithError(err).Warn("Server is not using an LFU maxmemory-policy, disabling --with-freq")
+			}
+		}
+	}
+
+	if refcountCmd != nil {
+		meta.refcount, err = refcountCmd.Result()
+		if err != nil {
+			return meta, fmt.Errorf("failed to get refcount: %w", err)
+		}
+	}
+
+	return meta, nil
+}
+
+// processKeySafely calls processKey, turning a panic into an error so one
+// bad key cannot crash the export and leave the output unfinished.
+func (e *Exporter) processKeySafely(ctx context.Context, task keyTask) (entry *RedisEntry, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic processing key %s: %v", task.key, r)
+		}
+	}()
+	return e.processKeyOfType(ctx, task.key, task.keyType)
+}
+
+// poolRetryWait is the wait before the first retry of a key that failed on a
+// connection pool timeout. It doubles with each retry.
+var poolRetryWait = 100 * time.Millisecond
+
+// processKeyRetrying processes key, retrying up to PoolRetries times when
+// every pooled connection was busy. Other workers free up connections while
+// this one backs off, so the key isn't lost to a momentary shortage. Keys
+// that failed because the connection dropped are retried up to
+// Reconnects times.
+func (e *Exporter) processKeyRetrying(ctx context.Context, task keyTask) (*RedisEntry, error) {
+	key := task.key
+	wait := poolRetryWait
+	attempt, reconnects := 0, 0
+	for {
+		entry, err := e.processKeySafely(ctx, task)
+		if isConnError(err) {
+			if !e.awaitReconnect(ctx, reconnects, err, logrus.Fields{"key": key}) {
+				return entry, err
+			}
+			reconnects++
+			continue
+		}
+		if !errors.Is(err, redis.ErrPoolTimeout) || attempt >= e.config.PoolRetries {
+			return entry, err
+		}
+
+		e.poolRetries.Add(1)
+		logrus.WithFields(logrus.Fields{
+			"key":     key,
+			"attempt": attempt + 1,
+			"wait":    wait,
+		}).Debug("Connection pool exhausted, retrying key")
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+		wait *= 2
+		attempt++
+	}
+}
+
+// outputEntry returns the value to encode for entry, renaming fields
+// according to the configured field map.
+func (e *Exporter) outputEntry(entry *RedisEntry) (interface{}, error) {
+	if len(e.config.FieldMap) == 0 && entry.ttlText == "" {
+		return entry, nil
+	}
+
+	data, err := e.marshalJSON(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	if entry.ttlText != "" {
+		ttl, err := e.marshalJSON(entry.ttlText)
+		if err != nil {
+			return nil, err
+		}
+		fields["ttl"] = ttl
+	}
+
+	mapped := make(map[string]json.RawMessage, len(fields))
+	for name, value := range fields {
+		if to, ok := e.config.FieldMap[name]; ok {
+			name = to
+		}
+		mapped[name] = value
+	}
+
+	return mapped, nil
+}
+
+func (e *Exporter) getTotalKeyCount(ctx context.Context) (int64, error) {
+	info, err := e.client.Info(ctx, "keyspace").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get keyspace info: %w", err)
+	}
+
+	// Parse the keyspace info to extract key count for current database
+	dbPattern := fmt.Sprintf(`db%d:keys=(\d+)`, e.config.RedisDB)
+	re := regexp.MustCompile(dbPattern)
+	matches := re.FindStringSubmatch(info)
+
+	if len(matches) < 2 {
+		return 0, fmt.Errorf("could not find key count for database %d", e.config.RedisDB)
+	}
+
+	count, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse key count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (e *Exporter) worker(ctx context.Context, id int, keysChan <-chan keyTask, resultsChan chan<- *RedisEntry, wg *sync.WaitGroup) {
+	defer wg.Done()
+	e.runWorker(ctx, id, keysChan, resultsChan, nil)
+}
+
+// runWorker processes keys until keysChan is closed, ctx is done, or stop is
+// closed. A nil stop never fires. id indexes the worker's --worker-stats
+// counters.
+func (e *Exporter) runWorker(ctx context.Context, id int, keysChan <-chan keyTask, resultsChan chan<- *RedisEntry, stop <-chan struct{}) {
+
+	// Stagger worker start so they don't all hit Redis at the same instant
+	if e.config.WorkerJitter > 0 {
+		select {
+		case <-time.After(time.Duration(rand.Int63n(int64(e.config.WorkerJitter)))):
+		case <-ctx.Done():
+			return
+		}
+	}
+	logrus.Debug("Worker started")
+
+	for {
+		var task keyTask
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case t, ok := <-keysChan:
+			if !ok {
+				return
+			}
+			task = t
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			start := time.Now()
+			entry, err := e.processKeyRetrying(ctx, task)
+			elapsed := time.Since(start)
+			if e.config.AutoWorkers {
+				e.latency.observe(elapsed)
+			}
+			if errors.Is(err, errKeySkipped) {
+				continue
+			}
+			if errors.Is(err, errKeyVanished) {
+				logrus.WithField("key", task.key).Debug("Key expired during export")
+				e.vanished.Add(1)
+				continue
+			}
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"key": task.key,
+				}).Error("Error processing key: ", err)
+				e.recordFailure(task.key, err)
+				continue
+			}
+			entry.seq = task.seq
+			if e.config.SlowThreshold > 0 && elapsed >= e.config.SlowThreshold {
+				logrus.WithFields(logrus.Fields{
+					"key":      task.key,
+					"type":     entry.Type,
+					"duration": elapsed.Round(time.Millisecond),
+				}).Warn("Slow key")
+				entry.elapsed = elapsed
+			}
+			if e.config.SizeHistogram {
+				entry.size = valueSize(entry.Value)
+			}
+			if e.config.ReportBinary {
+				entry.binary = hasInvalidUTF8(entry.Value)
+			}
+			if e.workerStats != nil {
+				e.workerStats.observe(id, elapsed)
+			}
+			select {
+			case resultsChan <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// keyInScope reports whether a scanned key should be sent to the workers.
+// Keys are limited to the bytewise range [KeyStart, KeyEnd); an empty bound
+// is unbounded. With --shard, only keys hashing to this process's shard are
+// in scope.
+func (e *Exporter) keyInScope(key string) bool {
+	if key < e.config.KeyStart {
+		return false
+	}
+	if e.config.KeyEnd != "" && key >= e.config.KeyEnd {
+		return false
+	}
+	if e.config.ShardCount > 0 && int(crc32.ChecksumIEEE([]byte(key))%uint32(e.config.ShardCount)) != e.config.Shard-1 {
+		return false
+	}
+	return true
+}
+
+// sampleFloat returns the random numbers that decide which keys --sample-rate
+// keeps.
+var sampleFloat = rand.Float64
+
+// sampled reports whether the next scanned key is in the --sample-rate
+// sample.
+func (e *Exporter) sampled() bool {
+	rate := e.config.SampleRate
+	return rate <= 0 || rate >= 1 || sampleFloat() < rate
+}
+
+// resolvePassword picks the Redis password from, in order of precedence, the
+// contents of passwordFile, the REDIS_PASSWORD environment variable, and the
+// --password flag. Trailing newlines in the file are ignored.
+func resolvePassword(flag, passwordFile string) (string, error) {
+	if passwordFile != "" {
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if env, ok := os.LookupEnv("REDIS_PASSWORD"); ok && env != "" {
+		return env, nil
+	}
+	return flag, nil
+}
+
+// parseShard parses a --shard value of the form N/M, where 1 <= N <= M.
+func parseShard(value string) (int, int, error) {
+	n, m, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid shard %q, expected N/M", value)
+	}
+	shard, err := strconv.Atoi(n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid shard %q, expected N/M", value)
+	}
+	count, err := strconv.Atoi(m)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid shard %q, expected N/M", value)
+	}
+	if count < 1 || shard < 1 || shard > count {
+		return 0, 0, fmt.Errorf("invalid shard %q, N must be between 1 and M", value)
+	}
+	return shard, count, nil
+}
+
+// scanKeys iterates the keyspace with SCAN, sending each key to keysChan, and
+// closes keysChan when done. When a single --type is requested the filter is
+// pushed to the server with SCAN TYPE, falling back to a plain SCAN if the
+// server rejects it (Redis < 6).
+func (e *Exporter) scanKeys(ctx context.Context, keysChan chan<- keyTask) {
+	defer close(keysChan)
+
+	var seq int64
+	ctx, span := tracer().Start(ctx, "scan")
+	defer func() {
+		span.SetAttributes(attribute.Int64("keys", seq))
+		span.End()
+	}()
+
+	scanType := ""
+	if len(e.config.Types) == 1 {
+		scanType = e.config.Types[0]
+	}
+
+	var pacer *scanPacer
+	if e.config.AdaptiveScan {
+		pacer = newScanPacer(int64(e.config.BatchSize))
+	}
+
+	match := e.config.Match
+	if match == "" {
+		match = "*"
+	}
+
+	var cursor uint64
+	reconnects := 0
+	for {
+		if !e.paceScan(ctx) {
+			return
+		}
+
+		count := int64(e.config.BatchSize)
+		if pacer != nil {
+			count = pacer.count
+		}
+
+		start := time.Now()
+		var keys []string
+		var next uint64
+		var err error
+		if scanType != "" {
+			keys, next, err = e.client.ScanType(ctx, cursor, match, count, scanType).Result()
+		} else {
+			keys, next, err = e.client.Scan(ctx, cursor, match, count).Result()
+		}
+		// SCAN cursors stay valid across connections, so a dropped
+		// connection resumes from the last cursor rather than ending the scan
+		if isConnError(err) {
+			if e.awaitReconnect(ctx, reconnects, err, logrus.Fields{"cursor": cursor}) {
+				reconnects++
+				continue
+			}
+		}
+		if err != nil && !isConnError(err) && scanType != "" && seq == 0 {
+			logrus.WithError(err).Warn("SCAN TYPE not supported, falling back to client-side type filtering")
+			scanType = ""
+			cursor = 0
+			continue
+		}
+		if err != nil {
+			logrus.Error("Error during key scanning: ", err)
+			if ctx.Err() == nil {
+				e.scanErr = fmt.Errorf("scan failed at cursor %d: %w", cursor, err)
+			}
+			return
+		}
+		cursor, reconnects = next, 0
+		if pacer != nil {
+			pacer.observe(time.Since(start))
+		}
+
+		for _, key := range keys {
+			if !e.keyInScope(key) || !e.sampled() {
+				continue
+			}
+			task := keyTask{key: key, seq: seq}
+			seq++
+			select {
+			case keysChan <- task:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if cursor == 0 {
+			return
+		}
+	}
+}
+
+// scanPacerTarget is the SCAN latency --adaptive-scan aims for. COUNT grows
+// while calls finish faster than this and shrinks once they take twice as
+// long.
+var scanPacerTarget = 10 * time.Millisecond
+
+// scanPacer adapts the SCAN COUNT hint to observed SCAN latency. COUNT only
+// changes how much work each call does, never which keys are returned.
+type scanPacer struct {
+	count, min, max int64
+}
+
+// newScanPacer starts COUNT small and lets it grow up to ten times batch.
+func newScanPacer(batch int64) *scanPacer {
+	if batch < 1 {
+		batch = 1
+	}
+	min := int64(10)
+	if min > batch {
+		min = batch
+	}
+	return &scanPacer{count: min, min: min, max: batch * 10}
+}
+
+func (p *scanPacer) observe(latency time.Duration) {
+	switch {
+	case latency < scanPacerTarget:
+		p.count *= 2
+		if p.count > p.max {
+			p.count = p.max
+		}
+	case latency > 2*scanPacerTarget:
+		p.count /= 2
+		if p.count < p.min {
+			p.count = p.min
+		}
+	}
+}
+
+// sendKeys sends an explicit list of keys to keysChan and closes it.
+func (e *Exporter) sendKeys(ctx context.Context, keys []string, keysChan chan<- keyTask) {
+	defer close(keysChan)
+
+	for seq, key := range keys {
+		select {
+		case keysChan <- keyTask{key: key, seq: int64(seq)}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readKeys sends each non-empty line of r to keysChan and closes it.
+func (e *Exporter) readKeys(ctx context.Context, r io.Reader, keysChan chan<- keyTask) {
+	defer close(keysChan)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 512*1024*1024)
+	var seq int64
+	for scanner.Scan() {
+		key := strings.TrimSuffix(scanner.Text(), "\r")
+		if key == "" {
+			continue
+		}
+		select {
+		case keysChan <- keyTask{key: key, seq: seq}:
+			seq++
+		case <-ctx.Done():
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logrus.Error("Error reading keys file: ", err)
+	}
+}
+
+// deletionEvents are keyevent notifications that remove a key rather than
+// write to it.
+var deletionEvents = map[string]bool{
+	"del":     true,
+	"unlink":  true,
+	"expired": true,
+	"evicted": true,
+}
+
+// watchKeys subscribes to keyevent notifications for the configured database
+// for the --watch duration and returns the distinct keys that were written.
+// The server must have keyevent notifications enabled (notify-keyspace-events).
+func (e *Exporter) watchKeys(ctx context.Context) ([]string, error) {
+	pattern := fmt.Sprintf("__keyevent@%d__:*", e.config.RedisDB)
+	pubsub := e.client.PSubscribe(ctx, pattern)
+	defer func() { _ = pubsub.Close() }()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to keyspace notifications: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"pattern":  pattern,
+		"duration": e.config.Watch,
+	}).Info("Watching for changed keys")
+
+	keys := collectTouchedKeys(ctx, pubsub.Channel(), e.config.Watch)
+
+	logrus.WithField("touched_keys", len(keys)).Info("Finished watching for changed keys")
+	return keys, nil
+}
+
+// collectTouchedKeys reads keyevent messages until duration elapses, returning
+// the distinct keys that received write events in the order first seen.
+func collectTouchedKeys(ctx context.Context, messages <-chan *redis.Message, duration time.Duration) []string {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	seen := make(map[string]bool)
+	var keys []string
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return keys
+			}
+			event := msg.Channel[strings.LastIndex(msg.Channel, ":")+1:]
+			if deletionEvents[event] || seen[msg.Payload] {
+				continue
+			}
+			seen[msg.Payload] = true
+			keys = append(keys, msg.Payload)
+		case <-timer.C:
+			return keys
+		case <-ctx.Done():
+			return keys
+		}
+	}
+}
+
+// stdoutOutput is the --output value that writes the export to stdout.
+const stdoutOutput = "-"
+
+// expandOutputTemplate replaces each {layout} in name with now formatted by
+// the Go time layout inside the braces, so backup-{2006-01-02}.json becomes
+// backup-2025-08-12.json. Text outside braces, including extensions such as
+// .json.gz, is kept as is.
+func expandOutputTemplate(name string, now time.Time) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(name, '{')
+		if start < 0 {
+			if strings.IndexByte(name, '}') >= 0 {
+				return "", fmt.Errorf("unmatched } in %q", name)
+			}
+			b.WriteString(name)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(name[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed { in %q", name)
+		}
+		end += start
+
+		prefix := name[:start]
+		if strings.IndexByte(prefix, '}') >= 0 {
+			return "", fmt.Errorf("unmatched } in %q", name)
+		}
+		b.WriteString(prefix)
+		b.WriteString(now.Format(name[start+1 : end]))
+		name = name[end+1:]
+	}
+}
+
+// defaultStatsInterval is how often export progress is logged unless
+// --stats-interval says otherwise.
+const defaultStatsInterval = 5 * time.Second
+
+// firstMilestone is the key count of the first progress line with
+// --milestone-logging. Each later one is ten times the last.
+var firstMilestone int64 = 1000
+
+// Export writes every key in the database to the configured output file.
+func (e *Exporter) Export(ctx context.Context) error {
+	ctx, span := tracer().Start(ctx, "Export")
+	defer span.End()
+
+	err := e.export(ctx)
+	if err == nil && e.config.Archive != "" {
+		err = e.writeArchive()
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (e *Exporter) export(ctx context.Context) error {
+	if e.config.NamespaceStats {
+		return e.namespaceStats(ctx)
+	}
+
+	// Cancelling on return stops the key source and workers on every early
+	// exit, so none of them are left blocked on a channel nobody drains.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	startedAt := timeNow()
+
+	sources := e.sources
+	if sources == nil && len(e.config.Sources) > 1 {
+		var err error
+		sources, err = e.openSources(ctx)
+		if err != nil {
+			return err
+		}
+		defer closeSources(sources)
+	}
+
+	if e.config.TagRouting && len(sources) == 0 && e.config.Watch == 0 && e.config.KeysFile == "" && e.config.RDBFile == "" {
+		e.routeHashTag(ctx)
+	}
+
+	if e.config.SinceFile != "" {
+		if err := e.loadSince(ctx); err != nil {
+			return err
+		}
+	}
+
+	if e.config.Consistent {
+		offset, err := e.replicationOffset(ctx)
+		if err != nil {
+			return err
+		}
+		e.replication = &ManifestReplication{StartOffset: offset}
+	}
+
+	// Get total key count first
+	var totalKeys int64
+	var err error
+	if e.config.RDBFile == "" {
+		totalKeys, err = e.getTotalKeyCount(ctx)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to get total key count, progress tracking will be limited")
+			totalKeys = 0
+		}
+	}
+	for _, source := range sources {
+		if n, err := source.getTotalKeyCount(ctx); err == nil {
+			totalKeys += n
+		}
+	}
+	if e.config.SampleRate > 0 && e.config.SampleRate < 1 {
+		totalKeys = int64(float64(totalKeys) * e.config.SampleRate)
+	}
+
+	keySource := e.scanKeys
+	if e.config.Watch > 0 {
+		keys, err := e.watchKeys(ctx)
+		if err != nil {
+			return err
+		}
+		totalKeys = int64(len(keys))
+		keySource = func(ctx context.Context, keysChan chan<- keyTask) {
+			e.sendKeys(ctx, keys, keysChan)
+		}
+	}
+	if e.config.KeysFile != "" {
+		keysFile, err := os.Open(e.config.KeysFile)
+		if err != nil {
+			return fmt.Errorf("failed to open keys file: %w", err)
+		}
+		defer func() { _ = keysFile.Close() }()
+		totalKeys = 0
+		keySource = func(ctx context.Context, keysChan chan<- keyTask) {
+			e.readKeys(ctx, keysFile, keysChan)
+		}
+	}
+
+	if e.config.RDBFile != "" {
+		// Entries come straight from the dump, with no keys for workers
+		keySource = func(ctx context.Context, keysChan chan<- keyTask) {
+			close(keysChan)
+		}
+	}
+
+	if e.config.WithSlot && e.config.RDBFile == "" {
+		e.loadSlotNodes(ctx)
+	}
+
+	if e.config.JQ != "" && e.jq == nil {
+		code, err := compileJQ(e.config.JQ)
+		if err != nil {
+			return err
+		}
+		e.jq = code
+	}
+
+	if e.config.Template != "" && e.tmpl == nil {
+		tmpl, err := compileTemplate(e.config.Template)
+		if err != nil {
+			return err
+		}
+		e.tmpl = tmpl
+	}
+
+	if e.config.FilterScript != "" && e.filter == nil {
+		script, err := loadFilterScript(e.config.FilterScript)
+		if err != nil {
+			return err
+		}
+		// Load it up front so a script that doesn't compile fails the
+		// export instead of every key
+		if err := script.Load(ctx, e.client).Err(); err != nil {
+			return fmt.Errorf("invalid --filter-script: %w", err)
+		}
+		e.filter = script
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"output_file": e.outputName(),
+		"workers":     e.config.Workers,
+		"batch_size":  e.config.BatchSize,
+		"total_keys":  totalKeys,
+	}).Info("Starting Redis export")
+
+	output, err := e.openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = output.Close() }()
+
+	var failures chan FailedKey
+	var failuresDone chan error
+	if e.config.ErrorsFile != "" {
+		errorsFile, err := createFile(e.config.ErrorsFile, e.config.FileMode)
+		if err != nil {
+			return fmt.Errorf("failed to create errors file: %w", err)
+		}
+		defer func() { _ = errorsFile.Close() }()
+
+		failures = make(chan FailedKey, e.config.BatchSize)
+		failuresDone = make(chan error, 1)
+		e.failures = failures
+		go func() { failuresDone <- writeFailedKeys(errorsFile, failures) }()
+	}
+
+	keysChan := make(chan keyTask, e.config.BatchSize)
+	resultsChan := make(chan *RedisEntry, e.config.BatchSize)
+	e.results = resultsChan
+
+	// Auto-tuned workers reuse the IDs of retired ones, so there are never
+	// more IDs than the --workers ceiling. The workers of extra sources are
+	// numbered after e's.
+	if e.config.WorkerStats {
+		e.workerStats = newWorkerStats(e.totalWorkers() + len(sources)*e.config.Workers)
+	}
+
+	var wg sync.WaitGroup
+	var pool *workerPool
+	if e.config.RDBFile != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.readRDB(ctx, resultsChan); err != nil && ctx.Err() == nil {
+				e.scanErr = err
+			}
+		}()
+	} else if e.config.AutoWorkers {
+		pool = newWorkerPool(ctx, e, keysChan, resultsChan, &wg)
+		pool.resize(newWorkerTuner(e.config.MinWorkers, e.config.Workers).min)
+	} else if e.config.KeyAffinity {
+		workerChans := make([]chan keyTask, e.config.Workers)
+		for i := range workerChans {
+			workerChans[i] = make(chan keyTask, e.config.BatchSize)
+			wg.Add(1)
+			go e.worker(ctx, i, workerChans[i], resultsChan, &wg)
+		}
+		go routeKeys(ctx, keysChan, workerChans)
+	} else if len(e.config.TypeWorkers) > 0 {
+		// Per-type pools are numbered after the --workers pool
+		id := e.config.Workers
+		pools := make(map[string]chan keyTask, len(e.config.TypeWorkers))
+		for keyType, n := range e.config.TypeWorkers {
+			pools[keyType] = make(chan keyTask, e.config.BatchSize)
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go e.worker(ctx, id, pools[keyType], resultsChan, &wg)
+				id++
+			}
+		}
+		shared := make(chan keyTask, e.config.BatchSize)
+		for i := 0; i < e.config.Workers; i++ {
+			wg.Add(1)
+			go e.worker(ctx, i, shared, resultsChan, &wg)
+		}
+		go e.classifyKeys(ctx, keysChan, pools, shared)
+	} else {
+		for i := 0; i < e.config.Workers; i++ {
+			wg.Add(1)
+			go e.worker(ctx, i, keysChan, resultsChan, &wg)
+		}
+	}
+
+	for i, source := range sources {
+		e.runSource(ctx, source, e.totalWorkers()+i*e.config.Workers, resultsChan, &wg)
+	}
+
+	go func() {
+		wg.Wait()
+		e.addSourceCounts(sources)
+		if failures != nil {
+			close(failures)
+		}
+		close(resultsChan)
+	}()
+
+	var out io.Writer = output
+	var buffered *bufio.Writer
+	if e.config.WriteBuffer > 0 {
+		buffered = bufio.NewWriterSize(output, e.config.WriteBuffer)
+		out = buffered
 	}
-}
 
-func (e *Exporter) processKey(ctx context.Context, key string) (*RedisEntry, error) {
-	keyType, err := e.client.Type(ctx, key).Result()
+	writer, err := e.newEntryWriter(e.config.Format, e.config.OutputFile, out)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get type for key %s: %w", key, err)
+		return err
+	}
+	if len(e.config.ExtraFormats) > 0 {
+		extra, err := e.openExtraWriters()
+		if err != nil {
+			_ = writer.Close()
+			return err
+		}
+		writer = append(multiEntryWriter{writer}, extra...)
 	}
 
-	value, err := e.getValueByType(ctx, key, keyType)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get value for key %s: %w", key, err)
+	var values *valueTable
+	if e.config.DedupeValues {
+		values, err = newValueTable(valuesFilePath(e.config.OutputFile), e.config.FileMode)
+		if err != nil {
+			return err
+		}
 	}
 
-	ttl, err := e.client.TTL(ctx, key).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get TTL for key %s: %w", key, err)
+	var compressor *dictCompressor
+	if e.config.CompressDict {
+		compressor = newDictCompressor(dictSamples)
 	}
 
-	entry := &RedisEntry{
-		Key:   key,
-		Type:  keyType,
-		Value: value,
+	// Finalize the output on every return path, including cancellation and
+	// panics, so an interrupted export is still a valid (partial) file. A
+	// cloud storage upload is aborted instead when the export failed.
+	finalized := false
+	finalize := func(failed error) error {
+		if finalized {
+			return nil
+		}
+		finalized = true
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("failed to finalize output: %w", err)
+		}
+		if buffered != nil {
+			if err := buffered.Flush(); err != nil {
+				return fmt.Errorf("failed to flush output: %w", err)
+			}
+		}
+		// Closing a cloud storage sink completes the upload
+		if upload, ok := output.(*uploadWriter); ok && failed != nil {
+			upload.Abort(failed)
+		} else if err := output.Close(); err != nil {
+			return fmt.Errorf("failed to close output: %w", err)
+		}
+		if values != nil {
+			if err := values.Close(); err != nil {
+				return fmt.Errorf("failed to finalize values file: %w", err)
+			}
+		}
+		return nil
 	}
+	defer func() { _ = finalize(errExportFailed) }()
 
-	if ttl > 0 {
-		entry.TTL = int64(ttl.Seconds())
+	var processed int64
+
+	var sizes *sizeHistogram
+	if e.config.SizeHistogram {
+		sizes = newSizeHistogram(e.config.TopKeys)
 	}
 
-	return entry, nil
-}
+	var ttls *ttlHistogram
+	if e.config.TTLHistogram {
+		ttls = newTTLHistogram()
+	}
 
-func (e *Exporter) getTotalKeyCount(ctx context.Context) (int64, error) {
-	info, err := e.client.Info(ctx, "keyspace").Result()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get keyspace info: %w", err)
+	var slow *slowKeys
+	if e.config.SlowThreshold > 0 {
+		slow = newSlowKeys(e.config.TopKeys)
 	}
 
-	// Parse the keyspace info to extract key count for current database
-	dbPattern := fmt.Sprintf(`db%d:keys=(\d+)`, e.config.RedisDB)
-	re := regexp.MustCompile(dbPattern)
-	matches := re.FindStringSubmatch(info)
+	var binaryKeys []string
 
-	if len(matches) < 2 {
-		return 0, fmt.Errorf("could not find key count for database %d", e.config.RedisDB)
+	var collisions *sourceCollisions
+	if len(sources) > 0 && e.config.Collisions {
+		collisions = newSourceCollisions()
 	}
 
-	count, err := strconv.ParseInt(matches[1], 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse key count: %w", err)
+	var typeCounts map[string]int64
+	if e.config.StatsJSON != "" {
+		typeCounts = make(map[string]int64)
 	}
 
-	return count, nil
-}
+	var bar *progressbar.ProgressBar
+	if useProgressBar(e.config) {
+		bar = newProgressBar(totalKeys)
+	}
 
-func (e *Exporter) worker(ctx context.Context, keysChan <-chan string, resultsChan chan<- *RedisEntry, wg *sync.WaitGroup) {
-	defer wg.Done()
+	// Each batch of written entries gets its own span
+	var batchSpan trace.Span
+	var batchCount int
+	endBatch := func() {
+		if batchSpan != nil {
+			batchSpan.SetAttributes(attribute.Int("entries", batchCount))
+			batchSpan.End()
+			batchSpan = nil
+			batchCount = 0
+		}
+	}
+	defer endBatch()
 
-	for key := range keysChan {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			entry, err := e.processKey(ctx, key)
+	emitEntry := func(entry *RedisEntry) {
+		if batchSpan == nil {
+			_, batchSpan = tracer().Start(ctx, "write batch")
+		}
+		batchCount++
+		if batchCount >= e.config.BatchSize {
+			defer endBatch()
+		}
+
+		if collisions != nil {
+			collisions.check(entry)
+		}
+
+		if values != nil && entry.Value != nil {
+			ref, err := values.ref(entry.Value)
 			if err != nil {
 				logrus.WithFields(logrus.Fields{
-					"key": key,
-				}).Error("Error processing key: ", err)
-				continue
+					"key": entry.Key,
+				}).Error("Error writing value to values file: ", err)
+				e.failed.Add(1)
+				return
 			}
-			resultsChan <- entry
+			entry.Value, entry.ValueRef = nil, ref
+		}
+
+		if err := writer.WriteEntry(entry); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"key": entry.Key,
+			}).Error("Error encoding entry: ", err)
+			e.failed.Add(1)
+			return
+		}
+
+		processed++
+		if typeCounts != nil {
+			typeCounts[entry.Type]++
+		}
+		if entry.binary {
+			binaryKeys = append(binaryKeys, entry.Key)
+		}
+		if bar != nil {
+			_ = bar.Add(1)
+		}
+		if sizes != nil {
+			sizes.observe(entry.Key, entry.size)
+		}
+		if ttls != nil {
+			ttls.observe(entry)
+		}
+		if slow != nil && entry.elapsed > 0 {
+			slow.observe(entry.Key, entry.elapsed)
 		}
 	}
-}
 
-func (e *Exporter) Export(ctx context.Context) error {
-	// Get total key count first
-	totalKeys, err := e.getTotalKeyCount(ctx)
-	if err != nil {
-		logrus.WithError(err).Warn("Failed to get total key count, progress tracking will be limited")
-		totalKeys = 0
+	// With --compress-dict, entries are held back until the dictionary is
+	// trained on the first of them
+	writeEntry := emitEntry
+	if compressor != nil {
+		writeEntry = func(entry *RedisEntry) {
+			for _, ready := range compressor.add(entry) {
+				emitEntry(ready)
+			}
+		}
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"output_file": e.config.OutputFile,
-		"workers":     e.config.Workers,
-		"batch_size":  e.config.BatchSize,
-		"total_keys":  totalKeys,
-	}).Info("Starting Redis export")
+	var reorder *reorderBuffer
+	if e.config.Ordered {
+		reorder = newReorderBuffer(e.config.ReorderWindow)
+	}
 
-	file, err := os.Create(e.config.OutputFile)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+	if pool != nil {
+		go func() {
+			keySource(ctx, keysChan)
+			pool.seal()
+		}()
+	} else {
+		go keySource(ctx, keysChan)
 	}
-	defer func() { _ = file.Close() }()
 
-	keysChan := make(chan string, e.config.BatchSize)
-	resultsChan := make(chan *RedisEntry, e.config.BatchSize)
+	acceptEntry := func(entry *RedisEntry) {
+		if reorder == nil {
+			writeEntry(entry)
+			return
+		}
+		for _, ready := range reorder.push(entry) {
+			writeEntry(ready)
+		}
+	}
 
-	var wg sync.WaitGroup
-	for i := 0; i < e.config.Workers; i++ {
-		wg.Add(1)
-		go e.worker(ctx, keysChan, resultsChan, &wg)
+	startTime := time.Now()
+	var progress <-chan time.Time
+	var milestone int64
+	switch {
+	case e.config.Quiet || bar != nil:
+	case e.config.Milestones:
+		milestone = firstMilestone
+	case e.config.StatsInterval > 0:
+		ticker := time.NewTicker(e.config.StatsInterval)
+		defer ticker.Stop()
+		progress = ticker.C
 	}
 
-	go func() {
-		wg.Wait()
-		close(resultsChan)
-	}()
+	logProgress := func() {
+		if buffered != nil {
+			if err := buffered.Flush(); err != nil {
+				logrus.Error("Error flushing output: ", err)
+			}
+		}
 
-	encoder := json.NewEncoder(file)
-	_, _ = file.WriteString("[\n")
+		elapsed := time.Since(startTime)
+		rate := float64(processed) / elapsed.Seconds()
 
-	var processed int64
-	var firstEntry = true
+		fields := logrus.Fields{
+			"processed_keys": processed,
+			"keys_per_sec":   math.Round(rate),
+			"elapsed":        elapsed.Round(time.Second),
+			"buffered":       len(resultsChan),
+		}
 
-	go func() {
-		defer close(keysChan)
+		if totalKeys > 0 {
+			remaining := totalKeys - processed
+			fields["remaining_keys"] = remaining
 
-		iter := e.client.Scan(ctx, 0, "*", int64(e.config.BatchSize)).Iterator()
-		for iter.Next(ctx) {
-			key := iter.Val()
-			select {
-			case keysChan <- key:
-			case <-ctx.Done():
-				return
+			if rate > 0 {
+				etaSeconds := float64(remaining) / rate
+				eta := time.Duration(etaSeconds) * time.Second
+				fields["eta"] = eta.Round(time.Second)
 			}
 		}
 
-		if err := iter.Err(); err != nil {
-			logrus.Error("Error during key scanning: ", err)
-		}
-	}()
+		logrus.WithFields(fields).Info("Export progress")
+	}
 
-	startTime := time.Now()
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	bufferTicker := time.NewTicker(bufferSampleInterval)
+	defer bufferTicker.Stop()
+	var buffer bufferMonitor
+
+	var tune <-chan time.Time
+	var tuner *workerTuner
+	var tunedAt time.Time
+	var tunedProcessed int64
+	if pool != nil {
+		ticker := time.NewTicker(autoTuneInterval)
+		defer ticker.Stop()
+		tune = ticker.C
+		tuner = newWorkerTuner(e.config.MinWorkers, e.config.Workers)
+		tunedAt = startTime
+	}
 
 	for {
 		select {
 		case entry, ok := <-resultsChan:
 			if !ok {
-				_, _ = file.WriteString("\n]")
+				if reorder != nil {
+					for _, ready := range reorder.flush() {
+						writeEntry(ready)
+					}
+				}
+				if compressor != nil {
+					for _, ready := range compressor.flush() {
+						emitEntry(ready)
+					}
+					e.dictionary = compressor.dict
+				}
+				if err := finalize(e.scanErr); err != nil {
+					return err
+				}
+				if e.scanErr != nil {
+					return fmt.Errorf("export is incomplete, %d keys were written: %w", processed, e.scanErr)
+				}
+				if bar != nil {
+					_ = bar.Finish()
+				}
+				if failuresDone != nil {
+					if err := <-failuresDone; err != nil {
+						return fmt.Errorf("failed to write errors file: %w", err)
+					}
+				}
 				elapsed := time.Since(startTime)
 				rate := float64(processed) / elapsed.Seconds()
+				stats := e.client.PoolStats()
 				logrus.WithFields(logrus.Fields{
 					"total_keys":       processed,
 					"total_duration":   elapsed.Round(time.Second),
 					"avg_keys_per_sec": math.Round(rate),
+					"vanished_keys":    e.vanished.Load(),
+					"pool_retries":     e.poolRetries.Load(),
+					"reconnects":       e.reconnects.Load(),
+					"scan_pauses":      e.scanPauses.Load(),
+					"pool_hits":        stats.Hits,
+					"pool_misses":      stats.Misses,
+					"pool_timeouts":    stats.Timeouts,
+					"pool_total_conns": stats.TotalConns,
+					"pool_idle_conns":  stats.IdleConns,
 				}).Info("Export completed successfully")
+				e.warnVanished(processed)
+				e.modules.log()
+				if collisions != nil && collisions.count > 0 {
+					logrus.WithField("collisions", collisions.count).Warn("Keys were exported from more than one source and are marked \"collision\"")
+				}
+				if sizes != nil {
+					sizes.log()
+				}
+				if ttls != nil {
+					ttls.log()
+				}
+				if slow != nil {
+					slow.log()
+				}
+				if e.workerStats != nil {
+					e.workerStats.log()
+				}
+				if e.config.ReportBinary {
+					logBinaryKeys(binaryKeys)
+				}
+				if e.config.StatsJSON != "" {
+					if err := e.writeRunStats(startedAt, elapsed, processed, typeCounts, stats); err != nil {
+						return err
+					}
+				}
+				if e.config.Consistent {
+					if err := e.endConsistent(ctx); err != nil {
+						return err
+					}
+				}
+				if e.config.Manifest || e.config.SinceFile != "" || e.config.CompressDict || e.config.Consistent {
+					return e.writeManifest(startedAt, processed, stats)
+				}
 				return nil
 			}
 
-			if !firstEntry {
-				_, _ = file.WriteString(",\n")
-			} else {
-				firstEntry = false
+			acceptEntry(entry)
+			if milestone > 0 && processed >= milestone {
+				logProgress()
+				for milestone <= processed {
+					milestone *= 10
+				}
 			}
 
-			if err := encoder.Encode(entry); err != nil {
-				logrus.WithFields(logrus.Fields{
-					"key": entry.Key,
-				}).Error("Error encoding entry: ", err)
-				continue
-			}
+		case <-progress:
+			logProgress()
 
-			processed++
+		case <-bufferTicker.C:
+			buffer.sample(len(resultsChan), cap(resultsChan))
 
-		case <-ticker.C:
-			elapsed := time.Since(startTime)
-			rate := float64(processed) / elapsed.Seconds()
+		case now := <-tune:
+			rate := float64(processed-tunedProcessed) / now.Sub(tunedAt).Seconds()
+			tunedAt, tunedProcessed = now, processed
+			e.retune(pool, tuner, rate)
 
-			fields := logrus.Fields{
-				"processed_keys": processed,
-				"keys_per_sec":   math.Round(rate),
-				"elapsed":        elapsed.Round(time.Second),
+		case <-ctx.Done():
+			if e.config.MaxDuration <= 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ctx.Err()
 			}
-
-			if totalKeys > 0 {
-				remaining := totalKeys - processed
-				fields["remaining_keys"] = remaining
-
-				if rate > 0 {
-					etaSeconds := float64(remaining) / rate
-					eta := time.Duration(etaSeconds) * time.Second
-					fields["eta"] = eta.Round(time.Second)
+			// Write the entries the workers already fetched before closing
+			// the output, so the time-limited export ends on a valid file
+			for drained := false; !drained; {
+				select {
+				case entry, ok := <-resultsChan:
+					if ok {
+						acceptEntry(entry)
+					} else {
+						drained = true
+					}
+				default:
+					drained = true
 				}
 			}
-
-			logrus.WithFields(fields).Info("Export progress")
-
-		case <-ctx.Done():
-			return ctx.Err()
+			if reorder != nil {
+				for _, ready := range reorder.flush() {
+					writeEntry(ready)
+				}
+			}
+			if compressor != nil {
+				for _, ready := range compressor.flush() {
+					emitEntry(ready)
+				}
+			}
+			if err := finalize(nil); err != nil {
+				return err
+			}
+			logrus.WithFields(logrus.Fields{
+				"total_keys":     processed,
+				"total_duration": time.Since(startTime).Round(time.Second),
+				"max_duration":   e.config.MaxDuration,
+				"vanished_keys":  e.vanished.Load(),
+			}).Warn("Export stopped at --max-duration, the output is partial")
+			return fmt.Errorf("export is incomplete, --max-duration elapsed after %d keys were written: %w", processed, ctx.Err())
 		}
 	}
 }
 
-var config Config
+var (
+	config          Config
+	addrs           []string
+	rewritePrefixes []string
+	renameCommands  []string
+	shard           string
+	passwordFile    string
+	fileMode        string
+	arrayWrapper    bool
+	tcpNoDelay      bool
+	modules         bool
+	tlsEnabled      bool
+	tlsCA           string
+)
+
+// configureLogging sets up logrus. Quiet mode raises the level so only
+// warnings and errors are logged.
+func configureLogging(logLevel string, quiet bool) error {
+	level, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	if quiet && level > logrus.WarnLevel {
+		level = logrus.WarnLevel
+	}
+	logrus.SetLevel(level)
+	logrus.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+	return nil
+}
 
 var rootCmd = &cobra.Command{
 	Use:     "redis-export",
@@ -280,45 +2668,374 @@ var rootCmd = &cobra.Command{
 	Long:    "Export all keys and values from a Redis database to JSON format with concurrent processing",
 	Version: version,
 	Args:    cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return configureLogging(config.LogLevel, config.Quiet)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if !cmd.Flags().Changed("addr") && !cmd.Flags().Changed("output") {
+		if !cmd.Flags().Changed("addr") && !cmd.Flags().Changed("output") && !cmd.Flags().Changed("rdb-file") {
 			return cmd.Help()
 		}
 
-		// Configure logrus
-		level, err := logrus.ParseLevel(config.LogLevel)
+		if err := validateTTLFormat(config.TTLFormat); err != nil {
+			return err
+		}
+
+		if err := validateTTLPrecision(config.TTLPrecision); err != nil {
+			return err
+		}
+
+		if err := validateNumbers(config.Numbers); err != nil {
+			return err
+		}
+
+		if err := validateProfile(config.Profile); err != nil {
+			return err
+		}
+
+		if err := validateFieldMap(config.FieldMap); err != nil {
+			return fmt.Errorf("invalid field map: %w", err)
+		}
+
+		now := timeNow()
+		for _, name := range []*string{&config.OutputFile, &config.GCSObject, &config.AzureBlob, &config.Archive} {
+			expanded, err := expandOutputTemplate(*name, now)
+			if err != nil {
+				return fmt.Errorf("invalid output name: %w", err)
+			}
+			*name = expanded
+		}
+
+		format, extraFormats, err := parseFormats(config.Format)
+		if err != nil {
+			return err
+		}
+		config.Format, config.ExtraFormats = format, extraFormats
+
+		if config.Template != "" {
+			if cmd.Flags().Changed("format") {
+				return errors.New("--template replaces --format, use one or the other")
+			}
+			config.Format = templateFormat
+		}
+
+		if err := validateFormats(config); err != nil {
+			return err
+		}
+
+		config.Unwrapped = !arrayWrapper
+		config.NoModules = !modules
+
+		config.TLS, err = newTLSConfig(tlsEnabled, tlsCA)
+		if err != nil {
+			return err
+		}
+		config.Nagle = !tcpNoDelay
+		if err := validateArrayWrapper(config); err != nil {
+			return err
+		}
+
+		if err := validateSink(config); err != nil {
+			return err
+		}
+
+		if err := validateDedupe(config); err != nil {
+			return err
+		}
+
+		if err := validateCompressDict(config); err != nil {
+			return err
+		}
+
+		if err := validateManifest(config); err != nil {
+			return err
+		}
+
+		if err := validateRDB(config); err != nil {
+			return err
+		}
+
+		if err := validateArchive(config); err != nil {
+			return err
+		}
+
+		config.RedisAddr = addrs[0]
+		if len(addrs) > 1 {
+			config.Sources = addrs
+		}
+		if err := validateSources(config); err != nil {
+			return err
+		}
+
+		if config.SampleRate <= 0 || config.SampleRate > 1 {
+			return fmt.Errorf("--sample-rate must be greater than 0 and at most 1")
+		}
+
+		if config.VanishedWarn < 0 || config.VanishedWarn > 1 {
+			return fmt.Errorf("--vanished-warn-ratio must be between 0 and 1")
+		}
+
+		if config.SampleMembers < 0 {
+			return fmt.Errorf("--sample-members must not be negative")
+		}
+		if config.SampleMembers > 0 && (config.ZsetMinScore != "" || config.ZsetMaxScore != "") {
+			return fmt.Errorf("--sample-members cannot be used with --zset-min-score or --zset-max-score")
+		}
+
+		for flag, bound := range map[string]string{"--zset-min-score": config.ZsetMinScore, "--zset-max-score": config.ZsetMaxScore} {
+			if bound != "" && !validScoreBound(bound) {
+				return fmt.Errorf("%s must be a score, -inf or +inf, optionally prefixed with ( to exclude it: %q", flag, bound)
+			}
+		}
+
+		if config.PoolSize < 0 {
+			return fmt.Errorf("--pool-size must not be negative")
+		}
+
+		if config.MaxDuration < 0 {
+			return fmt.Errorf("--max-duration must not be negative")
+		}
+
+		if config.KeyAffinity && config.AutoWorkers {
+			return fmt.Errorf("--key-affinity and --auto-workers cannot be used together")
+		}
+
+		config.TypeWorkers, err = typeWorkers()
+		if err != nil {
+			return err
+		}
+		if len(config.TypeWorkers) > 0 && (config.KeyAffinity || config.AutoWorkers) {
+			return fmt.Errorf("--workers-<type> cannot be used with --key-affinity or --auto-workers")
+		}
+
+		if config.KeysFile != "" && config.Watch > 0 {
+			return fmt.Errorf("--keys-file and --watch cannot be used together")
+		}
+
+		if len(config.Types) > 0 && len(config.ExcludeTypes) > 0 {
+			return fmt.Errorf("--type and --exclude-type cannot be used together")
+		}
+
+		rules, err := parsePrefixRules(rewritePrefixes)
+		if err != nil {
+			return err
+		}
+		config.PrefixRules = rules
+
+		config.Renames, err = parseCommandRenames(renameCommands)
+		if err != nil {
+			return err
+		}
+
+		config.RedisPassword, err = resolvePassword(config.RedisPassword, passwordFile)
 		if err != nil {
-			return fmt.Errorf("invalid log level: %w", err)
+			return err
+		}
+
+		config.FileMode, err = parseFileMode(fileMode)
+		if err != nil {
+			return err
+		}
+
+		if shard != "" {
+			config.Shard, config.ShardCount, err = parseShard(shard)
+			if err != nil {
+				return err
+			}
 		}
-		logrus.SetLevel(level)
-		logrus.SetFormatter(&logrus.TextFormatter{
-			FullTimestamp: true,
-		})
 
 		exporter := NewExporter(config)
 		defer func() { _ = exporter.client.Close() }()
 
 		ctx := context.Background()
 
-		logrus.WithField("redis_addr", config.RedisAddr).Info("Connecting to Redis")
-		pong, err := exporter.client.Ping(ctx).Result()
-		if err != nil {
-			return fmt.Errorf("failed to connect to Redis: %w", err)
+		if config.RDBFile != "" {
+			logrus.WithField("rdb_file", config.RDBFile).Info("Exporting from RDB file")
+		} else {
+			logrus.WithField("redis_addr", config.RedisAddr).Info("Connecting to Redis")
+			pong, err := exporter.connect(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to connect to Redis: %w", err)
+			}
+			logrus.WithField("response", pong).Info("Successfully connected to Redis")
+			exporter.logServerInfo(ctx)
+		}
+
+		if config.OTelEndpoint != "" {
+			shutdown, err := setupTracing(ctx, config.OTelEndpoint, exporter.client)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = shutdown(context.Background()) }()
+		}
+
+		if config.ReplicaRead {
+			exporter.checkReplica(ctx)
+		}
+
+		if config.Warmup {
+			if err := exporter.warmup(ctx); err != nil {
+				return err
+			}
+		}
+
+		if config.Preview {
+			summary, err := exporter.preview(ctx)
+			if err != nil {
+				return fmt.Errorf("preview failed: %w", err)
+			}
+			printPreview(os.Stderr, summary, config.Workers)
+			// Without a terminal to answer on, the export just continues
+			if isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+				ok, err := confirmExport(os.Stdin, os.Stderr)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					logrus.Info("Export cancelled")
+					return nil
+				}
+			}
+		}
+
+		if config.Profile != "" {
+			path := config.ProfileFile
+			if path == "" {
+				path = profilePath(config.Profile)
+			}
+			stop, err := startProfile(config.Profile, path)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := stop(); err != nil {
+					logrus.WithError(err).Error("Failed to write profile")
+					return
+				}
+				logrus.WithField("profile", path).Info("Wrote profile")
+			}()
+
+			// An interrupted export still writes its profile.
+			var cancel context.CancelFunc
+			ctx, cancel = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+		}
+
+		if config.MaxDuration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, config.MaxDuration)
+			defer cancel()
 		}
-		logrus.WithField("response", pong).Info("Successfully connected to Redis")
 
 		return exporter.Export(ctx)
 	},
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&config.RedisAddr, "addr", "a", "localhost:6379", "Redis server address")
-	rootCmd.Flags().StringVarP(&config.RedisPassword, "password", "p", "", "Redis password")
+	rootCmd.Flags().StringArrayVarP(&addrs, "addr", "a", []string{"localhost:6379"}, "Redis server address; repeat to merge several servers into one export")
+	rootCmd.Flags().BoolVar(&config.Collisions, "detect-collisions", false, "With several --addr, mark keys written from more than one server (keeps every key name in memory)")
+	rootCmd.Flags().StringVarP(&config.RedisPassword, "password", "p", "", "Redis password (prefer REDIS_PASSWORD or --password-file)")
+	rootCmd.Flags().StringVar(&passwordFile, "password-file", "", "Read the Redis password from this file")
+	rootCmd.Flags().StringVar(&fileMode, "file-mode", "0600", "Permissions of the created output files, in octal")
 	rootCmd.Flags().IntVarP(&config.RedisDB, "db", "d", 0, "Redis database number")
-	rootCmd.Flags().StringVarP(&config.OutputFile, "output", "o", "redis_export.json", "Output JSON file")
-	rootCmd.Flags().IntVarP(&config.Workers, "workers", "w", runtime.NumCPU()*2, "Number of worker goroutines")
+	rootCmd.Flags().StringVarP(&config.OutputFile, "output", "o", "redis_export.json", "Output file (- for stdout); {layout} is replaced with the current time in that Go time layout")
+	rootCmd.Flags().DurationVar(&config.MaxDuration, "max-duration", 0, "Stop the export after this long, writing a valid partial output, 0 for no limit")
+	rootCmd.Flags().DurationVar(&config.Watch, "watch", 0, "Export only keys written during this period (requires keyspace notifications)")
+	rootCmd.Flags().StringVar(&config.SinceFile, "since-file", "", "Export only keys touched since the export described by this manifest (uses OBJECT IDLETIME)")
+	rootCmd.Flags().BoolVar(&config.Manifest, "manifest", false, "Write a .manifest.json file describing the export next to the output")
+	rootCmd.Flags().BoolVar(&config.Consistent, "consistent", false, "Record the replication offsets at the start and end of the export in the .manifest.json file")
+	rootCmd.Flags().IntVar(&config.WriteBuffer, "write-buffer", 64*1024, "Output buffer size in bytes (0 to disable buffering)")
+	rootCmd.Flags().StringVar(&config.ErrorsFile, "errors-file", "", "Write keys that failed to export to this JSON file")
+	rootCmd.Flags().StringVarP(&config.Format, "format", "f", "json", "Output format (json, parquet, sqlite), or a comma-separated list to write several in one pass")
+	rootCmd.Flags().BoolVar(&arrayWrapper, "array-wrapper", true, "Wrap JSON output in an array; false writes one JSON object per line without brackets or commas")
+	rootCmd.Flags().BoolVar(&config.NoHTMLEscape, "no-html-escape", false, "Write <, > and & in JSON output verbatim instead of as \\u003c, \\u003e and \\u0026")
+	rootCmd.Flags().StringVar(&config.GCSBucket, "gcs-bucket", "", "Upload the export to this Google Cloud Storage bucket instead of --output")
+	rootCmd.Flags().StringVar(&config.GCSObject, "gcs-object", "", "Object name for --gcs-bucket")
+	rootCmd.Flags().StringVar(&config.AzureContainer, "azure-container", "", "Upload the export to this Azure Blob Storage container instead of --output")
+	rootCmd.Flags().StringVar(&config.AzureBlob, "azure-blob", "", "Blob name for --azure-container")
+	rootCmd.Flags().StringVar(&config.RDBFile, "rdb-file", "", "Export from this RDB dump file instead of a live server")
+	rootCmd.Flags().StringVar(&config.KeysFile, "keys-file", "", "Export only the keys listed in this file, one per line, instead of scanning")
+	rootCmd.Flags().StringVar(&shard, "shard", "", "Export only shard N of M (N/M), assigning keys by CRC32 hash")
+	rootCmd.Flags().StringVar(&config.KeyStart, "key-start", "", "Only export keys >= this value (bytewise)")
+	rootCmd.Flags().StringVar(&config.KeyEnd, "key-end", "", "Only export keys < this value (bytewise)")
+	rootCmd.Flags().IntVar(&config.MaxValueBytes, "max-value-bytes", 0, "Truncate values larger than this many bytes and mark them truncated, 0 to disable")
+	rootCmd.Flags().StringVar(&config.Profile, "profile", "", "Write a pprof profile of the export (cpu or mem)")
+	rootCmd.Flags().StringVar(&config.ProfileFile, "profile-file", "", "File to write the --profile profile to (default \"redis-export.<kind>.pprof\")")
+	rootCmd.Flags().Float64Var(&config.SampleRate, "sample-rate", 1, "Export a random fraction of the scanned keys, such as 0.01 for 1%")
+	rootCmd.Flags().BoolVar(&config.SkipEmpty, "skip-empty", false, "Omit lists, sets, sorted sets and hashes that are empty when read")
+	rootCmd.Flags().IntVar(&config.ListLimit, "list-limit", 0, "Export only the first N elements of each list and mark longer lists truncated, 0 to disable")
+	rootCmd.Flags().Float64Var(&config.VanishedWarn, "vanished-warn-ratio", 0.01, "Warn that the export is likely incomplete when more than this fraction of keys vanished during it, 0 to disable")
+	rootCmd.Flags().IntVar(&config.SampleMembers, "sample-members", 0, "Export N random members of each set, sorted set and hash and mark larger ones sampled, 0 to disable")
+	rootCmd.Flags().StringVar(&config.ZsetMinScore, "zset-min-score", "", "Export only sorted set members scoring at least this, such as 100, or (100 to exclude it")
+	rootCmd.Flags().StringVar(&config.ZsetMaxScore, "zset-max-score", "", "Export only sorted set members scoring at most this, such as 100, or (100 to exclude it")
+	rootCmd.Flags().BoolVar(&config.PersistentOnly, "persistent-only", false, "Only export keys with no expiry (TTL -1)")
+	rootCmd.Flags().BoolVar(&config.KeysOnly, "keys-only", false, "Export only key, type, and TTL without fetching values")
+	rootCmd.Flags().BoolVar(&config.DedupeValues, "dedupe-values", false, "Write each distinct value once to a .values.json side file and reference it by SHA-256")
+	rootCmd.Flags().BoolVar(&config.CompressDict, "compress-dict", false, "Compress each value with a zstd dictionary trained on the first values, stored in the .manifest.json file")
+	rootCmd.Flags().StringVar(&config.Match, "match", "*", "Only export keys matching this glob pattern (SCAN MATCH)")
+	rootCmd.Flags().BoolVar(&config.TagRouting, "hash-tag-routing", true, "On a cluster, scan only the node owning the slot of a --match pattern like '{tag}*'")
+	rootCmd.Flags().StringSliceVarP(&config.Types, "type", "t", nil, "Only export keys of these types (repeatable)")
+	rootCmd.Flags().StringSliceVar(&config.ExcludeTypes, "exclude-type", nil, "Skip keys of these types (repeatable)")
+	rootCmd.Flags().StringSliceVar(&config.SkipValuesFor, "skip-values-for", nil, "Export only key, type and TTL for keys of these types, such as stream,list, and values for the rest")
+	rootCmd.Flags().BoolVar(&config.OrderedHashes, "ordered-hashes", false, "Export hashes as field-sorted arrays of {field, value} objects")
+	rootCmd.Flags().BoolVar(&modules, "modules", true, "Export module types (RedisJSON via JSON.GET, others via DUMP); false fails their keys as unsupported")
+	rootCmd.Flags().BoolVar(&config.ReportBinary, "report-binary", false, "List keys whose values contain invalid UTF-8 at the end of the export")
+	rootCmd.Flags().BoolVar(&config.NamespaceStats, "namespace-stats", false, "Report key counts, memory, and types per key prefix as JSON instead of exporting values")
+	rootCmd.Flags().IntVar(&config.NamespaceDepth, "namespace-depth", 2, "Number of colon-delimited segments that form a namespace for --namespace-stats")
+	rootCmd.Flags().BoolVar(&config.SizeHistogram, "size-histogram", false, "Log a histogram of value sizes and the largest keys at completion")
+	rootCmd.Flags().BoolVar(&config.TTLHistogram, "ttl-histogram", false, "Log how many keys have no expiry or expire within a minute, an hour, a day or later at completion")
+	rootCmd.Flags().IntVar(&config.TopKeys, "top-keys", 10, "Number of largest keys to report with --size-histogram, and of slowest keys with --slow-key-threshold")
+	rootCmd.Flags().StringVar(&config.Archive, "archive", "", "Bundle the export and its side files (manifest, values, errors, stats) into this zip file once it completes")
+	rootCmd.Flags().StringVar(&config.StatsJSON, "stats-json", "", "Write the final statistics as JSON to this file at completion, for CI checks")
+	rootCmd.Flags().BoolVar(&config.WorkerStats, "worker-stats", false, "Log the keys exported and fetch time of each worker at completion, to spot unbalanced work")
+	rootCmd.Flags().DurationVar(&config.SlowThreshold, "slow-key-threshold", 0, "Log keys that take longer than this to fetch and report the slowest at completion, 0 to disable")
+	rootCmd.Flags().IntVar(&config.RowGroupSize, "row-group-size", 10000, "Rows per Parquet row group")
+	rootCmd.Flags().IntVarP(&config.Workers, "workers", "w", runtime.NumCPU()*2, "Number of worker goroutines (the upper bound with --auto-workers)")
+	rootCmd.Flags().BoolVar(&config.AutoWorkers, "auto-workers", false, "Scale the worker count between --min-workers and --workers based on throughput and latency")
+	rootCmd.Flags().IntVar(&config.MinWorkers, "min-workers", 2, "Starting and minimum worker count with --auto-workers")
+	for _, t := range poolTypes {
+		typeWorkerFlags[t] = rootCmd.Flags().Int("workers-"+t, 0, fmt.Sprintf("Dedicated workers for %s keys, 0 to handle them in the --workers pool", t))
+	}
+	rootCmd.Flags().BoolVar(&config.KeyAffinity, "key-affinity", false, "Route each key to a worker by its hash, so workers tend to reuse the same cluster shard connections")
+	rootCmd.Flags().BoolVar(&config.AdaptiveScan, "adaptive-scan", false, "Grow or shrink the SCAN COUNT hint based on SCAN latency")
 	rootCmd.Flags().IntVarP(&config.BatchSize, "batch", "b", 1000, "Batch size for key scanning")
-	rootCmd.Flags().StringVarP(&config.LogLevel, "log-level", "l", "info", "Log level (trace, debug, info, warn, error, fatal, panic)")
+	rootCmd.Flags().BoolVar(&config.ProgressBar, "progress-bar", false, "Show a live progress bar instead of progress log lines (interactive terminals only)")
+	rootCmd.PersistentFlags().BoolVarP(&config.Quiet, "quiet", "q", false, "Only log warnings and errors, and skip progress updates")
+	rootCmd.Flags().DurationVar(&config.StatsInterval, "stats-interval", defaultStatsInterval, "How often to log export progress, 0 to disable")
+	rootCmd.Flags().BoolVar(&config.Milestones, "milestone-logging", false, "Log progress only as the key count reaches 1k, 10k, 100k and so on, instead of every --stats-interval")
+	rootCmd.PersistentFlags().StringVarP(&config.LogLevel, "log-level", "l", "info", "Log level (trace, debug, info, warn, error, fatal, panic)")
+	rootCmd.Flags().StringArrayVar(&rewritePrefixes, "rewrite-prefix", nil, "Rewrite key prefix in output as old=new (repeatable, first match wins)")
+	rootCmd.Flags().StringVar(&config.JQ, "jq", "", "Transform each value with a jq expression before writing")
+	rootCmd.Flags().BoolVar(&config.JQDropNull, "jq-drop-null", false, "Drop entries whose --jq result is null")
+	rootCmd.Flags().StringVar(&config.FilterScript, "filter-script", "", "Export only keys for which this Lua script, run with the key as KEYS[1] and its type as ARGV[1], returns true or non-zero")
+	rootCmd.Flags().StringVar(&config.Template, "template", "", "Write each entry as a line rendered by this Go text/template, e.g. '{{.Key}}={{.Value}}', instead of --format")
+	rootCmd.Flags().BoolVar(&config.WithSlot, "with-slot", false, "Include each key's cluster hash slot and owning node")
+	rootCmd.Flags().BoolVar(&config.WithFreq, "with-freq", false, "Include each key's LFU access frequency (requires an LFU maxmemory-policy)")
+	rootCmd.Flags().BoolVar(&config.WithRefcount, "with-refcount", false, "Include each key's OBJECT REFCOUNT, or shared for Redis's shared objects")
+	rootCmd.Flags().BoolVar(&config.ReplicaRead, "replica-read", false, "Read from a replica (issues READONLY on each connection)")
+	rootCmd.Flags().StringVar(&config.TTLPrecision, "ttl-precision", ttlPrecisionSeconds, "TTL precision: s (TTL) or ms (PTTL, also written as ttl_ms)")
+	rootCmd.Flags().StringVar(&config.TTLFormat, "ttl-format", ttlFormatSeconds, "TTL representation in JSON output: seconds, iso8601 (absolute expiry) or duration")
+	rootCmd.Flags().BoolVar(&config.TTLWithValue, "ttl-with-value", false, "Read each key's TTL in the same pipeline as its value, so the expiry matches the value captured")
+	rootCmd.Flags().BoolVar(&config.ParseJSON, "parse-json-values", false, "Embed string and hash field values that hold JSON objects or arrays as nested JSON rather than quoted strings")
+	rootCmd.Flags().StringVar(&config.Numbers, "numbers", numbersString, "How numeric string values are written: string (quoted) or json.Number (bare, exact digits)")
+	rootCmd.Flags().StringVar(&config.ClientName, "client-name", "redis-export/"+version, "Connection name shown in CLIENT LIST")
+	rootCmd.Flags().StringArrayVar(&renameCommands, "rename-command", nil, "Send a command under the name the server's rename-command gave it, as orig=renamed (repeatable)")
+	rootCmd.Flags().BoolVar(&config.RESP3, "resp3", false, "Use the RESP3 protocol (Redis 6+)")
+	rootCmd.Flags().DurationVar(&config.WorkerJitter, "worker-jitter", 100*time.Millisecond, "Maximum random delay before each worker starts")
+	rootCmd.Flags().BoolVar(&config.Warmup, "warmup", false, "Pre-establish the connection pool before exporting")
+	rootCmd.Flags().StringVar(&config.OTelEndpoint, "otel-endpoint", "", "Send OpenTelemetry traces to this OTLP HTTP endpoint (e.g. http://localhost:4318)")
+	rootCmd.Flags().IntVar(&config.ConnectRetries, "connect-retries", 0, "Number of times to retry the initial connection")
+	rootCmd.Flags().DurationVar(&config.ConnectWait, "connect-wait", time.Second, "Initial wait between connection retries (doubles each attempt)")
+	rootCmd.Flags().BoolVar(&config.Preview, "preview", false, "Sample the first keys, print an estimate of the full export and ask before running it")
+	rootCmd.Flags().BoolVar(&tlsEnabled, "tls", false, "Connect to Redis over TLS, verifying the server against the system certificates or --tls-ca")
+	rootCmd.Flags().StringVar(&tlsCA, "tls-ca", "", "PEM file or directory of .pem/.crt files with the CA certificates to trust instead of the system ones (implies --tls)")
+	rootCmd.Flags().DurationVar(&config.KeepAlive, "keepalive", 30*time.Second, "Interval between TCP keepalive probes on Redis connections, negative to disable")
+	rootCmd.Flags().BoolVar(&tcpNoDelay, "tcp-nodelay", true, "Disable Nagle's algorithm on Redis connections so small commands are sent at once")
+	rootCmd.Flags().IntVar(&config.PoolSize, "pool-size", 0, "Maximum number of Redis connections (default twice the workers)")
+	rootCmd.Flags().IntVar(&config.PoolRetries, "pool-retries", 3, "Number of times to retry a key that failed because every pooled connection was busy")
+	rootCmd.Flags().IntVar(&config.Reconnects, "reconnect-retries", 5, "Number of times to retry a scan or key after losing the connection to Redis, with a growing wait")
+	rootCmd.Flags().BoolVar(&config.Ordered, "ordered", false, "Write entries in scan order")
+	rootCmd.Flags().IntVar(&config.ReorderWindow, "reorder-window", 1000, "Maximum entries held back waiting for a slow key in --ordered mode")
+	rootCmd.Flags().StringToStringVar(&config.FieldMap, "field-map", nil, "Rename output fields (e.g. key=k,type=t,value=v,ttl=expiry)")
 }
 
 func main() {
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -59,7 +69,7 @@ func TestGetValueByType_UnsupportedType(t *testing.T) {
 
 	exporter := &Exporter{
 		client: db,
-		config: Config{},
+		config: Config{NoModules: true},
 	}
 
 	ctx := context.Background()
@@ -158,3 +168,716 @@ func TestExporter_Export_InvalidOutputPath(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to create output file")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestExporter_Connect_RetriesUntilReachable(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{
+			ConnectRetries: 3,
+			ConnectWait:    time.Millisecond,
+		},
+	}
+
+	mock.ExpectPing().SetErr(errors.New("connection refused"))
+	mock.ExpectPing().SetErr(errors.New("connection refused"))
+	mock.ExpectPing().SetVal("PONG")
+
+	pong, err := exporter.connect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", pong)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Connect_SingleAttemptByDefault(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{},
+	}
+
+	mock.ExpectPing().SetErr(errors.New("connection refused"))
+
+	_, err := exporter.connect(context.Background())
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_LogServerInfo(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	exporter := &Exporter{client: db}
+
+	mock.ExpectInfo("memory").SetVal("# Memory\r\nused_memory:1572864\r\nmaxmemory:2097152\r\nmaxmemory_policy:allkeys-lru\r\n")
+	mock.ExpectDBSize().SetVal(1234)
+
+	exporter.logServerInfo(context.Background())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "Redis server info", entry.Message)
+	assert.Equal(t, int64(1234), entry.Data["db_keys"])
+	assert.Equal(t, formatBytes(1572864), entry.Data["used_memory"])
+	assert.Equal(t, formatBytes(2097152), entry.Data["maxmemory"])
+	assert.Equal(t, 75.0, entry.Data["memory_used_pct"])
+	assert.Equal(t, "allkeys-lru", entry.Data["maxmemory_policy"])
+}
+
+func TestExporter_LogServerInfo_BestEffort(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	exporter := &Exporter{client: db}
+
+	// A proxy that refuses DBSIZE still reports memory, and no limit
+	mock.ExpectInfo("memory").SetVal("# Memory\r\nused_memory:1024\r\nmaxmemory:0\r\n")
+	mock.ExpectDBSize().SetErr(errors.New("ERR unknown command 'DBSIZE'"))
+
+	exporter.logServerInfo(context.Background())
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.InfoLevel, entry.Level)
+	assert.NotContains(t, entry.Data, "db_keys")
+	assert.Equal(t, "unlimited", entry.Data["maxmemory"])
+	assert.NotContains(t, entry.Data, "memory_used_pct")
+}
+
+func TestValidateFieldMap(t *testing.T) {
+	assert.NoError(t, validateFieldMap(nil))
+	assert.NoError(t, validateFieldMap(map[string]string{"key": "k", "type": "t", "value": "v", "ttl": "expiry"}))
+	assert.NoError(t, validateFieldMap(map[string]string{"key": "type", "type": "key"}))
+
+	err := validateFieldMap(map[string]string{"key": "id", "value": "id"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "both map to")
+
+	err = validateFieldMap(map[string]string{"key": "type"})
+	assert.Error(t, err)
+
+	err = validateFieldMap(map[string]string{"name": "n"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown field")
+}
+
+func TestExporter_Export_FieldMap(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_field_map.json",
+		Workers:    1,
+		BatchSize:  10,
+		FieldMap:   map[string]string{"key": "k", "type": "t", "value": "v", "ttl": "expiry"},
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"test:key"}, 0)
+	mock.ExpectType("test:key").SetVal("string")
+	mock.ExpectGet("test:key").SetVal("test value")
+	mock.ExpectTTL("test:key").SetVal(60 * time.Second)
+
+	err := exporter.Export(context.Background())
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var entries []map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, map[string]interface{}{
+		"k":      "test:key",
+		"t":      "string",
+		"v":      "test value",
+		"expiry": float64(60),
+	}, entries[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReorderBuffer_OutOfOrder(t *testing.T) {
+	buf := newReorderBuffer(10)
+
+	var written []int64
+	for _, seq := range []int64{2, 0, 4, 1, 3, 6, 5} {
+		for _, entry := range buf.push(&RedisEntry{seq: seq}) {
+			written = append(written, entry.seq)
+		}
+	}
+	for _, entry := range buf.flush() {
+		written = append(written, entry.seq)
+	}
+
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6}, written)
+}
+
+func TestReorderBuffer_SkipsStuckKey(t *testing.T) {
+	buf := newReorderBuffer(2)
+
+	var written []int64
+	// seq 0 never arrives; once the window overflows the gap is skipped.
+	for _, seq := range []int64{1, 2, 3, 4} {
+		for _, entry := range buf.push(&RedisEntry{seq: seq}) {
+			written = append(written, entry.seq)
+		}
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4}, written)
+
+	// A late arrival for a skipped slot is still written.
+	late := buf.push(&RedisEntry{seq: 0})
+	require.Len(t, late, 1)
+	assert.Equal(t, int64(0), late[0].seq)
+	assert.Empty(t, buf.flush())
+}
+
+func TestNewRedisOptions_Protocol(t *testing.T) {
+	assert.Equal(t, 2, newRedisOptions(Config{}).Protocol)
+	assert.Equal(t, 3, newRedisOptions(Config{RESP3: true}).Protocol)
+}
+
+func TestNewRedisOptions_PoolSize(t *testing.T) {
+	opts := newRedisOptions(Config{Workers: 8})
+	assert.Equal(t, 16, opts.PoolSize, "defaults to twice the workers")
+	assert.Equal(t, 8, opts.MinIdleConns)
+
+	opts = newRedisOptions(Config{Workers: 64, PoolSize: 10})
+	assert.Equal(t, 10, opts.PoolSize)
+	assert.Equal(t, 10, opts.MinIdleConns, "idle connections never exceed the pool")
+
+	opts = newRedisOptions(Config{Workers: 2, PoolSize: 10})
+	assert.Equal(t, 10, opts.PoolSize)
+	assert.Equal(t, 2, opts.MinIdleConns)
+}
+
+func TestNewRedisOptions_Dialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	for _, config := range []Config{
+		{KeepAlive: 15 * time.Second},
+		{KeepAlive: -1, Nagle: true},
+	} {
+		opts := newRedisOptions(config)
+		require.NotNil(t, opts.Dialer, "the custom dialer is wired into the options")
+
+		conn, err := opts.Dialer(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		assert.IsType(t, &net.TCPConn{}, conn)
+		_ = conn.Close()
+	}
+}
+
+func TestExporter_GetValueByType_HashRESP3(t *testing.T) {
+	opts := newRedisOptions(Config{RESP3: true})
+	require.Equal(t, 3, opts.Protocol)
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{RESP3: true},
+	}
+
+	mock.ExpectHGetAll("test:hash").SetVal(map[string]string{"field1": "value1"})
+
+	value, err := exporter.getValueByType(context.Background(), "test:hash", "hash")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"field1": "value1"}, value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestParsePrefixRules(t *testing.T) {
+	rules, err := parsePrefixRules([]string{"prod:=staging:", "tmp:="})
+	require.NoError(t, err)
+	assert.Equal(t, []PrefixRule{{Old: "prod:", New: "staging:"}, {Old: "tmp:", New: ""}}, rules)
+
+	_, err = parsePrefixRules([]string{"prod:"})
+	assert.Error(t, err)
+
+	_, err = parsePrefixRules([]string{"=staging:"})
+	assert.Error(t, err)
+}
+
+func TestExporter_RewriteKey(t *testing.T) {
+	exporter := &Exporter{
+		config: Config{
+			PrefixRules: []PrefixRule{
+				{Old: "prod:", New: "staging:"},
+				{Old: "prod:user:", New: "never:"},
+				{Old: "cache:", New: ""},
+			},
+		},
+	}
+
+	assert.Equal(t, "staging:user:1", exporter.rewriteKey("prod:user:1"))
+	assert.Equal(t, "session:1", exporter.rewriteKey("cache:session:1"))
+	assert.Equal(t, "other:1", exporter.rewriteKey("other:1"))
+}
+
+func TestSizeHistogram(t *testing.T) {
+	h := newSizeHistogram(3)
+
+	h.observe("tiny", 10)
+	h.observe("small", 500)
+	h.observe("medium", 5<<10)
+	h.observe("large", 50<<10)
+	h.observe("larger", 500<<10)
+	h.observe("huge", 2<<20)
+	h.observe("also-tiny", 1023)
+
+	assert.Equal(t, []int64{3, 1, 1, 1, 1}, h.counts)
+	assert.Equal(t, []keySize{
+		{Key: "huge", Size: 2 << 20},
+		{Key: "larger", Size: 500 << 10},
+		{Key: "large", Size: 50 << 10},
+	}, h.top)
+}
+
+func TestTTLHistogram(t *testing.T) {
+	h := newTTLHistogram()
+
+	for _, entry := range []*RedisEntry{
+		{Key: "persistent"},
+		{Key: "also-persistent"},
+		{Key: "lock", TTLMillis: 400},
+		{Key: "rate-limit", TTL: 59},
+		{Key: "session", TTL: 60},
+		{Key: "cache", TTL: 3599},
+		{Key: "token", TTL: 12 * 3600},
+		{Key: "daily", TTL: 24 * 3600},
+		{Key: "archive", TTL: 30 * 24 * 3600},
+	} {
+		h.observe(entry)
+	}
+
+	assert.Equal(t, int64(2), h.persistent)
+	assert.Equal(t, []int64{2, 2, 1, 2}, h.counts, "<1m, <1h, <1d and >1d")
+}
+
+func TestSlowKeys(t *testing.T) {
+	s := newSlowKeys(2)
+
+	s.observe("a", 2*time.Second)
+	s.observe("b", 5*time.Second)
+	s.observe("c", time.Second)
+	s.observe("d", 3*time.Second)
+
+	assert.Equal(t, int64(4), s.count)
+	assert.Equal(t, []slowKey{
+		{Key: "b", Duration: 5 * time.Second},
+		{Key: "d", Duration: 3 * time.Second},
+	}, s.top)
+}
+
+func TestValueSize(t *testing.T) {
+	assert.Equal(t, len(`"hello"`), valueSize("hello"))
+	assert.Equal(t, len(`["a","b"]`), valueSize([]string{"a", "b"}))
+}
+
+func TestExporter_Export_BufferedWrites(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:  "test_export_buffered.json",
+		Workers:     1,
+		BatchSize:   10,
+		WriteBuffer: 4096,
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"key1"}, 0)
+	mock.ExpectType("key1").SetVal("string")
+	mock.ExpectGet("key1").SetVal("value1")
+	mock.ExpectTTL("key1").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	content, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(content, &entries), "buffer must be flushed after the closing bracket")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "value1", entries[0].Value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func benchmarkJSONArrayWriter(b *testing.B, bufferSize int) {
+	file, err := os.CreateTemp(b.TempDir(), "bench-*.json")
+	require.NoError(b, err)
+	defer func() { _ = file.Close() }()
+
+	var out io.Writer = file
+	var buffered *bufio.Writer
+	if bufferSize > 0 {
+		buffered = bufio.NewWriterSize(file, bufferSize)
+		out = buffered
+	}
+
+	writer, err := newJSONArrayWriter(out, true, true, func(entry *RedisEntry) (interface{}, error) { return entry, nil })
+	require.NoError(b, err)
+
+	entry := &RedisEntry{Key: "bench:key", Type: "string", Value: "some moderately sized value", TTL: 60}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := writer.WriteEntry(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+	require.NoError(b, writer.Close())
+	if buffered != nil {
+		require.NoError(b, buffered.Flush())
+	}
+}
+
+func BenchmarkJSONArrayWriter_Unbuffered(b *testing.B) {
+	benchmarkJSONArrayWriter(b, 0)
+}
+
+func BenchmarkJSONArrayWriter_Buffered(b *testing.B) {
+	benchmarkJSONArrayWriter(b, 64*1024)
+}
+
+func TestNewRedisOptions_ReplicaRead(t *testing.T) {
+	assert.Nil(t, newRedisOptions(Config{}).OnConnect)
+	assert.NotNil(t, newRedisOptions(Config{ReplicaRead: true}).OnConnect)
+}
+
+func TestReadOnlyOnConnect(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	conn := db.Conn()
+	defer func() { _ = conn.Close() }()
+
+	mock.ExpectReadOnly().SetVal("OK")
+	assert.NoError(t, readOnlyOnConnect(ctx, conn))
+
+	mock.ExpectReadOnly().SetErr(errors.New("ERR This instance has cluster support disabled"))
+	assert.NoError(t, readOnlyOnConnect(ctx, conn))
+
+	mock.ExpectReadOnly().SetErr(errors.New("ERR connection reset"))
+	assert.Error(t, readOnlyOnConnect(ctx, conn))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_KeyInScope_Range(t *testing.T) {
+	exporter := &Exporter{config: Config{KeyStart: "b", KeyEnd: "d"}}
+
+	assert.False(t, exporter.keyInScope("a"))
+	assert.False(t, exporter.keyInScope("azzz"))
+	assert.True(t, exporter.keyInScope("b"))
+	assert.True(t, exporter.keyInScope("c:1"))
+	assert.True(t, exporter.keyInScope("czzz"))
+	assert.False(t, exporter.keyInScope("d"))
+	assert.False(t, exporter.keyInScope("e"))
+
+	unbounded := &Exporter{config: Config{KeyStart: "m"}}
+	assert.False(t, unbounded.keyInScope("a"))
+	assert.True(t, unbounded.keyInScope("zzz"))
+
+	all := &Exporter{config: Config{}}
+	assert.True(t, all.keyInScope(""))
+	assert.True(t, all.keyInScope("anything"))
+}
+
+func TestConfigureLogging_Quiet(t *testing.T) {
+	t.Cleanup(func() { logrus.SetLevel(logrus.InfoLevel) })
+
+	require.NoError(t, configureLogging("debug", true))
+	assert.Equal(t, logrus.WarnLevel, logrus.GetLevel())
+
+	require.NoError(t, configureLogging("error", true))
+	assert.Equal(t, logrus.ErrorLevel, logrus.GetLevel(), "quiet never lowers a stricter level")
+
+	require.NoError(t, configureLogging("debug", false))
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	assert.Error(t, configureLogging("loud", false))
+}
+
+func TestNewExporter_ClientName(t *testing.T) {
+	exporter := NewExporter(Config{RedisAddr: "localhost:6379", Workers: 1, ClientName: "redis-export/test"})
+	defer func() { _ = exporter.client.Close() }()
+
+	assert.Equal(t, "redis-export/test", exporter.client.Options().ClientName)
+}
+
+func TestFormatTTL(t *testing.T) {
+	previous := timeNow
+	timeNow = func() time.Time { return time.Date(2025, 8, 12, 10, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { timeNow = previous })
+
+	ttl := 90 * time.Minute
+	assert.Equal(t, "", formatTTL(ttl, ttlFormatSeconds))
+	assert.Equal(t, "2025-08-12T12:00:00Z", formatTTL(ttl, ttlFormatISO8601))
+	assert.Equal(t, "1h30m0s", formatTTL(ttl, ttlFormatDuration))
+
+	assert.NoError(t, validateTTLFormat(ttlFormatISO8601))
+	assert.Error(t, validateTTLFormat("minutes"))
+
+	assert.NoError(t, validateTTLPrecision(ttlPrecisionMillis))
+	assert.Error(t, validateTTLPrecision("us"))
+}
+
+func TestExporter_OutputEntry_TTLFormat(t *testing.T) {
+	previous := timeNow
+	timeNow = func() time.Time { return time.Date(2025, 8, 12, 10, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { timeNow = previous })
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{ttlFormatSeconds, `"ttl":3600`},
+		{ttlFormatISO8601, `"ttl":"2025-08-12T11:30:00Z"`},
+		{ttlFormatDuration, `"ttl":"1h0m0s"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			db, mock := redismock.NewClientMock()
+			defer func() { _ = db.Close() }()
+
+			exporter := &Exporter{
+				client: db,
+				config: Config{TTLFormat: tt.format, FieldMap: map[string]string{"ttl": "expires"}},
+			}
+
+			mock.ExpectType("session").SetVal("string")
+			mock.ExpectGet("session").SetVal("abc")
+			mock.ExpectTTL("session").SetVal(time.Hour)
+
+			entry, err := exporter.processKey(context.Background(), "session")
+			require.NoError(t, err)
+			assert.Equal(t, int64(3600), entry.TTL)
+
+			out, err := exporter.outputEntry(entry)
+			require.NoError(t, err)
+			data, err := json.Marshal(out)
+			require.NoError(t, err)
+			assert.Contains(t, string(data), strings.Replace(tt.want, "ttl", "expires", 1))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRedisEntry_UnmarshalTTLFormats(t *testing.T) {
+	previous := timeNow
+	timeNow = func() time.Time { return time.Date(2025, 8, 12, 10, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { timeNow = previous })
+
+	tests := []struct {
+		ttl  string
+		want int64
+	}{
+		{`3600`, 3600},
+		{`"1h0m0s"`, 3600},
+		{`"2025-08-12T11:30:00Z"`, 3600},
+		{`"2025-08-12T09:00:00Z"`, 1},
+	}
+
+	for _, tt := range tests {
+		var entry RedisEntry
+		require.NoError(t, json.Unmarshal([]byte(`{"key":"k","type":"string","value":"v","ttl":`+tt.ttl+`}`), &entry))
+		assert.Equal(t, tt.want, entry.TTL, tt.ttl)
+		assert.Equal(t, "k", entry.Key)
+		assert.Equal(t, "v", entry.Value)
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	shard, count, err := parseShard("2/5")
+	require.NoError(t, err)
+	assert.Equal(t, 2, shard)
+	assert.Equal(t, 5, count)
+
+	for _, value := range []string{"2", "0/5", "6/5", "a/5", "1/0", "1/b"} {
+		_, _, err := parseShard(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestExporter_KeyInScope_Shards(t *testing.T) {
+	const shards = 4
+	exporters := make([]*Exporter, shards)
+	for i := range exporters {
+		exporters[i] = &Exporter{config: Config{Shard: i + 1, ShardCount: shards}}
+	}
+
+	counts := make([]int, shards)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		owners := 0
+		for shard, exporter := range exporters {
+			if exporter.keyInScope(key) {
+				owners++
+				counts[shard]++
+			}
+		}
+		assert.Equal(t, 1, owners, "key %s should belong to exactly one shard", key)
+	}
+
+	for shard, count := range counts {
+		assert.Positive(t, count, "shard %d should own some keys", shard+1)
+	}
+}
+
+func TestResolvePassword(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("from-file\n"), 0o600))
+
+	t.Setenv("REDIS_PASSWORD", "")
+	password, err := resolvePassword("from-flag", "")
+	require.NoError(t, err)
+	assert.Equal(t, "from-flag", password)
+
+	t.Setenv("REDIS_PASSWORD", "from-env")
+	password, err = resolvePassword("from-flag", "")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", password, "env overrides the flag")
+
+	password, err = resolvePassword("from-flag", passwordFile)
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", password, "file overrides env and flag")
+
+	_, err = resolvePassword("", filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestHasInvalidUTF8(t *testing.T) {
+	invalid := "\xff\xfe"
+
+	assert.False(t, hasInvalidUTF8("plain"))
+	assert.False(t, hasInvalidUTF8("héllo"))
+	assert.True(t, hasInvalidUTF8(invalid))
+	assert.True(t, hasInvalidUTF8([]string{"ok", invalid}))
+	assert.True(t, hasInvalidUTF8(map[string]string{invalid: "ok"}))
+	assert.True(t, hasInvalidUTF8([]redis.Z{{Score: 1, Member: invalid}}))
+	assert.True(t, hasInvalidUTF8([]redis.XMessage{{ID: "1-0", Values: map[string]interface{}{"f": invalid}}}))
+	assert.False(t, hasInvalidUTF8([]HashField{{Field: "f", Value: "v"}}))
+}
+
+func TestScanPacer(t *testing.T) {
+	pacer := newScanPacer(100)
+	assert.Equal(t, int64(10), pacer.count)
+
+	for i := 0; i < 10; i++ {
+		pacer.observe(time.Millisecond)
+	}
+	assert.Equal(t, int64(1000), pacer.count, "fast responses grow COUNT up to ten times the batch size")
+
+	pacer.observe(scanPacerTarget * 3 / 2)
+	assert.Equal(t, int64(1000), pacer.count, "latency between the target and twice the target holds COUNT")
+
+	for i := 0; i < 10; i++ {
+		pacer.observe(time.Second)
+	}
+	assert.Equal(t, int64(10), pacer.count, "slow responses back COUNT off to the minimum")
+}
+
+func TestExpandOutputTemplate(t *testing.T) {
+	now := time.Date(2025, 8, 12, 9, 30, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"redis_export.json", "redis_export.json"},
+		{"backup-{2006-01-02T15-04-05}.json", "backup-2025-08-12T09-30-05.json"},
+		{"backup-{2006-01-02}.json.gz", "backup-2025-08-12.json.gz"},
+		{"{2006}/{01}/{02}/dump.parquet", "2025/08/12/dump.parquet"},
+		{"-", "-"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := expandOutputTemplate(tt.name, now)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, got)
+	}
+
+	for _, name := range []string{"backup-{2006.json", "backup-2006}.json", "a}{2006}.json"} {
+		_, err := expandOutputTemplate(name, now)
+		assert.Error(t, err, name)
+	}
+
+	got, err := expandOutputTemplate("backup-{2006-01-02T15-04-05}.json", time.Now())
+	require.NoError(t, err)
+	assert.Regexp(t, `^backup-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.json$`, got)
+}
+
+func TestIsJSONNumber(t *testing.T) {
+	for _, s := range []string{"0", "-1", "12345678901234567890", "3.14", "1e10", "-2.5E-3"} {
+		assert.True(t, isJSONNumber(s), s)
+	}
+	for _, s := range []string{"", "007", "+1", "1.", ".5", "1e", "0x10", "NaN", "1 "} {
+		assert.False(t, isJSONNumber(s), s)
+	}
+}
+
+func TestRedisEntry_UnmarshalJSON_Numbers(t *testing.T) {
+	var entry RedisEntry
+	require.NoError(t, json.Unmarshal([]byte(`{"key":"n","type":"string","value":98765432109876543210}`), &entry))
+	assert.Equal(t, json.Number("98765432109876543210"), entry.Value, "no float64 precision loss")
+
+	// Nested numbers such as zset scores still decode as float64
+	require.NoError(t, json.Unmarshal([]byte(`{"key":"z","type":"zset","value":[{"Score":1.5,"Member":"m"}]}`), &entry))
+	assert.Equal(t, []interface{}{map[string]interface{}{"Score": 1.5, "Member": "m"}}, entry.Value)
+}
+
+func TestWorkerStats(t *testing.T) {
+	s := newWorkerStats(3)
+
+	s.observe(0, time.Second)
+	s.observe(0, 2*time.Second)
+	s.observe(2, 5*time.Second)
+
+	assert.Equal(t, int64(2), s[0].keys.Load())
+	assert.Equal(t, int64(3*time.Second), s[0].fetch.Load())
+	assert.Zero(t, s[1].keys.Load())
+	assert.Equal(t, int64(3), s.total())
+}
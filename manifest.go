@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Manifest describes a completed export. It is written next to the export
+// with --manifest, --since-file, --compress-dict or --consistent, and a
+// later --since-file run reads it to export only what changed afterwards.
+type Manifest struct {
+	Output       string       `json:"output"`
+	Format       string       `json:"format"`
+	StartedAt    time.Time    `json:"started_at"`
+	CompletedAt  time.Time    `json:"completed_at"`
+	Keys         int64        `json:"keys"`
+	VanishedKeys int64        `json:"vanished_keys"`
+	Since        *time.Time   `json:"since,omitempty"` // cutoff of an incremental export
+	Base         string       `json:"base,omitempty"`  // export the incremental follows
+	Pool         ManifestPool `json:"pool"`
+	Dictionary   []byte       `json:"dictionary,omitempty"` // --compress-dict zstd dictionary
+
+	Replication *ManifestReplication `json:"replication,omitempty"`
+}
+
+// ManifestReplication is the replication offset window of a --consistent
+// export. SCAN doesn't see a snapshot, so writes made between the two offsets
+// may or may not be in the export; writes before the start are.
+type ManifestReplication struct {
+	StartOffset int64 `json:"start_offset"`
+	EndOffset   int64 `json:"end_offset"`
+}
+
+// ManifestPool is the connection pool usage of the export.
+type ManifestPool struct {
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"`
+	TotalConns uint32 `json:"total_conns"`
+	IdleConns  uint32 `json:"idle_conns"`
+}
+
+func manifestPool(stats *redis.PoolStats) ManifestPool {
+	return ManifestPool{
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Timeouts:   stats.Timeouts,
+		TotalConns: stats.TotalConns,
+		IdleConns:  stats.IdleConns,
+	}
+}
+
+// manifestPath returns the manifest file for an export:
+// export.json becomes export.manifest.json.
+func manifestPath(output string) string {
+	return sidecarPath(output, "manifest")
+}
+
+// validateManifest checks that --manifest, --since-file and --consistent are
+// used with a local output file, next to which the manifest is written.
+func validateManifest(config Config) error {
+	if !config.Manifest && config.SinceFile == "" && !config.Consistent {
+		return nil
+	}
+	if config.OutputFile == stdoutOutput || config.GCSBucket != "" || config.AzureContainer != "" {
+		return errors.New("--manifest, --since-file and --consistent require a local output file")
+	}
+	return nil
+}
+
+func readManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if manifest.CompletedAt.IsZero() {
+		return nil, fmt.Errorf("manifest %s has no completion time", path)
+	}
+	return &manifest, nil
+}
+
+// writeManifest writes manifest to path with the given --file-mode, since
+// a trained compression dictionary in it holds data taken from the values.
+func writeManifest(path string, manifest *Manifest, mode os.FileMode) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, append(data, '\n'), mode); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// writeManifest writes the manifest of a completed export.
+func (e *Exporter) writeManifest(startedAt time.Time, keys int64, stats *redis.PoolStats) error {
+	format := e.config.Format
+	if format == "" {
+		format = "json"
+	}
+	manifest := &Manifest{
+		Output:       e.config.OutputFile,
+		Format:       format,
+		StartedAt:    startedAt.UTC(),
+		CompletedAt:  timeNow().UTC(),
+		Keys:         keys,
+		VanishedKeys: e.vanished.Load(),
+		Base:         e.sinceBase,
+		Dictionary:   e.dictionary,
+		Replication:  e.replication,
+		Pool:         manifestPool(stats),
+	}
+	if !e.since.IsZero() {
+		since := e.since.UTC()
+		manifest.Since = &since
+	}
+
+	path := manifestPath(e.config.OutputFile)
+	if err := writeManifest(path, manifest, e.config.FileMode); err != nil {
+		return err
+	}
+	logrus.WithField("manifest", path).Info("Wrote export manifest")
+	return nil
+}
+
+// loadSince reads the --since-file manifest, setting the cutoff after which
+// keys must have been touched to be exported. Idle times aren't tracked
+// under an LFU maxmemory-policy, so that is rejected up front; servers that
+// refuse CONFIG GET are assumed to be fine.
+func (e *Exporter) loadSince(ctx context.Context) error {
+	previous, err := readManifest(e.config.SinceFile)
+	if err != nil {
+		return err
+	}
+
+	policy, err := e.client.ConfigGet(ctx, "maxmemory-policy").Result()
+	if err != nil {
+		logrus.WithError(err).Debug("Could not read maxmemory-policy")
+	} else if strings.Contains(policy["maxmemory-policy"], "lfu") {
+		return fmt.Errorf("--since-file needs OBJECT IDLETIME, which is not tracked under the %s maxmemory-policy", policy["maxmemory-policy"])
+	}
+
+	e.since = previous.CompletedAt
+	e.sinceBase = previous.Output
+	logrus.WithFields(logrus.Fields{
+		"since": e.since.Format(time.RFC3339),
+		"base":  e.sinceBase,
+	}).Info("Exporting keys changed since the previous export")
+	return nil
+}
+
+// touchedSince reports whether key was written or read after the --since-file
+// cutoff, going by OBJECT IDLETIME.
+func (e *Exporter) touchedSince(ctx context.Context, key string) (bool, error) {
+	idle, err := e.client.ObjectIdleTime(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return !timeNow().Add(-idle).Before(e.since), nil
+}
+
+// consistentWarnBytes is how far the replication offset may advance during
+// a --consistent export before the export warns that it ran under heavy
+// writes.
+var consistentWarnBytes int64 = 1 << 20
+
+// replicationOffset returns the server's master_repl_offset, which counts
+// the bytes of writes it has applied. On a replica it is the offset
+// replicated so far.
+func (e *Exporter) replicationOffset(ctx context.Context) (int64, error) {
+	info, err := e.client.Info(ctx, "replication").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get replication info: %w", err)
+	}
+	value, ok := infoField(info, "master_repl_offset")
+	if !ok {
+		return 0, errors.New("replication info has no master_repl_offset")
+	}
+	offset, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse replication offset %q: %w", value, err)
+	}
+	return offset, nil
+}
+
+// endConsistent records the replication offset at the end of a --consistent
+// export, warning when heavy writes during the export make it likely that
+// keys changed while they were being read.
+func (e *Exporter) endConsistent(ctx context.Context) error {
+	offset, err := e.replicationOffset(ctx)
+	if err != nil {
+		return err
+	}
+	e.replication.EndOffset = offset
+
+	advanced := offset - e.replication.StartOffset
+	fields := logrus.Fields{
+		"start_offset": e.replication.StartOffset,
+		"end_offset":   offset,
+		"written":      formatBytes(advanced),
+	}
+	if advanced > consistentWarnBytes {
+		logrus.WithFields(fields).Warn("Redis took heavy writes during the export, keys may have changed while they were read")
+		return nil
+	}
+	logrus.WithFields(fields).Info("Replication offset window of the export")
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestPath(t *testing.T) {
+	assert.Equal(t, "backup.manifest.json", manifestPath("backup.json"))
+	assert.Equal(t, "dir/backup.manifest.json", manifestPath("dir/backup.parquet"))
+}
+
+func TestValidateManifest(t *testing.T) {
+	assert.NoError(t, validateManifest(Config{OutputFile: stdoutOutput}))
+	assert.NoError(t, validateManifest(Config{OutputFile: "export.json", Manifest: true}))
+	assert.Error(t, validateManifest(Config{OutputFile: stdoutOutput, Manifest: true}))
+	assert.Error(t, validateManifest(Config{GCSBucket: "b", GCSObject: "o", SinceFile: "base.manifest.json"}))
+}
+
+func TestExporter_Export_SinceFile(t *testing.T) {
+	dir := t.TempDir()
+	baseCompleted := time.Date(2025, 8, 12, 10, 0, 0, 0, time.UTC)
+	now := baseCompleted.Add(time.Hour)
+
+	origNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = origNow }()
+
+	sinceFile := manifestPath(filepath.Join(dir, "base.json"))
+	require.NoError(t, writeManifest(sinceFile, &Manifest{
+		Output:      filepath.Join(dir, "base.json"),
+		Format:      "json",
+		StartedAt:   baseCompleted.Add(-time.Minute),
+		CompletedAt: baseCompleted,
+		Keys:        3,
+	}, 0))
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(dir, "incr-1.json"),
+		Workers:    1,
+		BatchSize:  10,
+		SinceFile:  sinceFile,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectConfigGet("maxmemory-policy").SetVal(map[string]string{"maxmemory-policy": "allkeys-lru"})
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"changed", "stale", "gone"}, 0)
+	mock.ExpectType("changed").SetVal("string")
+	mock.ExpectObjectIdleTime("changed").SetVal(10 * time.Minute)
+	mock.ExpectGet("changed").SetVal("new")
+	mock.ExpectTTL("changed").SetVal(-1 * time.Second)
+	mock.ExpectType("stale").SetVal("string")
+	mock.ExpectObjectIdleTime("stale").SetVal(2 * time.Hour)
+	mock.ExpectType("gone").SetVal("string")
+	mock.ExpectObjectIdleTime("gone").RedisNil()
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 1, "only keys touched after the base export completed are exported")
+	assert.Equal(t, "changed", entries[0].Key)
+
+	manifest, err := readManifest(manifestPath(config.OutputFile))
+	require.NoError(t, err)
+	assert.Equal(t, config.OutputFile, manifest.Output)
+	assert.Equal(t, filepath.Join(dir, "base.json"), manifest.Base)
+	require.NotNil(t, manifest.Since)
+	assert.True(t, baseCompleted.Equal(*manifest.Since))
+	assert.True(t, now.Equal(manifest.CompletedAt))
+	assert.Equal(t, int64(1), manifest.Keys)
+}
+
+func TestExporter_Export_SinceFileRejectsLFU(t *testing.T) {
+	dir := t.TempDir()
+	sinceFile := filepath.Join(dir, "base.manifest.json")
+	require.NoError(t, writeManifest(sinceFile, &Manifest{Output: "base.json", CompletedAt: time.Now()}, 0))
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db, config: Config{
+		OutputFile: filepath.Join(dir, "incr.json"),
+		Workers:    1,
+		BatchSize:  10,
+		SinceFile:  sinceFile,
+	}}
+
+	mock.ExpectConfigGet("maxmemory-policy").SetVal(map[string]string{"maxmemory-policy": "allkeys-lfu"})
+
+	err := exporter.Export(context.Background())
+	assert.ErrorContains(t, err, "allkeys-lfu")
+}
+
+func TestExporter_Export_Manifest(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Manifest:   true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"k"}, 0)
+	mock.ExpectType("k").SetVal("string")
+	mock.ExpectGet("k").SetVal("v")
+	mock.ExpectTTL("k").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	manifest, err := readManifest(manifestPath(config.OutputFile))
+	require.NoError(t, err)
+	assert.Equal(t, "json", manifest.Format)
+	assert.Equal(t, int64(1), manifest.Keys)
+	assert.Nil(t, manifest.Since)
+	assert.Empty(t, manifest.Base)
+	assert.False(t, manifest.StartedAt.After(manifest.CompletedAt))
+}
+
+func replicationInfo(offset int64) string {
+	return fmt.Sprintf("# Replication\r\nrole:master\r\nconnected_slaves:1\r\nmaster_repl_offset:%d\r\nrepl_backlog_active:1\r\n", offset)
+}
+
+func TestExporter_Export_Consistent(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	orig := consistentWarnBytes
+	consistentWarnBytes = 100
+	t.Cleanup(func() { consistentWarnBytes = orig })
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Consistent: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectInfo("replication").SetVal(replicationInfo(1000))
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"k"}, 0)
+	mock.ExpectType("k").SetVal("string")
+	mock.ExpectGet("k").SetVal("v")
+	mock.ExpectTTL("k").SetVal(-1 * time.Second)
+	mock.ExpectInfo("replication").SetVal(replicationInfo(5000))
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	manifest, err := readManifest(manifestPath(config.OutputFile))
+	require.NoError(t, err, "--consistent writes the manifest without --manifest")
+	assert.Equal(t, &ManifestReplication{StartOffset: 1000, EndOffset: 5000}, manifest.Replication)
+
+	var warned bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel && strings.Contains(entry.Message, "heavy writes") {
+			warned = true
+		}
+	}
+	assert.True(t, warned, "4000 bytes of writes is over the 100 byte threshold")
+}
+
+func TestExporter_ReplicationOffset(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	exporter := &Exporter{client: db}
+
+	mock.ExpectInfo("replication").SetVal(replicationInfo(42))
+	offset, err := exporter.replicationOffset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), offset)
+
+	mock.ExpectInfo("replication").SetVal("# Replication\r\nrole:master\r\n")
+	_, err = exporter.replicationOffset(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// NamespaceStat aggregates the keys sharing a namespace prefix.
+type NamespaceStat struct {
+	Namespace   string         `json:"namespace"`
+	Keys        int64          `json:"keys"`
+	MemoryBytes int64          `json:"memory_bytes"`
+	Types       map[string]int `json:"types"`
+}
+
+// NamespaceReport is the --namespace-stats output.
+type NamespaceReport struct {
+	Depth       int             `json:"depth"`
+	TotalKeys   int64           `json:"total_keys"`
+	MemoryBytes int64           `json:"memory_bytes"`
+	Namespaces  []NamespaceStat `json:"namespaces"`
+}
+
+// keyNamespace returns the first depth colon-delimited segments of key. The
+// last segment is never part of the namespace, so "user:42" is in "user" at
+// any depth; keys without a colon have the empty namespace.
+func keyNamespace(key string, depth int) string {
+	segments := strings.Split(key, ":")
+	n := len(segments) - 1
+	if n > depth {
+		n = depth
+	}
+	return strings.Join(segments[:n], ":")
+}
+
+// namespaceCounter accumulates NamespaceStats from concurrent workers.
+type namespaceCounter struct {
+	mu    sync.Mutex
+	stats map[string]*NamespaceStat
+}
+
+func (c *namespaceCounter) add(namespace, keyType string, memory int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stat, ok := c.stats[namespace]
+	if !ok {
+		stat = &NamespaceStat{Namespace: namespace, Types: make(map[string]int)}
+		c.stats[namespace] = stat
+	}
+	stat.Keys++
+	stat.MemoryBytes += memory
+	stat.Types[keyType]++
+}
+
+// report returns the namespaces ordered by memory, then key count, largest
+// first.
+func (c *namespaceCounter) report(depth int) NamespaceReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := NamespaceReport{Depth: depth, Namespaces: make([]NamespaceStat, 0, len(c.stats))}
+	for _, stat := range c.stats {
+		report.TotalKeys += stat.Keys
+		report.MemoryBytes += stat.MemoryBytes
+		report.Namespaces = append(report.Namespaces, *stat)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		a, b := report.Namespaces[i], report.Namespaces[j]
+		if a.MemoryBytes != b.MemoryBytes {
+			return a.MemoryBytes > b.MemoryBytes
+		}
+		if a.Keys != b.Keys {
+			return a.Keys > b.Keys
+		}
+		return a.Namespace < b.Namespace
+	})
+	return report
+}
+
+// namespaceStats scans the keyspace and writes a NamespaceReport to the
+// output instead of exporting values. Each key costs one pipelined TYPE and
+// MEMORY USAGE; if the server rejects MEMORY USAGE, memory is reported as 0.
+func (e *Exporter) namespaceStats(ctx context.Context) error {
+	depth := e.config.NamespaceDepth
+	if depth < 1 {
+		depth = 1
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"output_file": e.outputName(),
+		"depth":       depth,
+	}).Info("Starting namespace analysis")
+
+	counter := &namespaceCounter{stats: make(map[string]*NamespaceStat)}
+	var noMemory atomic.Bool
+
+	keysChan := make(chan keyTask, e.config.BatchSize)
+	go e.scanKeys(ctx, keysChan)
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range keysChan {
+				keyType, memory, err := e.typeAndMemory(ctx, task.key, &noMemory)
+				if err != nil {
+					logrus.WithField("key", task.key).Error("Error inspecting key: ", err)
+					continue
+				}
+				if keyType == "none" || !e.typeAllowed(keyType) {
+					continue
+				}
+				counter.add(keyNamespace(task.key, depth), keyType, memory)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	report := counter.report(depth)
+
+	output, err := e.openOutput(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = output.Close() }()
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write namespace stats: %w", err)
+	}
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"namespaces":   len(report.Namespaces),
+		"total_keys":   report.TotalKeys,
+		"memory_bytes": report.MemoryBytes,
+	}).Info("Namespace analysis completed")
+	return nil
+}
+
+// typeAndMemory fetches a key's type and MEMORY USAGE in one round trip.
+// The first MEMORY USAGE failure sets noMemory, after which only TYPE is
+// sent.
+func (e *Exporter) typeAndMemory(ctx context.Context, key string, noMemory *atomic.Bool) (string, int64, error) {
+	if noMemory.Load() {
+		keyType, err := e.client.Type(ctx, key).Result()
+		return keyType, 0, err
+	}
+
+	pipe := e.client.Pipeline()
+	typeCmd := pipe.Type(ctx, key)
+	memoryCmd := pipe.MemoryUsage(ctx, key)
+	_, _ = pipe.Exec(ctx)
+
+	keyType, err := typeCmd.Result()
+	if err != nil {
+		return "", 0, err
+	}
+
+	memory, err := memoryCmd.Result()
+	if errors.Is(err, redis.Nil) {
+		return keyType, 0, nil
+	}
+	if err != nil {
+		if noMemory.CompareAndSwap(false, true) {
+			logrus.WithError(err).Warn("MEMORY USAGE failed, reporting namespace memory as 0")
+		}
+		return keyType, 0, nil
+	}
+	return keyType, memory, nil
+}
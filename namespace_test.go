@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyNamespace(t *testing.T) {
+	tests := []struct {
+		key      string
+		depth    int
+		expected string
+	}{
+		{"a:b:1", 2, "a:b"},
+		{"a:b:1", 1, "a"},
+		{"a:b:1", 5, "a:b"},
+		{"user:42", 2, "user"},
+		{"plain", 2, ""},
+		{":x", 1, ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, keyNamespace(tt.key, tt.depth), "%s at depth %d", tt.key, tt.depth)
+	}
+}
+
+func TestExporter_NamespaceStats(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:     filepath.Join(t.TempDir(), "namespaces.json"),
+		Workers:        1,
+		BatchSize:      10,
+		NamespaceStats: true,
+		NamespaceDepth: 2,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a:b:1", "a:b:2", "a:c:1"}, 0)
+	mock.ExpectType("a:b:1").SetVal("string")
+	mock.ExpectMemoryUsage("a:b:1").SetVal(50)
+	mock.ExpectType("a:b:2").SetVal("hash")
+	mock.ExpectMemoryUsage("a:b:2").SetVal(70)
+	mock.ExpectType("a:c:1").SetVal("string")
+	mock.ExpectMemoryUsage("a:c:1").SetVal(200)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var report NamespaceReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, NamespaceReport{
+		Depth:       2,
+		TotalKeys:   3,
+		MemoryBytes: 320,
+		Namespaces: []NamespaceStat{
+			{Namespace: "a:c", Keys: 1, MemoryBytes: 200, Types: map[string]int{"string": 1}},
+			{Namespace: "a:b", Keys: 2, MemoryBytes: 120, Types: map[string]int{"string": 1, "hash": 1}},
+		},
+	}, report)
+}
+
+func TestExporter_NamespaceStats_WithoutMemoryUsage(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile:     filepath.Join(t.TempDir(), "namespaces.json"),
+		Workers:        1,
+		BatchSize:      10,
+		NamespaceStats: true,
+		NamespaceDepth: 1,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a:b:1", "a:c:1", "gone:1"}, 0)
+	mock.ExpectType("a:b:1").SetVal("string")
+	mock.ExpectMemoryUsage("a:b:1").SetErr(assert.AnError)
+	mock.ExpectType("a:c:1").SetVal("list")
+	mock.ExpectType("gone:1").SetVal("none")
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet(), "MEMORY USAGE is not retried after it fails")
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+
+	var report NamespaceReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, []NamespaceStat{
+		{Namespace: "a", Keys: 2, Types: map[string]int{"string": 1, "list": 1}},
+	}, report.Namespaces, "keys that vanished during the scan are not counted")
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// entryWriter writes exported entries in a particular output format. Close
+// finalizes the format (closing brackets, footers) but does not close the
+// underlying writer.
+type entryWriter interface {
+	WriteEntry(entry *RedisEntry) error
+	Close() error
+}
+
+// newEntryWriter returns an entryWriter for format writing to w. SQLite
+// opens the database at path itself and ignores w.
+func (e *Exporter) newEntryWriter(format, path string, w io.Writer) (entryWriter, error) {
+	switch format {
+	case "", "json":
+		return newJSONArrayWriter(w, !e.config.Unwrapped, !e.config.NoHTMLEscape, e.outputEntry)
+	case "parquet":
+		return newParquetWriter(w, e.config.RowGroupSize), nil
+	case "sqlite":
+		return newSQLiteWriter(path, e.config.BatchSize)
+	case templateFormat:
+		return newTemplateWriter(w, e.tmpl), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// parseFormats splits a comma-separated --format value into the format of
+// the main output and any extra formats written in the same pass.
+func parseFormats(value string) (string, []string, error) {
+	var formats []string
+	seen := make(map[string]bool)
+	for _, format := range strings.Split(value, ",") {
+		format = strings.TrimSpace(format)
+		switch format {
+		case "json", "parquet", "sqlite":
+		default:
+			return "", nil, fmt.Errorf("unsupported output format: %q", format)
+		}
+		if seen[format] {
+			return "", nil, fmt.Errorf("output format %s given twice", format)
+		}
+		seen[format] = true
+		formats = append(formats, format)
+	}
+	return formats[0], formats[1:], nil
+}
+
+// formatPath returns the file an extra format is written to: the output
+// file with its extension replaced by the format name, so export.json with
+// parquet becomes export.parquet.
+func formatPath(output, format string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + "." + format
+}
+
+// validateFormats checks that extra formats are written next to a local
+// output file, without colliding with it.
+func validateFormats(config Config) error {
+	if len(config.ExtraFormats) == 0 {
+		return nil
+	}
+	if config.OutputFile == stdoutOutput || config.GCSBucket != "" || config.AzureContainer != "" {
+		return errors.New("multiple output formats require a local output file")
+	}
+	if config.DedupeValues {
+		return errors.New("--dedupe-values cannot be used with multiple output formats")
+	}
+	for _, format := range config.ExtraFormats {
+		if formatPath(config.OutputFile, format) == config.OutputFile {
+			return fmt.Errorf("the %s output would overwrite %s, use a different extension for --output", format, config.OutputFile)
+		}
+	}
+	return nil
+}
+
+// validateArrayWrapper checks that --array-wrapper=false is only given with
+// JSON output, the one format it applies to.
+func validateArrayWrapper(config Config) error {
+	if !config.Unwrapped || config.Format == "json" {
+		return nil
+	}
+	for _, format := range config.ExtraFormats {
+		if format == "json" {
+			return nil
+		}
+	}
+	return errors.New("--array-wrapper=false requires JSON output")
+}
+
+// openExtraWriters opens a writer for each extra output format.
+func (e *Exporter) openExtraWriters() ([]entryWriter, error) {
+	var writers []entryWriter
+	closeAll := func() {
+		for _, w := range writers {
+			_ = w.Close()
+		}
+	}
+
+	for _, format := range e.config.ExtraFormats {
+		path := formatPath(e.config.OutputFile, format)
+		if format == "sqlite" {
+			// Truncate a stale file so the database starts empty, as the
+			// main output does.
+			file, err := createFile(path, e.config.FileMode)
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("failed to create %s output file: %w", format, err)
+			}
+			_ = file.Close()
+			w, err := e.newEntryWriter(format, path, nil)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			writers = append(writers, w)
+			continue
+		}
+
+		file, err := createFile(path, e.config.FileMode)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to create %s output file: %w", format, err)
+		}
+		buffered := bufio.NewWriter(file)
+		w, err := e.newEntryWriter(format, path, buffered)
+		if err != nil {
+			_ = file.Close()
+			closeAll()
+			return nil, err
+		}
+		writers = append(writers, &fileEntryWriter{entryWriter: w, buffered: buffered, file: file})
+	}
+	return writers, nil
+}
+
+// fileEntryWriter is an entryWriter that owns its output file, flushing and
+// closing it on Close.
+type fileEntryWriter struct {
+	entryWriter
+	buffered *bufio.Writer
+	file     *os.File
+}
+
+func (f *fileEntryWriter) Close() error {
+	err := f.entryWriter.Close()
+	if err == nil {
+		err = f.buffered.Flush()
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// multiEntryWriter writes every entry to each of its writers, so one pass
+// over Redis produces every output format.
+type multiEntryWriter []entryWriter
+
+func (m multiEntryWriter) WriteEntry(entry *RedisEntry) error {
+	var errs []error
+	for _, w := range m {
+		if err := w.WriteEntry(entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiEntryWriter) Close() error {
+	var errs []error
+	for _, w := range m {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// jsonArrayWriter writes entries as a JSON array with one entry per line.
+// Without wrap, the brackets and separating commas are left out, leaving
+// one JSON object per line. Without escapeHTML, <, > and & in values are
+// written as is rather than as \u003c, \u003e and \u0026.
+type jsonArrayWriter struct {
+	w         io.Writer
+	encoder   *json.Encoder
+	transform func(*RedisEntry) (interface{}, error)
+	wrap      bool
+	first     bool
+}
+
+func newJSONArrayWriter(w io.Writer, wrap, escapeHTML bool, transform func(*RedisEntry) (interface{}, error)) (*jsonArrayWriter, error) {
+	if wrap {
+		if _, err := io.WriteString(w, "[\n"); err != nil {
+			return nil, err
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(escapeHTML)
+	return &jsonArrayWriter{
+		w:         w,
+		encoder:   encoder,
+		transform: transform,
+		wrap:      wrap,
+		first:     true,
+	}, nil
+}
+
+func (j *jsonArrayWriter) WriteEntry(entry *RedisEntry) error {
+	out, err := j.transform(entry)
+	if err != nil {
+		return err
+	}
+
+	if !j.wrap {
+		return j.encoder.Encode(out)
+	}
+
+	if !j.first {
+		if _, err := io.WriteString(j.w, ",\n"); err != nil {
+			return err
+		}
+	} else {
+		j.first = false
+	}
+
+	return j.encoder.Encode(out)
+}
+
+func (j *jsonArrayWriter) Close() error {
+	if !j.wrap {
+		return nil
+	}
+	_, err := io.WriteString(j.w, "\n]")
+	return err
+}
+
+// jsonEntryReader reads back the entries of a JSON export, whether it was
+// written as an array or, with --array-wrapper=false, as one object per
+// line. An empty input is an unwrapped export of no keys.
+type jsonEntryReader struct {
+	dec  *json.Decoder
+	wrap bool
+}
+
+func newJSONEntryReader(r io.Reader) (*jsonEntryReader, error) {
+	br := bufio.NewReader(r)
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return &jsonEntryReader{dec: json.NewDecoder(br)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	reader := &jsonEntryReader{dec: json.NewDecoder(br)}
+	switch first {
+	case '[':
+		reader.wrap = true
+		if _, err := reader.dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+	case '{':
+	default:
+		return nil, errors.New("export is not a JSON array or one JSON object per line")
+	}
+	return reader, nil
+}
+
+// firstNonSpace returns the first byte of br that isn't JSON whitespace,
+// leaving it unread.
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c, br.UnreadByte()
+	}
+}
+
+// More reports whether another entry follows.
+func (j *jsonEntryReader) More() bool {
+	return j.dec.More()
+}
+
+// Decode decodes the next entry into v.
+func (j *jsonEntryReader) Decode(v interface{}) error {
+	return j.dec.Decode(v)
+}
+
+// Close checks that an array export ends with its closing bracket, so a
+// truncated file isn't taken for a complete one.
+func (j *jsonEntryReader) Close() error {
+	if !j.wrap {
+		return nil
+	}
+	if _, err := j.dec.Token(); err != nil {
+		return fmt.Errorf("export is truncated: %w", err)
+	}
+	return nil
+}
+
+// marshalJSON is json.Marshal, except that with --no-html-escape <, > and &
+// are left unescaped as they are in the output itself.
+func (e *Exporter) marshalJSON(v interface{}) ([]byte, error) {
+	if !e.config.NoHTMLEscape {
+		return json.Marshal(v)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// FailedKey is a key that could not be exported, as recorded in the errors
+// file.
+type FailedKey struct {
+	Key   string `json:"key"`
+	Type  string `json:"type,omitempty"`
+	Error string `json:"error"`
+}
+
+// writeFailedKeys writes every key received on failures to w as a JSON array.
+// It keeps draining failures after a write error so senders never block.
+func writeFailedKeys(w io.Writer, failures <-chan FailedKey) error {
+	_, err := io.WriteString(w, "[\n")
+
+	encoder := json.NewEncoder(w)
+	first := true
+	for failed := range failures {
+		if err != nil {
+			continue
+		}
+		if !first {
+			_, err = io.WriteString(w, ",\n")
+		}
+		first = false
+		if err == nil {
+			err = encoder.Encode(failed)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n]")
+	return err
+}
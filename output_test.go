@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormats(t *testing.T) {
+	format, extra, err := parseFormats("json")
+	require.NoError(t, err)
+	assert.Equal(t, "json", format)
+	assert.Empty(t, extra)
+
+	format, extra, err = parseFormats("json, parquet,sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, "json", format)
+	assert.Equal(t, []string{"parquet", "sqlite"}, extra)
+
+	for _, value := range []string{"", "csv", "json,", "json,json"} {
+		_, _, err := parseFormats(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFormatPath(t *testing.T) {
+	assert.Equal(t, "dir/export.parquet", formatPath("dir/export.json", "parquet"))
+	assert.Equal(t, "export.sqlite", formatPath("export", "sqlite"))
+}
+
+func TestValidateFormats(t *testing.T) {
+	assert.NoError(t, validateFormats(Config{OutputFile: stdoutOutput}))
+	assert.NoError(t, validateFormats(Config{OutputFile: "export.json", ExtraFormats: []string{"parquet"}}))
+	assert.Error(t, validateFormats(Config{OutputFile: stdoutOutput, ExtraFormats: []string{"parquet"}}))
+	assert.Error(t, validateFormats(Config{OutputFile: "export.json", ExtraFormats: []string{"parquet"}, DedupeValues: true}))
+	assert.Error(t, validateFormats(Config{OutputFile: "export.parquet", Format: "json", ExtraFormats: []string{"parquet"}}),
+		"the extra output must not overwrite the main one")
+}
+
+func TestValidateArrayWrapper(t *testing.T) {
+	assert.NoError(t, validateArrayWrapper(Config{Format: "parquet"}))
+	assert.NoError(t, validateArrayWrapper(Config{Format: "json", Unwrapped: true}))
+	assert.NoError(t, validateArrayWrapper(Config{Format: "parquet", ExtraFormats: []string{"json"}, Unwrapped: true}))
+	assert.Error(t, validateArrayWrapper(Config{Format: "sqlite", Unwrapped: true}))
+}
+
+func TestExporter_Export_Unwrapped(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Format:     "json",
+		Unwrapped:  true,
+		Workers:    1,
+		BatchSize:  10,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a", "b"}, 0)
+	for _, key := range []string{"a", "b"} {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("value-" + key)
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"key\":\"a\",\"type\":\"string\",\"value\":\"value-a\"}\n{\"key\":\"b\",\"type\":\"string\",\"value\":\"value-b\"}\n", string(data),
+		"one object per line, without brackets or commas between them")
+}
+
+func TestExporter_Export_NoHTMLEscape(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"escaped by default", Config{}, `\u003ctag\u003e a \u0026 b`},
+		{"verbatim", Config{NoHTMLEscape: true}, `<tag> a & b`},
+		{"verbatim with field map", Config{NoHTMLEscape: true, FieldMap: map[string]string{"value": "v"}}, `<tag> a & b`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := redismock.NewClientMock()
+			defer func() { _ = db.Close() }()
+
+			config := tc.config
+			config.OutputFile = filepath.Join(t.TempDir(), "export.json")
+			config.Workers = 1
+			config.BatchSize = 10
+			exporter := &Exporter{client: db, config: config}
+
+			mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"k"}, 0)
+			mock.ExpectType("k").SetVal("string")
+			mock.ExpectGet("k").SetVal("<tag> a & b")
+			mock.ExpectTTL("k").SetVal(-1 * time.Second)
+
+			require.NoError(t, exporter.Export(context.Background()))
+			require.NoError(t, mock.ExpectationsWereMet())
+
+			data, err := os.ReadFile(config.OutputFile)
+			require.NoError(t, err)
+			assert.Contains(t, string(data), tc.expected)
+		})
+	}
+}
+
+func TestExporter_Export_MultipleFormats(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	dir := t.TempDir()
+	config := Config{
+		OutputFile:   filepath.Join(dir, "export.json"),
+		Format:       "json",
+		ExtraFormats: []string{"parquet", "sqlite"},
+		Workers:      1,
+		BatchSize:    10,
+	}
+	// A stale database at the extra output path is replaced
+	require.NoError(t, os.WriteFile(formatPath(config.OutputFile, "sqlite"), []byte("stale"), 0o644))
+
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"s", "l", "h"}, 0)
+	mock.ExpectType("s").SetVal("string")
+	mock.ExpectGet("s").SetVal("v")
+	mock.ExpectTTL("s").SetVal(30 * time.Second)
+	mock.ExpectType("l").SetVal("list")
+	mock.ExpectLRange("l", 0, -1).SetVal([]string{"a", "b"})
+	mock.ExpectTTL("l").SetVal(-1 * time.Second)
+	mock.ExpectType("h").SetVal("hash")
+	mock.ExpectHGetAll("h").SetVal(map[string]string{"f": "v"})
+	mock.ExpectTTL("h").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	want := []string{"h", "l", "s"}
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	var jsonKeys []string
+	for _, entry := range entries {
+		jsonKeys = append(jsonKeys, entry.Key)
+	}
+	sort.Strings(jsonKeys)
+	assert.Equal(t, want, jsonKeys)
+
+	rows, err := parquet.ReadFile[parquetRow](formatPath(config.OutputFile, "parquet"))
+	require.NoError(t, err)
+	var parquetKeys []string
+	for _, row := range rows {
+		parquetKeys = append(parquetKeys, row.Key)
+	}
+	sort.Strings(parquetKeys)
+	assert.Equal(t, want, parquetKeys)
+
+	var sqliteKeys []string
+	for _, row := range readSQLiteRows(t, formatPath(config.OutputFile, "sqlite"), "SELECT key, type, ttl, value FROM redis_entries ORDER BY key") {
+		sqliteKeys = append(sqliteKeys, row.Key)
+	}
+	assert.Equal(t, want, sqliteKeys)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRow is the Parquet schema for an exported entry. Values are stored
+// as JSON because their shape varies by type.
+type parquetRow struct {
+	Key   string `parquet:"key"`
+	Type  string `parquet:"type"`
+	TTL   int64  `parquet:"ttl"`
+	Value string `parquet:"value"`
+	Freq  int64  `parquet:"freq"`
+	Slot  *int64 `parquet:"slot,optional"`
+	Node  string `parquet:"node,optional"`
+
+	Refcount int64 `parquet:"refcount"`
+	Shared   bool  `parquet:"shared"`
+
+	Truncated bool `parquet:"truncated"`
+}
+
+// parquetWriter buffers entries and writes them as Parquet row groups of up
+// to rowGroupSize rows.
+type parquetWriter struct {
+	writer       *parquet.GenericWriter[parquetRow]
+	rows         []parquetRow
+	rowGroupSize int
+}
+
+func newParquetWriter(w io.Writer, rowGroupSize int) *parquetWriter {
+	if rowGroupSize < 1 {
+		rowGroupSize = 10000
+	}
+	return &parquetWriter{
+		writer:       parquet.NewGenericWriter[parquetRow](w),
+		rows:         make([]parquetRow, 0, rowGroupSize),
+		rowGroupSize: rowGroupSize,
+	}
+}
+
+func (p *parquetWriter) WriteEntry(entry *RedisEntry) error {
+	value, err := json.Marshal(entry.Value)
+	if err != nil {
+		return err
+	}
+
+	var slot *int64
+	if entry.Slot != nil {
+		s := int64(*entry.Slot)
+		slot = &s
+	}
+
+	p.rows = append(p.rows, parquetRow{
+		Key:   entry.Key,
+		Type:  entry.Type,
+		TTL:   entry.TTL,
+		Value: string(value),
+		Freq:  int64(entry.Freq),
+		Slot:  slot,
+		Node:  entry.Node,
+
+		Refcount: entry.Refcount,
+		Shared:   entry.Shared,
+
+		Truncated: entry.Truncated,
+	})
+
+	if len(p.rows) >= p.rowGroupSize {
+		return p.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (p *parquetWriter) flush() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	if _, err := p.writer.Write(p.rows); err != nil {
+		return err
+	}
+	p.rows = p.rows[:0]
+	return p.writer.Flush()
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	return p.writer.Close()
+}
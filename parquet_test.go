@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetWriter_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	writer := newParquetWriter(&buf, 2)
+
+	entries := []*RedisEntry{
+		{Key: "s", Type: "string", Value: "hello", TTL: 60},
+		{Key: "l", Type: "list", Value: []string{"a", "b"}},
+		{Key: "h", Type: "hash", Value: map[string]string{"f": "v"}},
+	}
+	for _, entry := range entries {
+		require.NoError(t, writer.WriteEntry(entry))
+	}
+	require.NoError(t, writer.Close())
+
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, []parquetRow{
+		{Key: "s", Type: "string", TTL: 60, Value: `"hello"`},
+		{Key: "l", Type: "list", Value: `["a","b"]`},
+		{Key: "h", Type: "hash", Value: `{"f":"v"}`},
+	}, rows)
+}
+
+func TestExporter_Export_Parquet(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export.parquet",
+		Format:     "parquet",
+		Workers:    1,
+		BatchSize:  10,
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"key1"}, 0)
+	mock.ExpectType("key1").SetVal("string")
+	mock.ExpectGet("key1").SetVal("value1")
+	mock.ExpectTTL("key1").SetVal(30 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	rows, err := parquet.ReadFile[parquetRow](config.OutputFile)
+	require.NoError(t, err)
+	assert.Equal(t, []parquetRow{{Key: "key1", Type: "string", TTL: 30, Value: `"value1"`}}, rows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
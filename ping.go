@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pingConfig       Config
+	pingPasswordFile string
+	pingTimeout      time.Duration
+)
+
+// ping checks that Redis answers PING within timeout, returning the round
+// trip time.
+func (e *Exporter) ping(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := e.client.Ping(ctx).Err(); err != nil {
+		return 0, fmt.Errorf("redis at %s is not reachable: %w", e.config.RedisAddr, err)
+	}
+	return time.Since(start), nil
+}
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Check that Redis is reachable, for liveness probes",
+	Long:  "Connect to Redis and issue PING without exporting anything. Exits 0 when Redis answers and non-zero otherwise.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		pingConfig.RedisPassword, err = resolvePassword(pingConfig.RedisPassword, pingPasswordFile)
+		if err != nil {
+			return err
+		}
+
+		// A single connection is all a probe needs
+		pingConfig.Workers = 1
+		exporter := NewExporter(pingConfig)
+		defer func() { _ = exporter.client.Close() }()
+
+		latency, err := exporter.ping(cmd.Context(), pingTimeout)
+		if err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{
+			"redis_addr": pingConfig.RedisAddr,
+			"latency":    latency.Round(time.Microsecond),
+		}).Info("Redis is reachable")
+		return nil
+	},
+}
+
+func init() {
+	pingCmd.Flags().StringVarP(&pingConfig.RedisAddr, "addr", "a", "localhost:6379", "Redis server address")
+	pingCmd.Flags().StringVarP(&pingConfig.RedisPassword, "password", "p", "", "Redis password (prefer REDIS_PASSWORD or --password-file)")
+	pingCmd.Flags().StringVar(&pingPasswordFile, "password-file", "", "Read the Redis password from this file")
+	pingCmd.Flags().IntVarP(&pingConfig.RedisDB, "db", "d", 0, "Redis database number")
+	pingCmd.Flags().DurationVar(&pingTimeout, "timeout", 5*time.Second, "Give up if Redis hasn't answered within this time")
+	pingCmd.Flags().StringVar(&pingConfig.ClientName, "client-name", "redis-export/"+version, "Connection name shown in CLIENT LIST")
+	rootCmd.AddCommand(pingCmd)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_Ping(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db, config: Config{RedisAddr: "redis:6379"}}
+
+	mock.ExpectPing().SetVal("PONG")
+	_, err := exporter.ping(context.Background(), time.Second)
+	assert.NoError(t, err)
+
+	mock.ExpectPing().SetErr(errors.New("NOAUTH Authentication required."))
+	_, err = exporter.ping(context.Background(), time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redis:6379")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Ping_Unreachable(t *testing.T) {
+	// Reserve a port, then close it so nothing is listening there
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	exporter := NewExporter(Config{RedisAddr: addr, Workers: 1})
+	defer func() { _ = exporter.client.Close() }()
+
+	start := time.Now()
+	_, err = exporter.ping(context.Background(), 2*time.Second)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "the probe gives up within its timeout")
+}
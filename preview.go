@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// previewKeys is the number of scanned keys --preview samples.
+var previewKeys = 2000
+
+// previewSummary describes the keys sampled by --preview.
+type previewSummary struct {
+	Sampled   int            // keys read from the scan
+	Exported  int            // sampled keys that would be exported
+	Types     map[string]int // exported sample keys by type
+	Bytes     int64          // encoded value size of the exported sample keys
+	Elapsed   time.Duration  // time spent fetching the sample
+	TotalKeys int64          // keyspace size reported by INFO, 0 if unknown
+}
+
+// estimatedKeys extrapolates the number of keys the full export writes.
+func (s *previewSummary) estimatedKeys() int64 {
+	if s.Sampled == 0 {
+		return 0
+	}
+	return s.TotalKeys * int64(s.Exported) / int64(s.Sampled)
+}
+
+// estimatedBytes extrapolates the size of the exported values.
+func (s *previewSummary) estimatedBytes() int64 {
+	if s.Exported == 0 {
+		return 0
+	}
+	return s.Bytes * s.estimatedKeys() / int64(s.Exported)
+}
+
+// estimatedDuration extrapolates the export time. The sample is fetched one
+// key at a time, so this assumes the export speeds up linearly with workers,
+// which holds until Redis or the network is saturated.
+func (s *previewSummary) estimatedDuration(workers int) time.Duration {
+	if s.Sampled == 0 || workers < 1 {
+		return 0
+	}
+	perKey := s.Elapsed / time.Duration(s.Sampled)
+	return perKey * time.Duration(s.TotalKeys) / time.Duration(workers)
+}
+
+// preview fetches the first previewKeys keys of the scan, as the export
+// would, to estimate the size and duration of the full export.
+func (e *Exporter) preview(ctx context.Context) (*previewSummary, error) {
+	summary := &previewSummary{Types: make(map[string]int)}
+
+	total, err := e.getTotalKeyCount(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to get total key count, the preview can't estimate the full export")
+	}
+	summary.TotalKeys = total
+
+	// The scan is cancelled once the sample is taken
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	keysChan := make(chan keyTask, e.config.BatchSize)
+	go e.scanKeys(scanCtx, keysChan)
+
+	start := time.Now()
+	for task := range keysChan {
+		summary.Sampled++
+		entry, err := e.processKeySafely(ctx, task)
+		switch {
+		case errors.Is(err, errKeySkipped), errors.Is(err, errKeyVanished):
+		case err != nil:
+			logrus.WithField("key", task.key).Debug("Error previewing key: ", err)
+		default:
+			summary.Exported++
+			summary.Types[entry.Type]++
+			if entry.Value != nil {
+				summary.Bytes += int64(valueSize(entry.Value))
+			}
+		}
+		if summary.Sampled >= previewKeys {
+			break
+		}
+	}
+	summary.Elapsed = time.Since(start)
+	cancel()
+	for range keysChan {
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// printPreview writes the preview summary and its estimates to w.
+func printPreview(w io.Writer, s *previewSummary, workers int) {
+	fmt.Fprintf(w, "Preview of %d scanned keys (%d would be exported):\n", s.Sampled, s.Exported)
+
+	types := make([]string, 0, len(s.Types))
+	for keyType := range s.Types {
+		types = append(types, keyType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if s.Types[types[i]] != s.Types[types[j]] {
+			return s.Types[types[i]] > s.Types[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	for _, keyType := range types {
+		fmt.Fprintf(w, "  %-10s %d\n", keyType, s.Types[keyType])
+	}
+	fmt.Fprintf(w, "  %-10s %s\n", "values", formatBytes(s.Bytes))
+
+	if s.TotalKeys <= 0 {
+		fmt.Fprintln(w, "Keyspace size unknown, no estimate for the full export")
+		return
+	}
+	fmt.Fprintf(w, "Estimated full export: %d keys, %s of values, about %s with %d workers\n",
+		s.estimatedKeys(), formatBytes(s.estimatedBytes()), s.estimatedDuration(workers).Round(time.Second), workers)
+}
+
+// formatBytes renders n bytes with a binary unit, such as 1.5MB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", value, "KMGTP"[exp])
+}
+
+// confirmExport asks on out whether to continue with the full export and
+// reads the answer from in. Anything but y or yes declines.
+func confirmExport(in io.Reader, out io.Writer) (bool, error) {
+	fmt.Fprint(out, "Continue with the full export? [y/N] ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
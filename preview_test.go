@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_Preview(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	origKeys := previewKeys
+	previewKeys = 3
+	defer func() { previewKeys = origKeys }()
+
+	exporter := &Exporter{client: db, config: Config{BatchSize: 10, Types: []string{"string", "hash"}}}
+
+	mock.ExpectInfo("keyspace").SetVal("# Keyspace\r\ndb0:keys=3000,expires=0,avg_ttl=0\r\n")
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a", "b", "l", "never-fetched"}, 0)
+	mock.ExpectType("a").SetVal("string")
+	mock.ExpectGet("a").SetVal("12345678")
+	mock.ExpectTTL("a").SetVal(-1 * time.Second)
+	mock.ExpectType("b").SetVal("hash")
+	mock.ExpectHGetAll("b").SetVal(map[string]string{"f": "v"})
+	mock.ExpectTTL("b").SetVal(-1 * time.Second)
+	mock.ExpectType("l").SetVal("list")
+
+	summary, err := exporter.preview(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet(), "only the sampled keys are fetched")
+
+	assert.Equal(t, 3, summary.Sampled)
+	assert.Equal(t, 2, summary.Exported, "keys filtered out by --type aren't counted")
+	assert.Equal(t, map[string]int{"string": 1, "hash": 1}, summary.Types)
+	assert.Equal(t, int64(len(`"12345678"`)+len(`{"f":"v"}`)), summary.Bytes)
+	assert.Equal(t, int64(3000), summary.TotalKeys)
+	assert.Equal(t, int64(2000), summary.estimatedKeys())
+	assert.Equal(t, int64(19000), summary.estimatedBytes())
+
+	var out bytes.Buffer
+	printPreview(&out, summary, 4)
+	assert.Contains(t, out.String(), "Preview of 3 scanned keys (2 would be exported)")
+	assert.Contains(t, out.String(), "  hash       1\n")
+	assert.Contains(t, out.String(), "Estimated full export: 2000 keys, 18.6KB of values")
+	assert.Contains(t, out.String(), "with 4 workers")
+}
+
+func TestPreviewSummary_EstimatedDuration(t *testing.T) {
+	s := &previewSummary{Sampled: 100, Elapsed: time.Second, TotalKeys: 10000}
+	assert.Equal(t, 100*time.Second, s.estimatedDuration(1))
+	assert.Equal(t, 25*time.Second, s.estimatedDuration(4))
+	assert.Zero(t, (&previewSummary{}).estimatedDuration(4))
+}
+
+func TestPrintPreview_UnknownKeyspace(t *testing.T) {
+	var out bytes.Buffer
+	printPreview(&out, &previewSummary{Sampled: 1, Exported: 1, Types: map[string]int{"string": 1}}, 4)
+	assert.Contains(t, out.String(), "no estimate")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512B", formatBytes(512))
+	assert.Equal(t, "1.5KB", formatBytes(1536))
+	assert.Equal(t, "2.0GB", formatBytes(2<<30))
+}
+
+func TestConfirmExport(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		ok, err := confirmExport(strings.NewReader(answer), &out)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, fmt.Sprintf("answer %q", answer))
+		assert.Contains(t, out.String(), "[y/N]")
+	}
+}
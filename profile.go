@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// Profile kinds accepted by --profile.
+const (
+	profileCPU = "cpu"
+	profileMem = "mem"
+)
+
+func validateProfile(kind string) error {
+	switch kind {
+	case "", profileCPU, profileMem:
+		return nil
+	default:
+		return fmt.Errorf("invalid profile %q, expected %s or %s", kind, profileCPU, profileMem)
+	}
+}
+
+// profilePath returns the file a profile is written to when --profile-file
+// isn't set.
+func profilePath(kind string) string {
+	return "redis-export." + kind + ".pprof"
+}
+
+// startProfile starts a pprof profile of the given kind written to path. A
+// CPU profile samples from now until the returned function is called; a
+// memory profile records every allocation made in between and is written
+// when the function is called.
+func startProfile(kind, path string) (func() error, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create profile: %w", err)
+	}
+
+	switch kind {
+	case profileCPU:
+		if err := pprof.StartCPUProfile(file); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return file.Close()
+		}, nil
+	case profileMem:
+		return func() error {
+			runtime.GC() // bring the allocation statistics up to date
+			if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+				_ = file.Close()
+				return fmt.Errorf("failed to write memory profile: %w", err)
+			}
+			return file.Close()
+		}, nil
+	default:
+		_ = file.Close()
+		return nil, fmt.Errorf("invalid profile %q", kind)
+	}
+}
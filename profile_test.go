@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateProfile(t *testing.T) {
+	assert.NoError(t, validateProfile(""))
+	assert.NoError(t, validateProfile(profileCPU))
+	assert.NoError(t, validateProfile(profileMem))
+	assert.Error(t, validateProfile("block"))
+}
+
+func TestProfilePath(t *testing.T) {
+	assert.Equal(t, "redis-export.cpu.pprof", profilePath(profileCPU))
+}
+
+func TestStartProfile(t *testing.T) {
+	for _, kind := range []string{profileCPU, profileMem} {
+		t.Run(kind, func(t *testing.T) {
+			db, mock := redismock.NewClientMock()
+			defer func() { _ = db.Close() }()
+
+			keys := make([]string, 50)
+			for i := range keys {
+				keys[i] = fmt.Sprintf("key:%d", i)
+			}
+			mock.ExpectScan(0, "*", int64(100)).SetVal(keys, 0)
+			for _, key := range keys {
+				mock.ExpectType(key).SetVal("string")
+				mock.ExpectGet(key).SetVal("value")
+				mock.ExpectTTL(key).SetVal(-1 * time.Second)
+			}
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, profilePath(kind))
+			stop, err := startProfile(kind, path)
+			require.NoError(t, err)
+
+			exporter := &Exporter{client: db, config: Config{
+				OutputFile: filepath.Join(dir, "export.json"),
+				Workers:    1,
+				BatchSize:  100,
+			}}
+			require.NoError(t, exporter.Export(context.Background()))
+			require.NoError(t, stop())
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Positive(t, info.Size(), "profile should not be empty")
+		})
+	}
+}
+
+func TestStartProfile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.pprof")
+	_, err := startProfile("block", path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// isTerminal reports whether f is attached to a terminal.
+var isTerminal = func(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// useProgressBar reports whether --progress-bar can render. The bar is only
+// drawn for interactive runs: both stdout and stderr must be terminals and
+// the export must not be written to stdout.
+func useProgressBar(config Config) bool {
+	if !config.ProgressBar || config.Quiet || config.OutputFile == stdoutOutput {
+		return false
+	}
+	return isTerminal(os.Stdout) && isTerminal(os.Stderr)
+}
+
+// newProgressBar returns a bar drawn on stderr. A total of zero or less,
+// when the key count is unknown, shows a spinner instead.
+func newProgressBar(total int64) *progressbar.ProgressBar {
+	if total <= 0 {
+		total = -1
+	}
+	return progressbar.NewOptions64(total,
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionSetDescription("Exporting"),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetItsString("keys"),
+		progressbar.OptionSetElapsedTime(true),
+		progressbar.OptionSetPredictTime(true),
+		progressbar.OptionThrottle(100*time.Millisecond),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionOnCompletion(func() { _, _ = os.Stderr.WriteString("\n") }),
+	)
+}
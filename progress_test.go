@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stubTerminal(t *testing.T, terminal bool) {
+	previous := isTerminal
+	isTerminal = func(*os.File) bool { return terminal }
+	t.Cleanup(func() { isTerminal = previous })
+}
+
+func TestUseProgressBar_NonTTY(t *testing.T) {
+	stubTerminal(t, false)
+	assert.False(t, useProgressBar(Config{ProgressBar: true, OutputFile: "export.json"}))
+}
+
+func TestUseProgressBar_TTY(t *testing.T) {
+	stubTerminal(t, true)
+
+	assert.True(t, useProgressBar(Config{ProgressBar: true, OutputFile: "export.json"}))
+	assert.False(t, useProgressBar(Config{OutputFile: "export.json"}), "off unless requested")
+	assert.False(t, useProgressBar(Config{ProgressBar: true, OutputFile: stdoutOutput}), "off when exporting to stdout")
+	assert.False(t, useProgressBar(Config{ProgressBar: true, OutputFile: "export.json", Quiet: true}), "off with --quiet")
+}
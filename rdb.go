@@ -0,0 +1,1126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// RDB opcodes that precede a key, or stand in for one.
+const (
+	rdbOpSlotInfo     = 0xf4
+	rdbOpFunction2    = 0xf5
+	rdbOpModuleAux    = 0xf7
+	rdbOpIdle         = 0xf8
+	rdbOpFreq         = 0xf9
+	rdbOpAux          = 0xfa
+	rdbOpResizeDB     = 0xfb
+	rdbOpExpireTimeMS = 0xfc
+	rdbOpExpireTime   = 0xfd
+	rdbOpSelectDB     = 0xfe
+	rdbOpEOF          = 0xff
+)
+
+// RDB value types, from rdb.h.
+const (
+	rdbTypeString          = 0
+	rdbTypeList            = 1
+	rdbTypeSet             = 2
+	rdbTypeZset            = 3
+	rdbTypeHash            = 4
+	rdbTypeZset2           = 5
+	rdbTypeModule2         = 7
+	rdbTypeHashZipmap      = 9
+	rdbTypeListZiplist     = 10
+	rdbTypeSetIntset       = 11
+	rdbTypeZsetZiplist     = 12
+	rdbTypeHashZiplist     = 13
+	rdbTypeListQuicklist   = 14
+	rdbTypeStreamListpacks = 15
+	rdbTypeHashListpack    = 16
+	rdbTypeZsetListpack    = 17
+	rdbTypeListQuicklist2  = 18
+	rdbTypeStream2         = 19
+	rdbTypeSetListpack     = 20
+	rdbTypeStream3         = 21
+)
+
+// Module value opcodes, which let a module value be skipped without the
+// module that wrote it.
+const (
+	rdbModuleOpEOF    = 0
+	rdbModuleOpSint   = 1
+	rdbModuleOpUint   = 2
+	rdbModuleOpFloat  = 3
+	rdbModuleOpDouble = 4
+	rdbModuleOpString = 5
+)
+
+// maxRDBString bounds the strings read from an RDB file, matching Redis's
+// 512MB limit, so a corrupt length can't exhaust memory.
+const maxRDBString = 512 << 20
+
+// errRDBUnsupported is set on records whose value the parser skipped.
+var errRDBUnsupported = errors.New("not supported in RDB files")
+
+// rdbRecord is a key read from an RDB file.
+type rdbRecord struct {
+	db       int
+	key      string
+	keyType  string
+	value    interface{} // in the shapes getValueByType returns
+	expireAt time.Time   // zero for keys without an expiry
+	err      error       // errRDBUnsupported when the value was skipped
+}
+
+// rdbReader decodes the RDB format written by SAVE and BGSAVE.
+type rdbReader struct {
+	r *bufio.Reader
+}
+
+// parseRDB calls fn for each key in the RDB file read from r, stopping at
+// the first error. The trailing checksum is not verified.
+func parseRDB(r io.Reader, fn func(rdbRecord) error) error {
+	rd := &rdbReader{r: bufio.NewReaderSize(r, 64<<10)}
+
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(rd.r, header); err != nil || !bytes.HasPrefix(header, []byte("REDIS")) {
+		return errors.New("not an RDB file")
+	}
+	if _, err := strconv.Atoi(string(header[5:])); err != nil {
+		return fmt.Errorf("invalid RDB version %q", header[5:])
+	}
+
+	db := 0
+	var expireAt time.Time
+	for {
+		op, err := rd.r.ReadByte()
+		if err != nil {
+			return rdbReadError(err)
+		}
+
+		switch op {
+		case rdbOpEOF:
+			return nil
+		case rdbOpSelectDB:
+			var n uint64
+			n, err = rd.length()
+			db = int(n)
+		case rdbOpResizeDB:
+			err = rd.skipLengths(2)
+		case rdbOpSlotInfo:
+			err = rd.skipLengths(3)
+		case rdbOpExpireTimeMS:
+			var ms int64
+			ms, err = rd.uint64LE()
+			expireAt = time.UnixMilli(ms)
+		case rdbOpExpireTime:
+			var buf [4]byte
+			if _, err = io.ReadFull(rd.r, buf[:]); err == nil {
+				expireAt = time.Unix(int64(binary.LittleEndian.Uint32(buf[:])), 0)
+			}
+		case rdbOpAux:
+			if _, err = rd.string(); err == nil {
+				_, err = rd.string()
+			}
+		case rdbOpFreq:
+			_, err = rd.r.ReadByte()
+		case rdbOpIdle:
+			_, err = rd.length()
+		case rdbOpFunction2:
+			_, err = rd.string()
+		case rdbOpModuleAux:
+			err = rd.skipModuleAux()
+		default:
+			key, err := rd.string()
+			if err != nil {
+				return rdbReadError(err)
+			}
+			keyType, value, err := rd.value(op)
+			if err != nil && !errors.Is(err, errRDBUnsupported) {
+				return fmt.Errorf("failed to read key %s: %w", key, rdbReadError(err))
+			}
+			record := rdbRecord{db: db, key: key, keyType: keyType, value: value, expireAt: expireAt, err: err}
+			expireAt = time.Time{}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return rdbReadError(err)
+		}
+	}
+}
+
+// rdbReadError reports a file that ends early as truncated rather than as
+// a bare EOF.
+func rdbReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errors.New("RDB file is truncated")
+	}
+	return err
+}
+
+// rawLength reads a length. When encoded is true, the value is instead the
+// special encoding of the string that follows.
+func (rd *rdbReader) rawLength() (n uint64, encoded bool, err error) {
+	b, err := rd.r.ReadByte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		next, err := rd.r.ReadByte()
+		return uint64(b&0x3f)<<8 | uint64(next), false, err
+	case 2:
+		switch b {
+		case 0x80:
+			var buf [4]byte
+			_, err := io.ReadFull(rd.r, buf[:])
+			return uint64(binary.BigEndian.Uint32(buf[:])), false, err
+		case 0x81:
+			var buf [8]byte
+			_, err := io.ReadFull(rd.r, buf[:])
+			return binary.BigEndian.Uint64(buf[:]), false, err
+		}
+		return 0, false, fmt.Errorf("invalid length encoding 0x%02x", b)
+	default:
+		return uint64(b & 0x3f), true, nil
+	}
+}
+
+func (rd *rdbReader) length() (uint64, error) {
+	n, encoded, err := rd.rawLength()
+	if err == nil && encoded {
+		err = errors.New("unexpected string encoding in place of a length")
+	}
+	return n, err
+}
+
+func (rd *rdbReader) skipLengths(count int) error {
+	for i := 0; i < count; i++ {
+		if _, err := rd.length(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rd *rdbReader) uint64LE() (int64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(rd.r, buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:])), nil
+}
+
+func (rd *rdbReader) bytes(n uint64) ([]byte, error) {
+	if n > maxRDBString {
+		return nil, fmt.Errorf("string of %d bytes exceeds the %d byte limit", n, maxRDBString)
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(rd.r, buf)
+	return buf, err
+}
+
+// string reads a string, which may be stored as an integer or compressed
+// with LZF.
+func (rd *rdbReader) string() (string, error) {
+	n, encoded, err := rd.rawLength()
+	if err != nil {
+		return "", err
+	}
+	if !encoded {
+		buf, err := rd.bytes(n)
+		return string(buf), err
+	}
+
+	switch n {
+	case 0, 1, 2:
+		buf, err := rd.bytes(1 << n)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(intLE(buf), 10), nil
+	case 3:
+		compressed, err := rd.length()
+		if err != nil {
+			return "", err
+		}
+		size, err := rd.length()
+		if err != nil {
+			return "", err
+		}
+		if size > maxRDBString {
+			return "", fmt.Errorf("string of %d bytes exceeds the %d byte limit", size, maxRDBString)
+		}
+		data, err := rd.bytes(compressed)
+		if err != nil {
+			return "", err
+		}
+		out, err := lzfDecompress(data, int(size))
+		return string(out), err
+	default:
+		return "", fmt.Errorf("invalid string encoding %d", n)
+	}
+}
+
+// doubleString reads a sorted set score stored as text, the original zset
+// encoding.
+func (rd *rdbReader) doubleString() (float64, error) {
+	n, err := rd.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	buf, err := rd.bytes(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(buf), 64)
+}
+
+func (rd *rdbReader) strings(n uint64) ([]string, error) {
+	items := make([]string, 0, min(n, 1<<16))
+	for i := uint64(0); i < n; i++ {
+		item, err := rd.string()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// value reads a value of RDB type t, returning it in the shape the exporter
+// uses for its Redis type.
+func (rd *rdbReader) value(t byte) (string, interface{}, error) {
+	switch t {
+	case rdbTypeString:
+		s, err := rd.string()
+		return "string", s, err
+	case rdbTypeList, rdbTypeSet:
+		n, err := rd.length()
+		if err != nil {
+			return "", nil, err
+		}
+		items, err := rd.strings(n)
+		if t == rdbTypeList {
+			return "list", items, err
+		}
+		return "set", items, err
+	case rdbTypeZset, rdbTypeZset2:
+		n, err := rd.length()
+		if err != nil {
+			return "", nil, err
+		}
+		members := make([]redis.Z, 0, min(n, 1<<16))
+		for i := uint64(0); i < n; i++ {
+			member, err := rd.string()
+			if err != nil {
+				return "", nil, err
+			}
+			var score float64
+			if t == rdbTypeZset2 {
+				var bits int64
+				bits, err = rd.uint64LE()
+				score = math.Float64frombits(uint64(bits))
+			} else {
+				score, err = rd.doubleString()
+			}
+			if err != nil {
+				return "", nil, err
+			}
+			members = append(members, redis.Z{Member: member, Score: score})
+		}
+		return "zset", members, nil
+	case rdbTypeHash:
+		n, err := rd.length()
+		if err != nil {
+			return "", nil, err
+		}
+		items, err := rd.strings(2 * n)
+		if err != nil {
+			return "", nil, err
+		}
+		return "hash", pairsToHash(items), nil
+	case rdbTypeHashZipmap:
+		blob, err := rd.string()
+		if err != nil {
+			return "", nil, err
+		}
+		fields, err := zipmapEntries([]byte(blob))
+		return "hash", fields, err
+	case rdbTypeListZiplist, rdbTypeZsetZiplist, rdbTypeHashZiplist,
+		rdbTypeHashListpack, rdbTypeZsetListpack, rdbTypeSetListpack:
+		blob, err := rd.string()
+		if err != nil {
+			return "", nil, err
+		}
+		var items []string
+		switch t {
+		case rdbTypeListZiplist, rdbTypeZsetZiplist, rdbTypeHashZiplist:
+			items, err = ziplistEntries([]byte(blob))
+		default:
+			items, err = listpackEntries([]byte(blob))
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		switch t {
+		case rdbTypeListZiplist:
+			return "list", items, nil
+		case rdbTypeSetListpack:
+			return "set", items, nil
+		case rdbTypeZsetZiplist, rdbTypeZsetListpack:
+			members, err := pairsToZset(items)
+			return "zset", members, err
+		default:
+			return "hash", pairsToHash(items), nil
+		}
+	case rdbTypeSetIntset:
+		blob, err := rd.string()
+		if err != nil {
+			return "", nil, err
+		}
+		members, err := intsetEntries([]byte(blob))
+		return "set", members, err
+	case rdbTypeListQuicklist, rdbTypeListQuicklist2:
+		items, err := rd.quicklist(t == rdbTypeListQuicklist2)
+		return "list", items, err
+	case rdbTypeStreamListpacks, rdbTypeStream2, rdbTypeStream3:
+		if err := rd.skipStream(t); err != nil {
+			return "", nil, err
+		}
+		return "stream", nil, fmt.Errorf("streams are %w", errRDBUnsupported)
+	case rdbTypeModule2:
+		if _, err := rd.length(); err != nil {
+			return "", nil, err
+		}
+		if err := rd.skipModuleValue(); err != nil {
+			return "", nil, err
+		}
+		return "module", nil, fmt.Errorf("module values are %w", errRDBUnsupported)
+	default:
+		return "", nil, fmt.Errorf("unsupported RDB value type %d", t)
+	}
+}
+
+// quicklist reads a list stored as a sequence of ziplists or, from Redis 7,
+// of listpacks and plain elements.
+func (rd *rdbReader) quicklist(v2 bool) ([]string, error) {
+	n, err := rd.length()
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for i := uint64(0); i < n; i++ {
+		container := uint64(2)
+		if v2 {
+			if container, err = rd.length(); err != nil {
+				return nil, err
+			}
+		}
+		blob, err := rd.string()
+		if err != nil {
+			return nil, err
+		}
+		if container == 1 {
+			items = append(items, blob)
+			continue
+		}
+		var node []string
+		if v2 {
+			node, err = listpackEntries([]byte(blob))
+		} else {
+			node, err = ziplistEntries([]byte(blob))
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, node...)
+	}
+	return items, nil
+}
+
+// skipStream reads past a stream value, including its consumer groups.
+func (rd *rdbReader) skipStream(t byte) error {
+	nodes, err := rd.length()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < nodes; i++ {
+		if _, err := rd.string(); err != nil {
+			return err
+		}
+		if _, err := rd.string(); err != nil {
+			return err
+		}
+	}
+
+	// Length and last ID, then the first ID, max deleted ID and entries
+	// added from version 2
+	lengths := 3
+	if t >= rdbTypeStream2 {
+		lengths += 5
+	}
+	if err := rd.skipLengths(lengths); err != nil {
+		return err
+	}
+
+	groups, err := rd.length()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < groups; i++ {
+		if _, err := rd.string(); err != nil {
+			return err
+		}
+		lengths := 2
+		if t >= rdbTypeStream2 {
+			lengths++
+		}
+		if err := rd.skipLengths(lengths); err != nil {
+			return err
+		}
+
+		pending, err := rd.length()
+		if err != nil {
+			return err
+		}
+		for j := uint64(0); j < pending; j++ {
+			// Entry ID and delivery time, then the delivery count
+			if _, err := rd.bytes(16 + 8); err != nil {
+				return err
+			}
+			if _, err := rd.length(); err != nil {
+				return err
+			}
+		}
+
+		consumers, err := rd.length()
+		if err != nil {
+			return err
+		}
+		for j := uint64(0); j < consumers; j++ {
+			if _, err := rd.string(); err != nil {
+				return err
+			}
+			// Seen time, and active time from version 3
+			times := uint64(8)
+			if t >= rdbTypeStream3 {
+				times += 8
+			}
+			if _, err := rd.bytes(times); err != nil {
+				return err
+			}
+			owned, err := rd.length()
+			if err != nil {
+				return err
+			}
+			if _, err := rd.bytes(16 * owned); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipModuleValue reads past the opcodes of a module value up to its end
+// marker.
+func (rd *rdbReader) skipModuleValue() error {
+	for {
+		op, err := rd.length()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case rdbModuleOpEOF:
+			return nil
+		case rdbModuleOpSint, rdbModuleOpUint:
+			_, err = rd.length()
+		case rdbModuleOpFloat:
+			_, err = rd.bytes(4)
+		case rdbModuleOpDouble:
+			_, err = rd.bytes(8)
+		case rdbModuleOpString:
+			_, err = rd.string()
+		default:
+			return fmt.Errorf("invalid module opcode %d", op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// skipModuleAux reads past module data stored outside any key.
+func (rd *rdbReader) skipModuleAux() error {
+	// Module ID, then when the data is loaded as an opcode and its value
+	if err := rd.skipLengths(3); err != nil {
+		return err
+	}
+	return rd.skipModuleValue()
+}
+
+func pairsToHash(items []string) map[string]string {
+	fields := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		fields[items[i]] = items[i+1]
+	}
+	return fields
+}
+
+func pairsToZset(items []string) ([]redis.Z, error) {
+	members := make([]redis.Z, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		score, err := strconv.ParseFloat(items[i+1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid score %q", items[i+1])
+		}
+		members = append(members, redis.Z{Member: items[i], Score: score})
+	}
+	return members, nil
+}
+
+// intLE decodes a little-endian signed integer of 1 to 8 bytes.
+func intLE(buf []byte) int64 {
+	var v uint64
+	for i := len(buf) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(buf[i])
+	}
+	shift := 64 - 8*uint(len(buf))
+	return int64(v<<shift) >> shift
+}
+
+var errRDBCorrupt = errors.New("corrupt encoded value")
+
+// ziplistEntries decodes a ziplist, the compact list encoding of Redis
+// before 7.0.
+func ziplistEntries(zl []byte) ([]string, error) {
+	var entries []string
+	pos := 10 // total bytes, tail offset and entry count
+	for {
+		if pos >= len(zl) {
+			return nil, errRDBCorrupt
+		}
+		if zl[pos] == 0xff {
+			return entries, nil
+		}
+		// Length of the previous entry
+		if zl[pos] == 0xfe {
+			pos += 5
+		} else {
+			pos++
+		}
+		if pos >= len(zl) {
+			return nil, errRDBCorrupt
+		}
+
+		enc := zl[pos]
+		var n, size int
+		switch {
+		case enc>>6 == 0:
+			n, size = int(enc&0x3f), 1
+		case enc>>6 == 1:
+			if pos+1 >= len(zl) {
+				return nil, errRDBCorrupt
+			}
+			n, size = int(enc&0x3f)<<8|int(zl[pos+1]), 2
+		case enc == 0x80:
+			if pos+5 > len(zl) {
+				return nil, errRDBCorrupt
+			}
+			n, size = int(binary.BigEndian.Uint32(zl[pos+1:pos+5])), 5
+		case enc >= 0xf1 && enc <= 0xfd:
+			entries = append(entries, strconv.Itoa(int(enc&0x0f)-1))
+			pos++
+			continue
+		default:
+			width := ziplistIntWidth(enc)
+			if width == 0 || pos+1+width > len(zl) {
+				return nil, errRDBCorrupt
+			}
+			entries = append(entries, strconv.FormatInt(intLE(zl[pos+1:pos+1+width]), 10))
+			pos += 1 + width
+			continue
+		}
+		start := pos + size
+		if start+n > len(zl) {
+			return nil, errRDBCorrupt
+		}
+		entries = append(entries, string(zl[start:start+n]))
+		pos = start + n
+	}
+}
+
+// ziplistIntWidth returns the size of the integer that follows a ziplist
+// integer encoding, or 0 for an invalid encoding.
+func ziplistIntWidth(enc byte) int {
+	switch enc {
+	case 0xfe:
+		return 1
+	case 0xc0:
+		return 2
+	case 0xf0:
+		return 3
+	case 0xd0:
+		return 4
+	case 0xe0:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// listpackEntries decodes a listpack, the compact encoding that replaced
+// ziplists in Redis 7.0.
+func listpackEntries(lp []byte) ([]string, error) {
+	var entries []string
+	pos := 6 // total bytes and element count
+	for {
+		if pos >= len(lp) {
+			return nil, errRDBCorrupt
+		}
+		b := lp[pos]
+		if b == 0xff {
+			return entries, nil
+		}
+
+		// Where a string's bytes start, and how many there are
+		var start, n, size int
+		var integer int64
+		isString := true
+		switch {
+		case b&0x80 == 0:
+			integer, size, isString = int64(b), 1, false
+		case b&0xc0 == 0x80:
+			start, n = pos+1, int(b&0x3f)
+		case b&0xe0 == 0xc0:
+			if pos+1 >= len(lp) {
+				return nil, errRDBCorrupt
+			}
+			integer = int64(b&0x1f)<<8 | int64(lp[pos+1])
+			if integer >= 1<<12 {
+				integer -= 1 << 13
+			}
+			size, isString = 2, false
+		case b&0xf0 == 0xe0:
+			if pos+1 >= len(lp) {
+				return nil, errRDBCorrupt
+			}
+			start, n = pos+2, int(b&0x0f)<<8|int(lp[pos+1])
+		case b == 0xf0:
+			if pos+5 > len(lp) {
+				return nil, errRDBCorrupt
+			}
+			start, n = pos+5, int(binary.LittleEndian.Uint32(lp[pos+1:pos+5]))
+		default:
+			width := listpackIntWidth(b)
+			if width == 0 || pos+1+width > len(lp) {
+				return nil, errRDBCorrupt
+			}
+			integer, size, isString = intLE(lp[pos+1:pos+1+width]), 1+width, false
+		}
+
+		if isString {
+			if n < 0 || start+n > len(lp) {
+				return nil, errRDBCorrupt
+			}
+			entries = append(entries, string(lp[start:start+n]))
+			size = start + n - pos
+		} else {
+			entries = append(entries, strconv.FormatInt(integer, 10))
+		}
+		pos += size + listpackBacklen(size)
+	}
+}
+
+// listpackIntWidth returns the size of the integer that follows a listpack
+// integer encoding, or 0 for an invalid encoding.
+func listpackIntWidth(enc byte) int {
+	switch enc {
+	case 0xf1:
+		return 2
+	case 0xf2:
+		return 3
+	case 0xf3:
+		return 4
+	case 0xf4:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// listpackBacklen returns the size of the back length that follows a
+// listpack entry of size bytes.
+func listpackBacklen(size int) int {
+	switch {
+	case size <= 127:
+		return 1
+	case size < 16383:
+		return 2
+	case size < 2097151:
+		return 3
+	case size < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// intsetEntries decodes an intset, the encoding of small sets of integers.
+func intsetEntries(is []byte) ([]string, error) {
+	if len(is) < 8 {
+		return nil, errRDBCorrupt
+	}
+	width := int(binary.LittleEndian.Uint32(is[0:4]))
+	count := int(binary.LittleEndian.Uint32(is[4:8]))
+	if (width != 2 && width != 4 && width != 8) || len(is) < 8+width*count {
+		return nil, errRDBCorrupt
+	}
+	members := make([]string, count)
+	for i := range members {
+		offset := 8 + i*width
+		members[i] = strconv.FormatInt(intLE(is[offset:offset+width]), 10)
+	}
+	return members, nil
+}
+
+// zipmapEntries decodes a zipmap, the small hash encoding of Redis before
+// 2.6.
+func zipmapEntries(zm []byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	pos := 1 // element count
+	next := func() (int, bool) {
+		if pos >= len(zm) {
+			return 0, false
+		}
+		b := zm[pos]
+		switch b {
+		case 0xff:
+			return -1, true
+		case 0xfe:
+			if pos+5 > len(zm) {
+				return 0, false
+			}
+			n := int(binary.LittleEndian.Uint32(zm[pos+1 : pos+5]))
+			pos += 5
+			return n, true
+		default:
+			pos++
+			return int(b), true
+		}
+	}
+	for {
+		n, ok := next()
+		if !ok {
+			return nil, errRDBCorrupt
+		}
+		if n < 0 {
+			return fields, nil
+		}
+		if pos+n > len(zm) {
+			return nil, errRDBCorrupt
+		}
+		field := string(zm[pos : pos+n])
+		pos += n
+
+		n, ok = next()
+		if !ok || n < 0 || pos+1+n > len(zm) {
+			return nil, errRDBCorrupt
+		}
+		free := int(zm[pos])
+		pos++
+		fields[field] = string(zm[pos : pos+n])
+		pos += n + free
+	}
+}
+
+// lzfDecompress expands LZF data, used by RDB files to compress strings,
+// into size bytes.
+func lzfDecompress(in []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 1<<5 {
+			// A run of ctrl+1 literal bytes
+			n := ctrl + 1
+			if i+n > len(in) {
+				return nil, errRDBCorrupt
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		// A back reference to earlier output
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, errRDBCorrupt
+			}
+			n += int(in[i])
+			i++
+		}
+		n += 2
+		if i >= len(in) {
+			return nil, errRDBCorrupt
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, errRDBCorrupt
+		}
+		for j := 0; j < n; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != size {
+		return nil, errRDBCorrupt
+	}
+	return out, nil
+}
+
+// validateRDB rejects options that need a live server when exporting from
+// --rdb-file.
+func validateRDB(config Config) error {
+	if config.RDBFile == "" {
+		return nil
+	}
+	for flag, set := range map[string]bool{
+		"--watch":           config.Watch > 0,
+		"--keys-file":       config.KeysFile != "",
+		"--since-file":      config.SinceFile != "",
+		"--consistent":      config.Consistent,
+		"--namespace-stats": config.NamespaceStats,
+		"--preview":         config.Preview,
+		"--replica-read":    config.ReplicaRead,
+		"--warmup":          config.Warmup,
+		"--with-freq":       config.WithFreq,
+		"--with-refcount":   config.WithRefcount,
+		"--sample-members":  config.SampleMembers > 0,
+		"--filter-script":   config.FilterScript != "",
+		"--zset-min-score":  config.ZsetMinScore != "",
+		"--zset-max-score":  config.ZsetMaxScore != "",
+	} {
+		if set {
+			return fmt.Errorf("%s cannot be used with --rdb-file", flag)
+		}
+	}
+	return nil
+}
+
+// readRDB sends an entry to resultsChan for each key of the --db database in
+// the --rdb-file dump, applying the same filters as a scan. The file may be
+// compressed with gzip or zstd.
+func (e *Exporter) readRDB(ctx context.Context, resultsChan chan<- *RedisEntry) error {
+	file, err := os.Open(e.config.RDBFile)
+	if err != nil {
+		return fmt.Errorf("failed to open RDB file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	r, _, err := decompressReader(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	match := e.config.Match
+	if match == "" {
+		match = "*"
+	}
+
+	var seq int64
+	err = parseRDB(r, func(record rdbRecord) error {
+		if record.db != e.config.RedisDB || !globMatch(match, record.key) ||
+			!e.keyInScope(record.key) || !e.sampled() {
+			return nil
+		}
+
+		entry, err := e.rdbEntry(record)
+		if errors.Is(err, errKeySkipped) {
+			return nil
+		}
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"key": record.key,
+			}).Error("Error processing key: ", err)
+			e.recordFailure(record.key, err)
+			return nil
+		}
+
+		entry.seq = seq
+		seq++
+		select {
+		case resultsChan <- entry:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read RDB file %s: %w", e.config.RDBFile, err)
+	}
+	return nil
+}
+
+// rdbEntry builds the entry for a key read from an RDB file, as processKey
+// does for a key read from Redis.
+func (e *Exporter) rdbEntry(record rdbRecord) (*RedisEntry, error) {
+	if !e.typeAllowed(record.keyType) {
+		return nil, errKeySkipped
+	}
+	if record.err != nil {
+		return nil, &keyError{keyType: record.keyType, err: fmt.Errorf("failed to read value for key %s: %w", record.key, record.err)}
+	}
+
+	var ttl time.Duration
+	if !record.expireAt.IsZero() {
+		// Redis drops keys that expired before the dump is loaded
+		ttl = record.expireAt.Sub(timeNow())
+		if ttl <= 0 {
+			return nil, errKeySkipped
+		}
+	}
+	if e.config.PersistentOnly && ttl > 0 {
+		return nil, errKeySkipped
+	}
+
+	var value interface{}
+	var truncated bool
+	if e.valueWanted(record.keyType) {
+		value = record.value
+		switch v := value.(type) {
+		case []string:
+			if record.keyType == "list" && e.config.ListLimit > 0 && len(v) > e.config.ListLimit {
+				value, truncated = v[:e.config.ListLimit], true
+			}
+		case map[string]string:
+			if e.config.OrderedHashes {
+				value = sortedHashFields(v)
+			}
+		}
+
+		var cut bool
+		var err error
+		value, cut, err = e.finishValue(record.key, record.keyType, value)
+		if err != nil {
+			return nil, err
+		}
+		truncated = truncated || cut
+	}
+
+	entry := &RedisEntry{
+		Key:       e.rewriteKey(record.key),
+		Type:      record.keyType,
+		Value:     value,
+		Truncated: truncated,
+	}
+	if ttl > 0 {
+		e.setTTL(entry, ttl)
+	}
+	if e.config.WithSlot {
+		slot := keySlot(record.key)
+		entry.Slot = &slot
+	}
+	return entry, nil
+}
+
+// globMatch reports whether key matches a Redis glob pattern as SCAN MATCH
+// does: * and ? match any run of bytes and any one byte, [...] matches a
+// set or range of bytes, [^...] negates it, and \ escapes the next byte.
+func globMatch(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if globMatch(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			negate := len(pattern) > 0 && pattern[0] == '^'
+			if negate {
+				pattern = pattern[1:]
+			}
+			matched := false
+			for len(pattern) > 0 && pattern[0] != ']' {
+				switch {
+				case pattern[0] == '\\' && len(pattern) > 1:
+					matched = matched || pattern[1] == key[0]
+					pattern = pattern[2:]
+				case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+					lo, hi := pattern[0], pattern[2]
+					if lo > hi {
+						lo, hi = hi, lo
+					}
+					matched = matched || (key[0] >= lo && key[0] <= hi)
+					pattern = pattern[3:]
+				default:
+					matched = matched || pattern[0] == key[0]
+					pattern = pattern[1:]
+				}
+			}
+			if len(pattern) > 0 {
+				pattern = pattern[1:] // the closing ]
+			}
+			if matched == negate {
+				return false
+			}
+			key = key[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(key) == 0
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rdbBuilder writes RDB files for tests, in the encodings Redis uses.
+type rdbBuilder struct {
+	bytes.Buffer
+}
+
+func newRDBBuilder() *rdbBuilder {
+	b := &rdbBuilder{}
+	b.WriteString("REDIS0011")
+	b.WriteByte(rdbOpAux)
+	b.str("redis-ver")
+	b.str("7.2.4")
+	return b
+}
+
+func (b *rdbBuilder) length(n int) {
+	switch {
+	case n < 1<<6:
+		b.WriteByte(byte(n))
+	case n < 1<<14:
+		b.WriteByte(0x40 | byte(n>>8))
+		b.WriteByte(byte(n))
+	default:
+		b.WriteByte(0x80)
+		_ = binary.Write(b, binary.BigEndian, uint32(n))
+	}
+}
+
+func (b *rdbBuilder) str(s string) {
+	b.length(len(s))
+	b.WriteString(s)
+}
+
+func (b *rdbBuilder) key(t byte, key string) {
+	b.WriteByte(t)
+	b.str(key)
+}
+
+func (b *rdbBuilder) selectDB(db int) {
+	b.WriteByte(rdbOpSelectDB)
+	b.length(db)
+	b.WriteByte(rdbOpResizeDB)
+	b.length(1)
+	b.length(0)
+}
+
+func (b *rdbBuilder) expireAt(at time.Time) {
+	b.WriteByte(rdbOpExpireTimeMS)
+	_ = binary.Write(b, binary.LittleEndian, uint64(at.UnixMilli()))
+}
+
+func (b *rdbBuilder) end() []byte {
+	b.WriteByte(rdbOpEOF)
+	b.Write(make([]byte, 8)) // checksum, not verified
+	return b.Bytes()
+}
+
+// listpack encodes entries, which are strings or small integers.
+func listpack(entries ...interface{}) string {
+	var body bytes.Buffer
+	for _, entry := range entries {
+		var encoded []byte
+		switch v := entry.(type) {
+		case int:
+			encoded = []byte{byte(v)} // 7-bit unsigned integer
+		case string:
+			encoded = append([]byte{0x80 | byte(len(v))}, v...)
+		}
+		body.Write(encoded)
+		body.WriteByte(byte(len(encoded))) // back length
+	}
+	var lp bytes.Buffer
+	_ = binary.Write(&lp, binary.LittleEndian, uint32(6+body.Len()+1))
+	_ = binary.Write(&lp, binary.LittleEndian, uint16(len(entries)))
+	lp.Write(body.Bytes())
+	lp.WriteByte(0xff)
+	return lp.String()
+}
+
+// ziplist encodes entries, which are strings, 0 to 12 as immediate
+// integers, or int16 values.
+func ziplist(entries ...interface{}) string {
+	var body bytes.Buffer
+	prev := 0
+	for _, entry := range entries {
+		var encoded []byte
+		switch v := entry.(type) {
+		case int:
+			if v >= 0 && v <= 12 {
+				encoded = []byte{0xf1 + byte(v)}
+			} else {
+				encoded = []byte{0xc0, byte(v), byte(v >> 8)}
+			}
+		case string:
+			encoded = append([]byte{byte(len(v))}, v...)
+		}
+		body.WriteByte(byte(prev))
+		body.Write(encoded)
+		prev = 1 + len(encoded)
+	}
+	var zl bytes.Buffer
+	_ = binary.Write(&zl, binary.LittleEndian, uint32(10+body.Len()+1))
+	_ = binary.Write(&zl, binary.LittleEndian, uint32(0))
+	_ = binary.Write(&zl, binary.LittleEndian, uint16(len(entries)))
+	zl.Write(body.Bytes())
+	zl.WriteByte(0xff)
+	return zl.String()
+}
+
+// rdbFixture is an RDB file with a key in each of the common encodings.
+func rdbFixture(now time.Time) []byte {
+	b := newRDBBuilder()
+	b.selectDB(0)
+
+	b.key(rdbTypeString, "name")
+	b.str("alice")
+
+	b.key(rdbTypeString, "counter")
+	b.Write([]byte{0xc1, 0xe8, 0x03}) // 1000 as an int16
+
+	b.key(rdbTypeString, "blob")
+	// "abc", then a 9 byte back reference to it
+	lzf := []byte{0x02, 'a', 'b', 'c', 0xe0, 0x00, 0x02}
+	b.WriteByte(0xc3)
+	b.length(len(lzf))
+	b.length(12)
+	b.Write(lzf)
+
+	b.expireAt(now.Add(90 * time.Second))
+	b.key(rdbTypeString, "session")
+	b.str("token")
+
+	b.expireAt(now.Add(-time.Minute))
+	b.key(rdbTypeString, "stale")
+	b.str("expired")
+
+	b.key(rdbTypeListQuicklist2, "queue")
+	b.length(2)
+	b.length(2) // packed node
+	b.str(listpack("a", "b", 7))
+	b.length(1) // plain node
+	b.str("big")
+
+	b.key(rdbTypeListZiplist, "oldlist")
+	b.str(ziplist("x", 1, 500))
+
+	b.key(rdbTypeSetIntset, "ids")
+	var intset bytes.Buffer
+	_ = binary.Write(&intset, binary.LittleEndian, uint32(2))
+	_ = binary.Write(&intset, binary.LittleEndian, uint32(3))
+	for _, v := range []int16{1, 2, 300} {
+		_ = binary.Write(&intset, binary.LittleEndian, v)
+	}
+	b.str(intset.String())
+
+	b.key(rdbTypeSetListpack, "tags")
+	b.str(listpack("red", "blue"))
+
+	b.key(rdbTypeZset2, "scores")
+	b.length(2)
+	for _, member := range []struct {
+		name  string
+		score float64
+	}{{"p1", 1.5}, {"p2", 2}} {
+		b.str(member.name)
+		_ = binary.Write(b, binary.LittleEndian, math.Float64bits(member.score))
+	}
+
+	b.key(rdbTypeZsetListpack, "ranks")
+	b.str(listpack("m", 10))
+
+	b.key(rdbTypeHash, "user:1")
+	b.length(1)
+	b.str("email")
+	b.str("a@example.com")
+
+	b.key(rdbTypeHashListpack, "user:2")
+	b.str(listpack("name", "bob", "age", 42))
+
+	b.key(rdbTypeHashZiplist, "legacy")
+	b.str(ziplist("f", "v"))
+
+	// An empty stream without consumer groups
+	b.key(rdbTypeStreamListpacks, "events")
+	b.Write([]byte{0, 0, 0, 0, 0})
+
+	b.selectDB(1)
+	b.key(rdbTypeString, "other")
+	b.str("db1")
+
+	return b.end()
+}
+
+func TestParseRDB(t *testing.T) {
+	now := time.Date(2025, 8, 12, 10, 30, 0, 0, time.UTC)
+
+	records := make(map[string]rdbRecord)
+	var keys []string
+	require.NoError(t, parseRDB(bytes.NewReader(rdbFixture(now)), func(record rdbRecord) error {
+		records[record.key] = record
+		keys = append(keys, record.key)
+		return nil
+	}))
+
+	assert.Equal(t, []string{"name", "counter", "blob", "session", "stale", "queue", "oldlist", "ids", "tags",
+		"scores", "ranks", "user:1", "user:2", "legacy", "events", "other"}, keys)
+
+	assert.Equal(t, "alice", records["name"].value)
+	assert.Equal(t, "1000", records["counter"].value)
+	assert.Equal(t, "abcabcabcabc", records["blob"].value)
+	assert.Equal(t, now.Add(90*time.Second), records["session"].expireAt.UTC())
+	assert.True(t, records["name"].expireAt.IsZero())
+
+	assert.Equal(t, "list", records["queue"].keyType)
+	assert.Equal(t, []string{"a", "b", "7", "big"}, records["queue"].value)
+	assert.Equal(t, []string{"x", "1", "500"}, records["oldlist"].value)
+	assert.Equal(t, "set", records["ids"].keyType)
+	assert.Equal(t, []string{"1", "2", "300"}, records["ids"].value)
+	assert.Equal(t, []string{"red", "blue"}, records["tags"].value)
+
+	assert.Equal(t, "zset", records["scores"].keyType)
+	assert.Equal(t, []redis.Z{{Member: "p1", Score: 1.5}, {Member: "p2", Score: 2}}, records["scores"].value)
+	assert.Equal(t, []redis.Z{{Member: "m", Score: 10}}, records["ranks"].value)
+
+	assert.Equal(t, "hash", records["user:1"].keyType)
+	assert.Equal(t, map[string]string{"email": "a@example.com"}, records["user:1"].value)
+	assert.Equal(t, map[string]string{"name": "bob", "age": "42"}, records["user:2"].value)
+	assert.Equal(t, map[string]string{"f": "v"}, records["legacy"].value)
+
+	assert.Equal(t, "stream", records["events"].keyType)
+	assert.ErrorIs(t, records["events"].err, errRDBUnsupported)
+
+	assert.Equal(t, 1, records["other"].db)
+	assert.Equal(t, 0, records["name"].db)
+}
+
+func TestParseRDB_Invalid(t *testing.T) {
+	noop := func(rdbRecord) error { return nil }
+
+	assert.ErrorContains(t, parseRDB(bytes.NewReader([]byte("[{\"key\":\"a\"}]")), noop), "not an RDB file")
+
+	data := rdbFixture(time.Now())
+	assert.ErrorContains(t, parseRDB(bytes.NewReader(data[:len(data)/2]), noop), "truncated")
+}
+
+func TestExporter_Export_RDBFile(t *testing.T) {
+	previous := timeNow
+	now := time.Date(2025, 8, 12, 10, 30, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = previous })
+
+	dir := t.TempDir()
+	rdbFile := filepath.Join(dir, "dump.rdb")
+	require.NoError(t, os.WriteFile(rdbFile, rdbFixture(now), 0o644))
+
+	config := Config{
+		RDBFile:    rdbFile,
+		OutputFile: filepath.Join(dir, "export.json"),
+		ErrorsFile: filepath.Join(dir, "errors.json"),
+		Match:      "*",
+		Workers:    1,
+		BatchSize:  10,
+	}
+	exporter := NewExporter(config)
+	defer func() { _ = exporter.client.Close() }()
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	exported := make(map[string]RedisEntry)
+	for _, entry := range entries {
+		exported[entry.Key] = entry
+	}
+
+	assert.NotContains(t, exported, "stale", "keys that expired before the dump was read are dropped")
+	assert.NotContains(t, exported, "other", "keys of other databases are not exported")
+	assert.NotContains(t, exported, "events")
+	assert.Len(t, exported, 13)
+
+	assert.Equal(t, "string", exported["name"].Type)
+	assert.Equal(t, "alice", exported["name"].Value)
+	assert.Equal(t, int64(90), exported["session"].TTL)
+	assert.Equal(t, []interface{}{"a", "b", "7", "big"}, exported["queue"].Value)
+	assert.Equal(t, map[string]interface{}{"name": "bob", "age": "42"}, exported["user:2"].Value)
+	assert.Equal(t, "zset", exported["scores"].Type)
+
+	failed, err := os.ReadFile(config.ErrorsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(failed), "events")
+}
+
+func TestExporter_Export_RDBFile_Filters(t *testing.T) {
+	dir := t.TempDir()
+	rdbFile := filepath.Join(dir, "dump.rdb")
+	require.NoError(t, os.WriteFile(rdbFile, rdbFixture(time.Now()), 0o644))
+
+	config := Config{
+		RDBFile:    rdbFile,
+		OutputFile: filepath.Join(dir, "export.json"),
+		Match:      "user:*",
+		Types:      []string{"hash"},
+		RedisDB:    0,
+		Workers:    1,
+		BatchSize:  10,
+	}
+	exporter := NewExporter(config)
+	defer func() { _ = exporter.client.Close() }()
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "user:1", entries[0].Key)
+	assert.Equal(t, "user:2", entries[1].Key)
+}
+
+func TestValidateRDB(t *testing.T) {
+	assert.NoError(t, validateRDB(Config{Watch: time.Minute}))
+	assert.NoError(t, validateRDB(Config{RDBFile: "dump.rdb", Manifest: true}))
+	assert.ErrorContains(t, validateRDB(Config{RDBFile: "dump.rdb", Watch: time.Minute}), "--watch")
+	assert.ErrorContains(t, validateRDB(Config{RDBFile: "dump.rdb", WithFreq: true}), "--with-freq")
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"*", "anything", true},
+		{"user:*", "user:1", true},
+		{"user:*", "session:1", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"*:*:end", "a:b:end", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, globMatch(tt.pattern, tt.key), "%s against %s", tt.pattern, tt.key)
+	}
+}
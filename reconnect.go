@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// reconnectWait is the wait before the first retry after a lost connection.
+// It doubles with each retry, up to maxReconnectWait.
+var reconnectWait = 500 * time.Millisecond
+
+const maxReconnectWait = 10 * time.Second
+
+// isConnError reports whether err means the connection to Redis was lost or
+// the server is not serving yet, as during a failover, rather than that the
+// command itself failed. The client dials a new connection for the next
+// command, so these are worth retrying.
+func isConnError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if redis.HasErrorPrefix(err, "LOADING") {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// awaitReconnect backs off before the given retry (0-based) after a lost
+// connection. It returns false once --reconnect-retries is used up or ctx is
+// done, when the caller should give up.
+func (e *Exporter) awaitReconnect(ctx context.Context, attempt int, err error, fields logrus.Fields) bool {
+	if attempt >= e.config.Reconnects {
+		return false
+	}
+
+	wait := reconnectWait << attempt
+	if wait > maxReconnectWait || wait <= 0 {
+		wait = maxReconnectWait
+	}
+	e.reconnects.Add(1)
+	logrus.WithFields(fields).WithFields(logrus.Fields{
+		"attempt": attempt + 1,
+		"wait":    wait,
+	}).Warn("Lost connection to Redis, retrying: ", err)
+
+	select {
+	case <-time.After(wait):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
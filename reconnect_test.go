@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serverError is a reply error from Redis, such as LOADING.
+type serverError string
+
+func (e serverError) Error() string { return string(e) }
+func (e serverError) RedisError()   {}
+
+func fastReconnect(t *testing.T) {
+	t.Helper()
+	orig := reconnectWait
+	reconnectWait = time.Millisecond
+	t.Cleanup(func() { reconnectWait = orig })
+}
+
+func TestIsConnError(t *testing.T) {
+	assert.True(t, isConnError(io.EOF))
+	assert.True(t, isConnError(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	assert.True(t, isConnError(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}))
+	assert.True(t, isConnError(serverError("LOADING Redis is loading the dataset in memory")))
+
+	assert.False(t, isConnError(nil))
+	assert.False(t, isConnError(context.Canceled))
+	assert.False(t, isConnError(serverError("WRONGTYPE Operation against a key holding the wrong kind of value")))
+	assert.False(t, isConnError(errors.New("unsupported type")))
+}
+
+func TestExporter_Export_ResumesScanAfterConnectionDrop(t *testing.T) {
+	fastReconnect(t)
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Reconnects: 3,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a"}, 42)
+	mock.ExpectType("a").SetVal("string")
+	mock.ExpectGet("a").SetVal("1")
+	mock.ExpectTTL("a").SetVal(-1 * time.Second)
+	// The connection drops twice, and the scan resumes from cursor 42
+	mock.ExpectScan(42, "*", int64(10)).SetErr(io.EOF)
+	mock.ExpectScan(42, "*", int64(10)).SetErr(syscall.ECONNRESET)
+	mock.ExpectScan(42, "*", int64(10)).SetVal([]string{"b"}, 0)
+	mock.ExpectType("b").SetVal("string")
+	mock.ExpectGet("b").SetVal("2")
+	mock.ExpectTTL("b").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, int64(2), exporter.reconnects.Load())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].Key)
+	assert.Equal(t, "b", entries[1].Key)
+}
+
+func TestExporter_Export_FailsWhenScanCantReconnect(t *testing.T) {
+	fastReconnect(t)
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Reconnects: 1,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a"}, 42)
+	mock.ExpectType("a").SetVal("string")
+	mock.ExpectGet("a").SetVal("1")
+	mock.ExpectTTL("a").SetVal(-1 * time.Second)
+	mock.ExpectScan(42, "*", int64(10)).SetErr(io.EOF)
+	mock.ExpectScan(42, "*", int64(10)).SetErr(io.EOF)
+
+	err := exporter.Export(context.Background())
+	require.Error(t, err, "a scan cut short must not look like a complete export")
+	assert.ErrorIs(t, err, io.EOF)
+
+	// The keys read before the drop are still a valid partial file
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	assert.Len(t, entries, 1)
+}
+
+func TestExporter_ProcessKeyRetrying_Reconnects(t *testing.T) {
+	fastReconnect(t)
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db, config: Config{Reconnects: 2}}
+
+	mock.ExpectType("k").SetErr(io.EOF)
+	mock.ExpectType("k").SetVal("string")
+	mock.ExpectGet("k").SetVal("v")
+	mock.ExpectTTL("k").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKeyRetrying(context.Background(), keyTask{key: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, "v", entry.Value)
+	assert.Equal(t, int64(1), exporter.reconnects.Load())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
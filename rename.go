@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// parseCommandRenames parses orig=renamed pairs from --rename-command into a
+// map keyed by the lowercase original command name.
+func parseCommandRenames(values []string) (map[string]string, error) {
+	renames := make(map[string]string, len(values))
+	for _, value := range values {
+		orig, renamed, ok := strings.Cut(value, "=")
+		if !ok || orig == "" || renamed == "" {
+			return nil, fmt.Errorf("invalid command rename %q, expected orig=renamed", value)
+		}
+		renames[strings.ToLower(orig)] = renamed
+	}
+	return renames, nil
+}
+
+// renameHook sends commands under the names a server's rename-command
+// settings gave them. It rewrites the name of every command the client
+// sends, including in pipelines and the connection handshake, so no code
+// path issues a command by its original name.
+type renameHook map[string]string
+
+func (h renameHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h renameHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		defer h.rename(cmd)()
+		return next(ctx, cmd)
+	}
+}
+
+func (h renameHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		restores := make([]func(), len(cmds))
+		for i, cmd := range cmds {
+			restores[i] = h.rename(cmd)
+		}
+		defer func() {
+			for _, restore := range restores {
+				restore()
+			}
+		}()
+		return next(ctx, cmds)
+	}
+}
+
+// rename replaces the name of cmd in its arguments, returning a function
+// that puts the original name back. The name is only changed while the
+// command is sent, so a command processed again, such as a pipeline that is
+// run twice, is renamed from its original name rather than renamed twice.
+// Multi-word commands such as CONFIG GET are renamed by their first word,
+// as Redis does.
+func (h renameHook) rename(cmd redis.Cmder) func() {
+	args := cmd.Args()
+	if len(args) == 0 {
+		return func() {}
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return func() {}
+	}
+	renamed, ok := h[strings.ToLower(name)]
+	if !ok {
+		return func() {}
+	}
+	args[0] = renamed
+	return func() { args[0] = name }
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommandRenames(t *testing.T) {
+	renames, err := parseCommandRenames([]string{"SCAN=scan_9f2c", "dump=DUMP_x"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"scan": "scan_9f2c", "dump": "DUMP_x"}, renames)
+
+	for _, value := range []string{"scan", "=x", "scan="} {
+		_, err := parseCommandRenames([]string{value})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestExporter_ScanKeys_RenamedCommand(t *testing.T) {
+	client, writes := writeRecorder(t, func(cmd []string) string {
+		switch strings.ToLower(cmd[0]) {
+		case "scan_9f2c":
+			return "*2\r\n$1\r\n0\r\n*1\r\n$3\r\nkey\r\n"
+		case "scan":
+			return "-ERR unknown command 'SCAN'\r\n"
+		case "hello":
+			return "-ERR unknown command 'HELLO'\r\n"
+		default:
+			return "+OK\r\n"
+		}
+	})
+	client.AddHook(renameHook{"scan": "scan_9f2c"})
+
+	exporter := &Exporter{client: client, config: Config{BatchSize: 10}}
+
+	keysChan := make(chan keyTask, 10)
+	exporter.scanKeys(context.Background(), keysChan)
+	require.NoError(t, exporter.scanErr)
+
+	var keys []string
+	for task := range keysChan {
+		keys = append(keys, task.key)
+	}
+	assert.Equal(t, []string{"key"}, keys)
+
+	sent := writes()
+	require.NotEmpty(t, sent)
+	assert.Equal(t, []string{"scan_9f2c"}, sent[len(sent)-1], "SCAN is sent under its renamed verb")
+}
+
+func TestRenameHook_ProcessedTwice(t *testing.T) {
+	// Swapped names would flip back if a command were renamed twice
+	hook := renameHook{"get": "set", "set": "get"}
+	ctx := context.Background()
+
+	var sent []string
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		sent = append(sent, cmd.Name())
+		return nil
+	})
+	cmd := redis.NewStringCmd(ctx, "get", "k")
+	require.NoError(t, process(ctx, cmd))
+	require.NoError(t, process(ctx, cmd))
+	assert.Equal(t, []string{"set", "set"}, sent)
+	assert.Equal(t, "get", cmd.Name(), "the original name is restored")
+
+	sent = nil
+	pipeline := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			sent = append(sent, cmd.Name())
+		}
+		return nil
+	})
+	cmds := []redis.Cmder{redis.NewStringCmd(ctx, "get", "k"), redis.NewStatusCmd(ctx, "set", "k", "v")}
+	require.NoError(t, pipeline(ctx, cmds))
+	require.NoError(t, pipeline(ctx, cmds))
+	assert.Equal(t, []string{"set", "get", "set", "get"}, sent)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// exportErrorTrailer is the HTTP trailer that carries the error of an export
+// that failed after the response started streaming.
+const exportErrorTrailer = "X-Export-Error"
+
+// exportRequest is the JSON body of POST /export. Every field is optional.
+type exportRequest struct {
+	Match  string `json:"match"`
+	Format string `json:"format"`
+	DB     int    `json:"db"`
+}
+
+// exportServer serves on-demand exports over HTTP, streaming each export in
+// the response body.
+type exportServer struct {
+	config      Config
+	newExporter func(Config) *Exporter
+	token       string // bearer token every request must carry, if set
+}
+
+func (s *exportServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /export", s.handleExport)
+	if s.token == "" {
+		return mux
+	}
+	return s.requireToken(mux)
+}
+
+// requireToken rejects requests that don't carry the server's bearer token,
+// comparing in constant time so the token can't be guessed from timings.
+func (s *exportServer) requireToken(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopback reports whether a --listen address only accepts connections
+// from the local host. An empty host listens on every interface.
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *exportServer) handleExport(w http.ResponseWriter, r *http.Request) {
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var contentType string
+	switch req.Format {
+	case "", "json":
+		contentType = "application/json"
+	case "parquet":
+		contentType = "application/vnd.apache.parquet"
+	default:
+		http.Error(w, fmt.Sprintf("unsupported output format: %s", req.Format), http.StatusBadRequest)
+		return
+	}
+	if req.DB < 0 {
+		http.Error(w, "db must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	config := s.config
+	config.Match = req.Match
+	config.Format = req.Format
+	config.RedisDB = req.DB
+
+	exporter := s.newExporter(config)
+	defer func() { _ = exporter.client.Close() }()
+	exporter.output = w
+
+	ctx := r.Context()
+	log := logrus.WithFields(logrus.Fields{
+		"remote_addr": r.RemoteAddr,
+		"match":       req.Match,
+		"format":      req.Format,
+		"db":          req.DB,
+	})
+
+	if err := exporter.client.Ping(ctx).Err(); err != nil {
+		log.WithError(err).Error("Failed to connect to Redis")
+		http.Error(w, "failed to connect to Redis: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	// The status is sent before the export starts, so later failures are
+	// reported in a trailer.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Trailer", exportErrorTrailer)
+	w.WriteHeader(http.StatusOK)
+
+	// The request context is cancelled when the client disconnects, which
+	// stops the export.
+	if err := exporter.Export(ctx); err != nil {
+		log.WithError(err).Error("Export request failed")
+		w.Header().Set(exportErrorTrailer, err.Error())
+	}
+}
+
+var (
+	serveConfig       Config
+	serveListen       string
+	servePasswordFile string
+	serveToken        string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve exports over HTTP",
+	Long:  "Run an HTTP server where POST /export streams an export of the configured Redis server in the response body.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		serveConfig.RedisPassword, err = resolvePassword(serveConfig.RedisPassword, servePasswordFile)
+		if err != nil {
+			return err
+		}
+
+		if env, ok := os.LookupEnv("REDIS_EXPORT_TOKEN"); ok && env != "" {
+			serveToken = env
+		}
+		if serveToken == "" && !isLoopback(serveListen) {
+			logrus.WithField("listen", serveListen).Warn("Serving exports without --token on a non-loopback address, anyone who can reach it can dump the database")
+		}
+
+		server := &exportServer{config: serveConfig, newExporter: NewExporter, token: serveToken}
+		httpServer := &http.Server{
+			Addr:              serveListen,
+			Handler:           server.handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		serveErr := make(chan error, 1)
+		go func() { serveErr <- httpServer.ListenAndServe() }()
+
+		logrus.WithFields(logrus.Fields{
+			"listen":     serveListen,
+			"redis_addr": serveConfig.RedisAddr,
+		}).Info("Serving exports")
+
+		select {
+		case err := <-serveErr:
+			return err
+		case <-ctx.Done():
+		}
+
+		logrus.Info("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return httpServer.Shutdown(shutdownCtx)
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "localhost:8080", "Address to listen on; a non-loopback address exposes the Redis data to anyone who can reach it, so set --token")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Bearer token required on every request (prefer REDIS_EXPORT_TOKEN)")
+	serveCmd.Flags().StringVarP(&serveConfig.RedisAddr, "addr", "a", "localhost:6379", "Redis server address")
+	serveCmd.Flags().StringVarP(&serveConfig.RedisPassword, "password", "p", "", "Redis password (prefer REDIS_PASSWORD or --password-file)")
+	serveCmd.Flags().StringVar(&servePasswordFile, "password-file", "", "Read the Redis password from this file")
+	serveCmd.Flags().IntVarP(&serveConfig.Workers, "workers", "w", runtime.NumCPU()*2, "Number of worker goroutines per export")
+	serveCmd.Flags().IntVar(&serveConfig.PoolSize, "pool-size", 0, "Maximum number of Redis connections per export (default twice the workers)")
+	serveCmd.Flags().IntVarP(&serveConfig.BatchSize, "batch", "b", 1000, "Batch size for key scanning")
+	serveCmd.Flags().DurationVar(&serveConfig.StatsInterval, "stats-interval", defaultStatsInterval, "How often to log the progress of each export, 0 to disable")
+	serveCmd.Flags().IntVar(&serveConfig.WriteBuffer, "write-buffer", 64*1024, "Response buffer size in bytes (0 to disable buffering)")
+	serveCmd.Flags().StringVar(&serveConfig.ClientName, "client-name", "redis-export/"+version, "Connection name shown in CLIENT LIST")
+	rootCmd.AddCommand(serveCmd)
+}
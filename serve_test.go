@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockExportServer returns an exportServer whose exports use db, and
+// records the config of the last export.
+func newMockExportServer(db *redis.Client, last *Config) *exportServer {
+	return &exportServer{
+		config: Config{Workers: 1, BatchSize: 10, Quiet: true},
+		newExporter: func(config Config) *Exporter {
+			*last = config
+			return &Exporter{client: db, config: config}
+		},
+	}
+}
+
+func TestExportServer_Export(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	var last Config
+	server := httptest.NewServer(newMockExportServer(db, &last).handler())
+	defer server.Close()
+
+	mock.ExpectPing().SetVal("PONG")
+	mock.ExpectScan(0, "user:*", int64(10)).SetVal([]string{"user:1"}, 0)
+	mock.ExpectType("user:1").SetVal("string")
+	mock.ExpectGet("user:1").SetVal("alice")
+	mock.ExpectTTL("user:1").SetVal(-1 * time.Second)
+
+	resp, err := http.Post(server.URL+"/export", "application/json", strings.NewReader(`{"match":"user:*","db":3}`))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(body, &entries))
+	assert.Equal(t, []RedisEntry{{Key: "user:1", Type: "string", Value: "alice"}}, entries)
+	assert.Empty(t, resp.Trailer.Get(exportErrorTrailer))
+
+	assert.Equal(t, 3, last.RedisDB)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportServer_BadRequests(t *testing.T) {
+	db, _ := redismock.NewClientMock()
+
+	var last Config
+	server := httptest.NewServer(newMockExportServer(db, &last).handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/export")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	for _, body := range []string{`{"format":"sqlite"}`, `{"db":-1}`, `not json`} {
+		resp, err := http.Post(server.URL+"/export", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+}
+
+func TestExportServer_ClientDisconnect(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	var last Config
+	server := newMockExportServer(db, &last)
+
+	mock.ExpectPing().SetVal("PONG")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(`{}`)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		server.handleExport(rec, req)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("export did not stop after the client disconnected")
+	}
+	assert.Contains(t, rec.Header().Get(exportErrorTrailer), "context canceled")
+}
+
+func TestExportServer_Token(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	var last Config
+	exportServer := newMockExportServer(db, &last)
+	exportServer.token = "s3cret"
+	server := httptest.NewServer(exportServer.handler())
+	defer server.Close()
+
+	for _, auth := range []string{"", "Bearer wrong", "s3cret", "Bearer s3cret2"} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/export", nil)
+		require.NoError(t, err)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, auth)
+	}
+
+	mock.ExpectPing().SetVal("PONG")
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{}, 0)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/export", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsLoopback(t *testing.T) {
+	for _, listen := range []string{"localhost:8080", "127.0.0.1:8080", "[::1]:8080"} {
+		assert.True(t, isLoopback(listen), listen)
+	}
+	for _, listen := range []string{":8080", "0.0.0.0:8080", "10.0.0.5:8080", "example.com:8080"} {
+		assert.False(t, isLoopback(listen), listen)
+	}
+}
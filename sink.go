@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+// uploadFunc uploads everything read from r to a cloud storage object.
+type uploadFunc func(ctx context.Context, r io.Reader) error
+
+// newGCSUpload returns an uploadFunc writing to a Google Cloud Storage
+// object. Credentials come from Application Default Credentials
+// (GOOGLE_APPLICATION_CREDENTIALS, gcloud, or the metadata server).
+var newGCSUpload = func(ctx context.Context, bucket, object string) (uploadFunc, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	return func(ctx context.Context, r io.Reader) error {
+		defer func() { _ = client.Close() }()
+
+		// Cancelling the context aborts the upload rather than committing a
+		// partial object.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		w := client.Bucket(bucket).Object(object).NewWriter(ctx)
+		if _, err := io.Copy(w, r); err != nil {
+			return err
+		}
+		return w.Close()
+	}, nil
+}
+
+// newAzureUpload returns an uploadFunc writing to an Azure Blob Storage
+// block blob. See newAzureClient for credentials.
+var newAzureUpload = func(ctx context.Context, container, blob string) (uploadFunc, error) {
+	client, err := newAzureClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, r io.Reader) error {
+		_, err := client.UploadStream(ctx, container, blob, r, nil)
+		return err
+	}, nil
+}
+
+// newAzureClient creates a Blob Storage client from
+// AZURE_STORAGE_CONNECTION_STRING if set, otherwise for the account in
+// AZURE_STORAGE_ACCOUNT using DefaultAzureCredential (environment, workload
+// or managed identity, Azure CLI).
+func newAzureClient() (*azblob.Client, error) {
+	if conn := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); conn != "" {
+		client, err := azblob.NewClientFromConnectionString(conn, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+		}
+		return client, nil
+	}
+
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	if account == "" {
+		return nil, errors.New("azure blob output needs AZURE_STORAGE_CONNECTION_STRING or AZURE_STORAGE_ACCOUNT")
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Azure credentials: %w", err)
+	}
+	client, err := azblob.NewClient(fmt.Sprintf("https://%s.blob.core.windows.net/", account), cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+	}
+	return client, nil
+}
+
+// uploadWriter streams everything written to it into an upload running in
+// the background. Close waits for the upload and returns its error; Abort
+// fails it instead, so a failed export isn't committed under its name.
+type uploadWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+	once sync.Once
+	err  error
+}
+
+func newUploadWriter(ctx context.Context, upload uploadFunc) *uploadWriter {
+	pr, pw := io.Pipe()
+	u := &uploadWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := upload(ctx, pr)
+		// Unblock writers if the upload stopped reading early
+		_ = pr.CloseWithError(err)
+		u.done <- err
+	}()
+	return u
+}
+
+func (u *uploadWriter) Write(p []byte) (int, error) {
+	return u.pw.Write(p)
+}
+
+func (u *uploadWriter) Close() error {
+	u.once.Do(func() {
+		_ = u.pw.Close()
+		u.err = <-u.done
+	})
+	return u.err
+}
+
+// Abort fails the upload with err rather than completing it, and waits for
+// it to stop. The uploads only commit an object once its reader reaches
+// the end, so nothing written so far is kept.
+func (u *uploadWriter) Abort(err error) {
+	u.once.Do(func() {
+		_ = u.pw.CloseWithError(err)
+		u.err = <-u.done
+	})
+}
+
+// errExportFailed aborts the upload of an export that returned early.
+var errExportFailed = errors.New("export failed")
+
+// nopWriteCloser leaves the underlying writer open on Close, for stdout and
+// writers supplied by the caller.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// openOutput opens the export destination: the writer set by the caller (as
+// serve does), a cloud storage object when --gcs-bucket or --azure-container
+// is set, stdout for "-", otherwise the output file.
+func (e *Exporter) openOutput(ctx context.Context) (io.WriteCloser, error) {
+	switch {
+	case e.output != nil:
+		return nopWriteCloser{e.output}, nil
+	case e.config.GCSBucket != "":
+		upload, err := newGCSUpload(ctx, e.config.GCSBucket, e.config.GCSObject)
+		if err != nil {
+			return nil, err
+		}
+		return newUploadWriter(ctx, upload), nil
+	case e.config.AzureContainer != "":
+		upload, err := newAzureUpload(ctx, e.config.AzureContainer, e.config.AzureBlob)
+		if err != nil {
+			return nil, err
+		}
+		return newUploadWriter(ctx, upload), nil
+	case e.config.OutputFile == stdoutOutput:
+		return nopWriteCloser{os.Stdout}, nil
+	default:
+		file, err := createFile(e.config.OutputFile, e.config.FileMode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+		return file, nil
+	}
+}
+
+// defaultFileMode is the mode of files created by an export when --file-mode
+// isn't set. Exports often hold secrets, so only the owner can read them.
+const defaultFileMode os.FileMode = 0o600
+
+// createFile creates or truncates the file at path with the given mode, or
+// defaultFileMode if mode is zero. The mode is applied regardless of the
+// umask and also to a file that already existed.
+func createFile(path string, mode os.FileMode) (*os.File, error) {
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(mode); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeFile writes data to the file at path, created as by createFile.
+func writeFile(path string, data []byte, mode os.FileMode) error {
+	file, err := createFile(path, mode)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// parseFileMode parses an octal --file-mode value such as 0600.
+func parseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || mode > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q, expected octal permissions such as 0600", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// outputName describes the export destination for logs.
+func (e *Exporter) outputName() string {
+	switch {
+	case e.config.GCSBucket != "":
+		return "gs://" + e.config.GCSBucket + "/" + e.config.GCSObject
+	case e.config.AzureContainer != "":
+		return "azure://" + e.config.AzureContainer + "/" + e.config.AzureBlob
+	default:
+		return e.config.OutputFile
+	}
+}
+
+// validateSink checks the cloud storage flags: each sink needs both its
+// flags, only one sink can be used, and SQLite output must go to a file.
+func validateSink(config Config) error {
+	if (config.GCSBucket == "") != (config.GCSObject == "") {
+		return errors.New("--gcs-bucket and --gcs-object must be used together")
+	}
+	if (config.AzureContainer == "") != (config.AzureBlob == "") {
+		return errors.New("--azure-container and --azure-blob must be used together")
+	}
+	if config.GCSBucket != "" && config.AzureContainer != "" {
+		return errors.New("--gcs-bucket and --azure-container cannot be used together")
+	}
+	if config.Format == "sqlite" && (config.GCSBucket != "" || config.AzureContainer != "") {
+		return errors.New("sqlite output can only be written to a local file")
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage records uploads made through an uploadFunc.
+type fakeStorage struct {
+	container string
+	name      string
+	data      bytes.Buffer
+	committed bool
+}
+
+func (f *fakeStorage) open(_ context.Context, container, name string) (uploadFunc, error) {
+	f.container, f.name = container, name
+	return func(_ context.Context, r io.Reader) error {
+		if _, err := io.Copy(&f.data, r); err != nil {
+			return err
+		}
+		f.committed = true
+		return nil
+	}, nil
+}
+
+// exportSinkFixture runs an export of two keys with config, returning what
+// the same export writes to a local file for comparison.
+func exportSinkFixture(t *testing.T, config Config) []byte {
+	t.Helper()
+
+	run := func(config Config) {
+		db, mock := redismock.NewClientMock()
+		defer func() { _ = db.Close() }()
+
+		mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"s", "l"}, 0)
+		mock.ExpectType("s").SetVal("string")
+		mock.ExpectGet("s").SetVal("hello")
+		mock.ExpectTTL("s").SetVal(60 * time.Second)
+		mock.ExpectType("l").SetVal("list")
+		mock.ExpectLRange("l", 0, -1).SetVal([]string{"a", "b"})
+		mock.ExpectTTL("l").SetVal(-1 * time.Second)
+
+		exporter := &Exporter{client: db, config: config}
+		require.NoError(t, exporter.Export(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	}
+
+	config.Workers = 1
+	config.BatchSize = 10
+	run(config)
+
+	local := Config{OutputFile: filepath.Join(t.TempDir(), "export.json"), Workers: 1, BatchSize: 10}
+	run(local)
+	data, err := os.ReadFile(local.OutputFile)
+	require.NoError(t, err)
+	return data
+}
+
+func TestExporter_Export_GCS(t *testing.T) {
+	fake := &fakeStorage{}
+	orig := newGCSUpload
+	newGCSUpload = fake.open
+	defer func() { newGCSUpload = orig }()
+
+	expected := exportSinkFixture(t, Config{GCSBucket: "backups", GCSObject: "redis/export.json"})
+
+	assert.Equal(t, "backups", fake.container)
+	assert.Equal(t, "redis/export.json", fake.name)
+	assert.Equal(t, string(expected), fake.data.String())
+}
+
+func TestExporter_Export_GCSScanError(t *testing.T) {
+	fake := &fakeStorage{}
+	orig := newGCSUpload
+	newGCSUpload = fake.open
+	defer func() { newGCSUpload = orig }()
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"s"}, 7)
+	mock.ExpectType("s").SetVal("string")
+	mock.ExpectGet("s").SetVal("hello")
+	mock.ExpectTTL("s").SetVal(-1 * time.Second)
+	mock.ExpectScan(7, "*", int64(10)).SetErr(errors.New("READONLY You can't write against a read only replica"))
+
+	config := Config{GCSBucket: "backups", GCSObject: "export.json", Workers: 1, BatchSize: 10}
+	exporter := &Exporter{client: db, config: config}
+	assert.ErrorContains(t, exporter.Export(context.Background()), "export is incomplete")
+	assert.False(t, fake.committed, "a failed export isn't uploaded")
+}
+
+func TestExporter_Export_Azure(t *testing.T) {
+	fake := &fakeStorage{}
+	orig := newAzureUpload
+	newAzureUpload = fake.open
+	defer func() { newAzureUpload = orig }()
+
+	expected := exportSinkFixture(t, Config{AzureContainer: "backups", AzureBlob: "export.json"})
+
+	assert.Equal(t, "backups", fake.container)
+	assert.Equal(t, "export.json", fake.name)
+	assert.Equal(t, string(expected), fake.data.String())
+}
+
+func TestUploadWriter_UploadError(t *testing.T) {
+	w := newUploadWriter(context.Background(), func(context.Context, io.Reader) error {
+		return assert.AnError
+	})
+
+	// Writes fail once the upload has given up instead of blocking
+	_, err := w.Write([]byte("data"))
+	assert.Error(t, err)
+	assert.ErrorIs(t, w.Close(), assert.AnError)
+	assert.ErrorIs(t, w.Close(), assert.AnError, "Close is idempotent")
+}
+
+func TestValidateSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"local file", Config{}, false},
+		{"gcs", Config{GCSBucket: "b", GCSObject: "o"}, false},
+		{"azure", Config{AzureContainer: "c", AzureBlob: "b"}, false},
+		{"gcs without object", Config{GCSBucket: "b"}, true},
+		{"azure without container", Config{AzureBlob: "b"}, true},
+		{"both sinks", Config{GCSBucket: "b", GCSObject: "o", AzureContainer: "c", AzureBlob: "b"}, true},
+		{"sqlite to gcs", Config{Format: "sqlite", GCSBucket: "b", GCSObject: "o"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSink(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateFile_Mode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "default.json")
+	file, err := createFile(path, 0)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, defaultFileMode, info.Mode().Perm())
+
+	// An existing, more permissive file is tightened
+	path = filepath.Join(dir, "existing.json")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+	file, err = createFile(path, 0o640)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	assert.Zero(t, info.Size(), "the file is truncated")
+}
+
+func TestExporter_Export_FileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	dir := t.TempDir()
+	config := Config{
+		OutputFile: filepath.Join(dir, "export.json"),
+		ErrorsFile: filepath.Join(dir, "errors.json"),
+		Workers:    1,
+		BatchSize:  10,
+		FileMode:   0o640,
+		Manifest:   true,
+		StatsJSON:  filepath.Join(dir, "stats.json"),
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"k"}, 0)
+	mock.ExpectType("k").SetVal("string")
+	mock.ExpectGet("k").SetVal("v")
+	mock.ExpectTTL("k").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	for _, path := range []string{config.OutputFile, config.ErrorsFile, manifestPath(config.OutputFile), config.StatsJSON} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm(), path)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	mode, err := parseFileMode("0600")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), mode)
+
+	mode, err = parseFileMode("640")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), mode)
+
+	for _, value := range []string{"", "0", "0800", "rw-------", "01777"} {
+		_, err := parseFileMode(value)
+		assert.Error(t, err, value)
+	}
+}
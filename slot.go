@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// clusterSlots is the number of hash slots in a Redis cluster.
+const clusterSlots = 16384
+
+// crc16 computes the CRC16-XMODEM checksum Redis cluster uses for key
+// hashing.
+func crc16(data string) uint16 {
+	var crc uint16
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// keySlot returns the cluster hash slot for key. If the key contains a
+// non-empty hash tag ({...}), only the tag is hashed.
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// loadSlotNodes fetches CLUSTER SLOTS and records the primary node address
+// owning each slot. On servers without cluster support node addresses are
+// left out of the export with a warning.
+func (e *Exporter) loadSlotNodes(ctx context.Context) {
+	slots, err := e.client.ClusterSlots(ctx).Result()
+	if err != nil {
+		logrus.WithError(err).Warn("Could not read cluster slots, exporting slots without node addresses")
+		return
+	}
+
+	e.slotNodes = make([]string, clusterSlots)
+	for _, slot := range slots {
+		if len(slot.Nodes) == 0 {
+			continue
+		}
+		for i := slot.Start; i <= slot.End && i < clusterSlots; i++ {
+			e.slotNodes[i] = slot.Nodes[0].Addr
+		}
+	}
+}
+
+// globChars are the characters with a special meaning in a SCAN MATCH pattern.
+const globChars = `*?[\`
+
+// matchHashTag returns the hash tag shared by every key matching pattern, if
+// the pattern pins one: a literal prefix followed by a literal, non-empty
+// {tag}. Only the tag of such keys is hashed, so they all live in one slot.
+func matchHashTag(pattern string) (string, bool) {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 || strings.ContainsAny(pattern[:start], globChars) {
+		return "", false
+	}
+	end := strings.IndexByte(pattern[start+1:], '}')
+	if end <= 0 {
+		return "", false
+	}
+	tag := pattern[start+1 : start+1+end]
+	if strings.ContainsAny(tag, globChars) {
+		return "", false
+	}
+	return tag, true
+}
+
+// slotOwner returns the address of the primary serving slot, or "" if no
+// node does.
+func slotOwner(slots []redis.ClusterSlot, slot int) string {
+	for _, s := range slots {
+		if slot >= s.Start && slot <= s.End && len(s.Nodes) > 0 {
+			return s.Nodes[0].Addr
+		}
+	}
+	return ""
+}
+
+// newNodeClient opens a client to a single cluster node, replaced in tests.
+var newNodeClient = redis.NewClient
+
+// routeHashTag points the client at the cluster node owning the slot of the
+// --match hash tag, so a per-tenant export such as --match '{tenant42}:*'
+// scans the one node holding its keys, whichever node --addr names. Other
+// patterns and servers without cluster support scan --addr as before.
+func (e *Exporter) routeHashTag(ctx context.Context) {
+	tag, ok := matchHashTag(e.config.Match)
+	if !ok {
+		return
+	}
+	slots, err := e.client.ClusterSlots(ctx).Result()
+	if err != nil {
+		logrus.WithError(err).Debug("Could not read cluster slots, scanning the configured node")
+		return
+	}
+
+	slot := int(crc16(tag) % clusterSlots)
+	addr := slotOwner(slots, slot)
+	if addr == "" || addr == e.config.RedisAddr {
+		return
+	}
+
+	opts := newRedisOptions(e.config)
+	opts.Addr = addr
+	client := newNodeClient(opts)
+	if e.config.OTelEndpoint != "" {
+		if err := redisotel.InstrumentTracing(client); err != nil {
+			logrus.WithError(err).Warn("Failed to instrument the cluster node client")
+		}
+	}
+	_ = e.client.Close()
+	e.client = client
+
+	logrus.WithFields(logrus.Fields{
+		"tag":  tag,
+		"slot": slot,
+		"node": addr,
+	}).Info("Match pattern pins a hash tag, scanning the node that owns its slot")
+}
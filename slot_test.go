@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRC16(t *testing.T) {
+	assert.Equal(t, uint16(0x31C3), crc16("123456789"))
+}
+
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		slot int
+	}{
+		{"123456789", 12739},
+		{"foo", 12182},
+		{"{}foo", 9500},
+		{"foo{}", 5542},
+		{"foo{}{bar}", 8363},
+		{"{user1000}.following", keySlot("user1000")},
+		{"foo{bar}{zap}", keySlot("bar")},
+		{"foo{{bar}}zap", keySlot("{bar")},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.slot, keySlot(tt.key), tt.key)
+	}
+	assert.Equal(t, keySlot("{user1000}.following"), keySlot("{user1000}.followers"))
+}
+
+func TestExporter_ProcessKey_WithSlot(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{WithSlot: true},
+	}
+
+	mock.ExpectClusterSlots().SetVal([]redis.ClusterSlot{
+		{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: "10.0.0.1:6379"}}},
+		{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: "10.0.0.2:6379"}, {Addr: "10.0.0.3:6379"}}},
+	})
+	exporter.loadSlotNodes(context.Background())
+
+	mock.ExpectType("foo").SetVal("string")
+	mock.ExpectGet("foo").SetVal("bar")
+	mock.ExpectTTL("foo").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "foo")
+	require.NoError(t, err)
+	require.NotNil(t, entry.Slot)
+	assert.Equal(t, 12182, *entry.Slot)
+	assert.Equal(t, "10.0.0.2:6379", entry.Node)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_WithSlotNoCluster(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{WithSlot: true},
+	}
+
+	mock.ExpectClusterSlots().SetErr(errors.New("ERR This instance has cluster support disabled"))
+	exporter.loadSlotNodes(context.Background())
+
+	mock.ExpectType("{}foo").SetVal("string")
+	mock.ExpectGet("{}foo").SetVal("bar")
+	mock.ExpectTTL("{}foo").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "{}foo")
+	require.NoError(t, err)
+	require.NotNil(t, entry.Slot)
+	assert.Equal(t, 9500, *entry.Slot)
+	assert.Empty(t, entry.Node)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMatchHashTag(t *testing.T) {
+	tests := []struct {
+		pattern string
+		tag     string
+		ok      bool
+	}{
+		{"{tenant42}:*", "tenant42", true},
+		{"user:{42}:*", "42", true},
+		{"{a}*{b}", "a", true},
+		{"*", "", false},
+		{"user:*", "", false},
+		{"*{42}*", "", false},      // a key could have an earlier {
+		{"{tenant*}:*", "", false}, // the tag itself is a pattern
+		{"{}:*", "", false},
+		{`\{x}*`, "", false},
+	}
+	for _, tt := range tests {
+		tag, ok := matchHashTag(tt.pattern)
+		assert.Equal(t, tt.ok, ok, tt.pattern)
+		assert.Equal(t, tt.tag, tag, tt.pattern)
+	}
+}
+
+func TestExporter_Export_HashTagRouting(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	nodeDB, nodeMock := redismock.NewClientMock()
+	defer func() { _ = nodeDB.Close() }()
+
+	var nodeAddr string
+	orig := newNodeClient
+	newNodeClient = func(opts *redis.Options) *redis.Client {
+		nodeAddr = opts.Addr
+		return nodeDB
+	}
+	t.Cleanup(func() { newNodeClient = orig })
+
+	config := Config{
+		RedisAddr:  "node-a:6379",
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Match:      "{tenant42}:*",
+		TagRouting: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	slot := keySlot("{tenant42}")
+	mock.ExpectClusterSlots().SetVal([]redis.ClusterSlot{
+		{Start: 0, End: slot - 1, Nodes: []redis.ClusterNode{{Addr: "node-a:6379"}}},
+		{Start: slot, End: clusterSlots - 1, Nodes: []redis.ClusterNode{{Addr: "node-b:6379"}}},
+	})
+
+	// Only the owning node is scanned
+	nodeMock.ExpectScan(0, "{tenant42}:*", int64(10)).SetVal([]string{"{tenant42}:user"}, 0)
+	nodeMock.ExpectType("{tenant42}:user").SetVal("string")
+	nodeMock.ExpectGet("{tenant42}:user").SetVal("v")
+	nodeMock.ExpectTTL("{tenant42}:user").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.Equal(t, "node-b:6379", nodeAddr)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the configured node is not scanned")
+	assert.NoError(t, nodeMock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_HashTagRoutingNoCluster(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	orig := newNodeClient
+	newNodeClient = func(opts *redis.Options) *redis.Client {
+		t.Fatalf("unexpected client for %s", opts.Addr)
+		return nil
+	}
+	t.Cleanup(func() { newNodeClient = orig })
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+		Match:      "{tenant42}:*",
+		TagRouting: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectClusterSlots().SetErr(errors.New("ERR This instance has cluster support disabled"))
+	mock.ExpectScan(0, "{tenant42}:*", int64(10)).SetVal([]string{"{tenant42}:user"}, 0)
+	mock.ExpectType("{tenant42}:user").SetVal("string")
+	mock.ExpectGet("{tenant42}:user").SetVal("v")
+	mock.ExpectTTL("{tenant42}:user").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
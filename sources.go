@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// validateSources checks that an export merging several --addr sources only
+// uses options that work per source. Options that read from a single server
+// up front, or that order or assign keys across one scan, are rejected.
+func validateSources(config Config) error {
+	if len(config.Sources) < 2 {
+		if config.Collisions {
+			return errors.New("--detect-collisions requires more than one --addr")
+		}
+		return nil
+	}
+	seen := make(map[string]bool, len(config.Sources))
+	for _, addr := range config.Sources {
+		if seen[addr] {
+			return fmt.Errorf("--addr %s given twice", addr)
+		}
+		seen[addr] = true
+	}
+	for flag, set := range map[string]bool{
+		"--rdb-file":        config.RDBFile != "",
+		"--watch":           config.Watch > 0,
+		"--keys-file":       config.KeysFile != "",
+		"--since-file":      config.SinceFile != "",
+		"--consistent":      config.Consistent,
+		"--namespace-stats": config.NamespaceStats,
+		"--preview":         config.Preview,
+		"--auto-workers":    config.AutoWorkers,
+		"--key-affinity":    config.KeyAffinity,
+		"--workers-<type>":  len(config.TypeWorkers) > 0,
+		"--ordered":         config.Ordered,
+		"--with-slot":       config.WithSlot,
+	} {
+		if set {
+			return fmt.Errorf("%s cannot be used with more than one --addr", flag)
+		}
+	}
+	for _, format := range append([]string{config.Format}, config.ExtraFormats...) {
+		if format == "parquet" || format == "sqlite" {
+			return fmt.Errorf("--format %s cannot be used with more than one --addr, it has no source column", format)
+		}
+	}
+	return nil
+}
+
+// openSources connects an exporter to each --addr after the first, sharing
+// e's configuration otherwise.
+func (e *Exporter) openSources(ctx context.Context) ([]*Exporter, error) {
+	var sources []*Exporter
+	for _, addr := range e.config.Sources[1:] {
+		config := e.config
+		config.RedisAddr = addr
+		source := NewExporter(config)
+		sources = append(sources, source)
+		if err := source.client.Ping(ctx).Err(); err != nil {
+			closeSources(sources)
+			return nil, fmt.Errorf("failed to connect to Redis at %s: %w", addr, err)
+		}
+	}
+	return sources, nil
+}
+
+func closeSources(sources []*Exporter) {
+	for _, source := range sources {
+		_ = source.client.Close()
+	}
+}
+
+// runSource scans the keyspace of an extra source and fetches its keys with
+// workers of its own, sending the entries to the resultsChan the export
+// writes. The compiled filters, the errors file and the --worker-stats
+// counters are shared with e, the source's workers counting from firstID.
+func (e *Exporter) runSource(ctx context.Context, source *Exporter, firstID int, resultsChan chan<- *RedisEntry, wg *sync.WaitGroup) {
+	source.jq = e.jq
+	source.filter = e.filter
+	source.failures = e.failures
+	source.results = resultsChan
+	source.workerStats = e.workerStats
+
+	keysChan := make(chan keyTask, source.config.BatchSize)
+	go source.scanKeys(ctx, keysChan)
+	for i := 0; i < source.config.Workers; i++ {
+		wg.Add(1)
+		go source.worker(ctx, firstID+i, keysChan, resultsChan, wg)
+	}
+}
+
+// addSourceCounts adds the counters of finished sources to e's, so the
+// export summary covers every source. A failed scan of any source makes the
+// export incomplete.
+func (e *Exporter) addSourceCounts(sources []*Exporter) {
+	for _, source := range sources {
+		e.vanished.Add(source.vanished.Load())
+		e.poolRetries.Add(source.poolRetries.Load())
+		e.reconnects.Add(source.reconnects.Load())
+		e.scanPauses.Add(source.scanPauses.Load())
+		e.failed.Add(source.failed.Load())
+		e.modules.merge(&source.modules)
+		if source.scanErr != nil {
+			e.scanErr = errors.Join(e.scanErr, fmt.Errorf("source %s: %w", source.config.RedisAddr, source.scanErr))
+		}
+	}
+}
+
+// sourceCollisions flags entries whose key was already written from another
+// source, so a merged export shows where instances overlap instead of
+// silently holding the key twice. It holds every key written, so it is only
+// used with --detect-collisions.
+type sourceCollisions struct {
+	seen  map[string]string // source each key was first written from
+	count int64
+}
+
+func newSourceCollisions() *sourceCollisions {
+	return &sourceCollisions{seen: make(map[string]string)}
+}
+
+func (c *sourceCollisions) check(entry *RedisEntry) {
+	first, ok := c.seen[entry.Key]
+	if !ok {
+		c.seen[entry.Key] = entry.Source
+		return
+	}
+	if first != entry.Source {
+		entry.Collision = true
+		c.count++
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSources(t *testing.T) {
+	assert.NoError(t, validateSources(Config{}))
+	assert.NoError(t, validateSources(Config{Sources: []string{"a:6379", "b:6379"}, Format: "json"}))
+	assert.Error(t, validateSources(Config{Sources: []string{"a:6379", "a:6379"}}))
+	assert.Error(t, validateSources(Config{Sources: []string{"a:6379", "b:6379"}, KeysFile: "keys.txt"}))
+	assert.Error(t, validateSources(Config{Sources: []string{"a:6379", "b:6379"}, Format: "json", ExtraFormats: []string{"parquet"}}))
+	assert.NoError(t, validateSources(Config{Sources: []string{"a:6379", "b:6379"}, Format: "json", Collisions: true}))
+	assert.Error(t, validateSources(Config{Collisions: true}), "there is nothing to collide with a single server")
+}
+
+func TestExporter_Export_MultipleSources(t *testing.T) {
+	dbA, mockA := redismock.NewClientMock()
+	defer func() { _ = dbA.Close() }()
+	dbB, mockB := redismock.NewClientMock()
+	defer func() { _ = dbB.Close() }()
+
+	config := Config{
+		RedisAddr:  "a:6379",
+		Sources:    []string{"a:6379", "b:6379"},
+		OutputFile: filepath.Join(t.TempDir(), "export.json"),
+		Workers:    1,
+		BatchSize:  10,
+	}
+	configB := config
+	configB.RedisAddr = "b:6379"
+	exporter := &Exporter{
+		client:  dbA,
+		config:  config,
+		sources: []*Exporter{{client: dbB, config: configB}},
+	}
+
+	mockA.ExpectScan(0, "*", int64(10)).SetVal([]string{"app-a:1"}, 0)
+	mockA.ExpectType("app-a:1").SetVal("string")
+	mockA.ExpectGet("app-a:1").SetVal("from a")
+	mockA.ExpectTTL("app-a:1").SetVal(-1 * time.Second)
+
+	mockB.ExpectScan(0, "*", int64(10)).SetVal([]string{"app-b:1"}, 0)
+	mockB.ExpectType("app-b:1").SetVal("string")
+	mockB.ExpectGet("app-b:1").SetVal("from b")
+	mockB.ExpectTTL("app-b:1").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mockA.ExpectationsWereMet())
+	require.NoError(t, mockB.ExpectationsWereMet())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, RedisEntry{Key: "app-a:1", Type: "string", Value: "from a", Source: "a:6379"}, entries[0])
+	assert.Equal(t, RedisEntry{Key: "app-b:1", Type: "string", Value: "from b", Source: "b:6379"}, entries[1])
+}
+
+func TestExporter_Export_MultipleSourcesWorkerStats(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(func() {
+		hook.Reset()
+		logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	})
+
+	dbA, mockA := redismock.NewClientMock()
+	defer func() { _ = dbA.Close() }()
+	dbB, mockB := redismock.NewClientMock()
+	defer func() { _ = dbB.Close() }()
+	mockA.MatchExpectationsInOrder(false)
+	mockB.MatchExpectationsInOrder(false)
+
+	config := Config{
+		RedisAddr:   "a:6379",
+		Sources:     []string{"a:6379", "b:6379"},
+		OutputFile:  filepath.Join(t.TempDir(), "export.json"),
+		Workers:     2,
+		BatchSize:   10,
+		WorkerStats: true,
+	}
+	configB := config
+	configB.RedisAddr = "b:6379"
+	exporter := &Exporter{
+		client:  dbA,
+		config:  config,
+		sources: []*Exporter{{client: dbB, config: configB}},
+	}
+
+	expect := func(mock redismock.ClientMock, keys ...string) {
+		mock.ExpectScan(0, "*", int64(10)).SetVal(keys, 0)
+		for _, key := range keys {
+			mock.ExpectType(key).SetVal("string")
+			mock.ExpectGet(key).SetVal("v")
+			mock.ExpectTTL(key).SetVal(-1 * time.Second)
+		}
+	}
+	expect(mockA, "a:1", "a:2", "a:3")
+	expect(mockB, "b:1", "b:2")
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	var logged int64
+	var workers []int
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Worker statistics" {
+			workers = append(workers, entry.Data["worker"].(int))
+			logged += entry.Data["keys"].(int64)
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2, 3}, workers, "the second source's workers are numbered after the first's")
+	assert.Equal(t, int64(5), logged, "per-worker counts add up to the keys of every source")
+}
+
+func TestSourceCollisions(t *testing.T) {
+	collisions := newSourceCollisions()
+
+	first := &RedisEntry{Key: "shared", Source: "a:6379"}
+	second := &RedisEntry{Key: "shared", Source: "b:6379"}
+	other := &RedisEntry{Key: "only-b", Source: "b:6379"}
+	for _, entry := range []*RedisEntry{first, second, other} {
+		collisions.check(entry)
+	}
+
+	assert.False(t, first.Collision, "the first source to write a key keeps it unmarked")
+	assert.True(t, second.Collision)
+	assert.False(t, other.Collision)
+	assert.Equal(t, int64(1), collisions.count)
+}
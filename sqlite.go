@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteWriter inserts entries into a redis_entries table in a SQLite
+// database file. Rows are inserted in transactions of up to batchSize rows;
+// values are stored as JSON text, as in Parquet output.
+type sqliteWriter struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	insert    *sql.Stmt
+	pending   int
+	batchSize int
+}
+
+func newSQLiteWriter(path string, batchSize int) (*sqliteWriter, error) {
+	if path == stdoutOutput {
+		return nil, fmt.Errorf("sqlite output needs a file, not stdout")
+	}
+	if batchSize < 1 {
+		batchSize = 1000
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// A single connection keeps every statement on the open transaction.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS redis_entries (
+	key   TEXT NOT NULL,
+	type  TEXT NOT NULL,
+	ttl   INTEGER NOT NULL,
+	value TEXT
+)`); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create redis_entries table: %w", err)
+	}
+
+	return &sqliteWriter{db: db, batchSize: batchSize}, nil
+}
+
+func (s *sqliteWriter) WriteEntry(entry *RedisEntry) error {
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		insert, err := tx.Prepare("INSERT INTO redis_entries (key, type, ttl, value) VALUES (?, ?, ?, ?)")
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		s.tx, s.insert = tx, insert
+	}
+
+	var value interface{}
+	if entry.Value != nil {
+		data, err := json.Marshal(entry.Value)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	}
+
+	if _, err := s.insert.Exec(entry.Key, entry.Type, entry.TTL, value); err != nil {
+		return err
+	}
+
+	s.pending++
+	if s.pending >= s.batchSize {
+		return s.commit()
+	}
+	return nil
+}
+
+// commit commits the open transaction, if any.
+func (s *sqliteWriter) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	_ = s.insert.Close()
+	err := s.tx.Commit()
+	s.tx, s.insert, s.pending = nil, nil, 0
+	return err
+}
+
+// Close commits the remaining rows, indexes the key column and closes the
+// database. The index is built last so bulk inserts don't maintain it.
+func (s *sqliteWriter) Close() error {
+	err := s.commit()
+	if err == nil {
+		_, err = s.db.Exec("CREATE INDEX IF NOT EXISTS redis_entries_key ON redis_entries (key)")
+	}
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
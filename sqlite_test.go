@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sqliteRow struct {
+	Key   string
+	Type  string
+	TTL   int64
+	Value sql.NullString
+}
+
+func readSQLiteRows(t *testing.T, path, query string) []sqliteRow {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.Query(query)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+
+	var result []sqliteRow
+	for rows.Next() {
+		var row sqliteRow
+		require.NoError(t, rows.Scan(&row.Key, &row.Type, &row.TTL, &row.Value))
+		result = append(result, row)
+	}
+	require.NoError(t, rows.Err())
+	return result
+}
+
+func TestSQLiteWriter_Batches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.db")
+	writer, err := newSQLiteWriter(path, 2)
+	require.NoError(t, err)
+
+	entries := []*RedisEntry{
+		{Key: "s", Type: "string", Value: "hello", TTL: 60},
+		{Key: "l", Type: "list", Value: []string{"a", "b"}},
+		{Key: "h", Type: "hash", Value: map[string]string{"f": "v"}},
+		{Key: "meta", Type: "string"},
+	}
+	for _, entry := range entries {
+		require.NoError(t, writer.WriteEntry(entry))
+	}
+	require.NoError(t, writer.Close())
+
+	rows := readSQLiteRows(t, path, "SELECT key, type, ttl, value FROM redis_entries ORDER BY rowid")
+	assert.Equal(t, []sqliteRow{
+		{Key: "s", Type: "string", TTL: 60, Value: sql.NullString{String: `"hello"`, Valid: true}},
+		{Key: "l", Type: "list", Value: sql.NullString{String: `["a","b"]`, Valid: true}},
+		{Key: "h", Type: "hash", Value: sql.NullString{String: `{"f":"v"}`, Valid: true}},
+		{Key: "meta", Type: "string"},
+	}, rows)
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	var index string
+	require.NoError(t, db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'redis_entries'").Scan(&index))
+	assert.Equal(t, "redis_entries_key", index)
+}
+
+func TestSQLiteWriter_RejectsStdout(t *testing.T) {
+	_, err := newSQLiteWriter(stdoutOutput, 10)
+	assert.Error(t, err)
+}
+
+func TestExporter_Export_SQLite(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.db"),
+		Format:     "sqlite",
+		Workers:    1,
+		BatchSize:  10,
+	}
+
+	// A stale file at the output path is replaced, not appended to
+	require.NoError(t, os.WriteFile(config.OutputFile, []byte("stale"), 0o644))
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"small", "big"}, 0)
+	mock.ExpectType("small").SetVal("string")
+	mock.ExpectGet("small").SetVal("v")
+	mock.ExpectTTL("small").SetVal(30 * time.Second)
+	mock.ExpectType("big").SetVal("string")
+	mock.ExpectGet("big").SetVal(string(make([]byte, 2048)))
+	mock.ExpectTTL("big").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	rows := readSQLiteRows(t, config.OutputFile, "SELECT key, type, ttl, value FROM redis_entries ORDER BY key")
+	require.Len(t, rows, 2)
+	assert.Equal(t, "big", rows[0].Key)
+	assert.Equal(t, sqliteRow{Key: "small", Type: "string", TTL: 30, Value: sql.NullString{String: `"v"`, Valid: true}}, rows[1])
+
+	large := readSQLiteRows(t, config.OutputFile, "SELECT key, type, ttl, value FROM redis_entries WHERE length(value) > 1024")
+	require.Len(t, large, 1)
+	assert.Equal(t, "big", large[0].Key)
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// sizeBucket is a histogram bucket holding values smaller than limit bytes.
+// The last bucket has no limit.
+type sizeBucket struct {
+	label string
+	limit int
+}
+
+var sizeBuckets = []sizeBucket{
+	{label: "<1KB", limit: 1 << 10},
+	{label: "1-10KB", limit: 10 << 10},
+	{label: "10-100KB", limit: 100 << 10},
+	{label: "100KB-1MB", limit: 1 << 20},
+	{label: ">1MB"},
+}
+
+// keySize records the serialized size of a key's value.
+type keySize struct {
+	Key  string
+	Size int
+}
+
+// sizeHistogram buckets value sizes and tracks the largest keys.
+type sizeHistogram struct {
+	counts []int64
+	top    []keySize
+	topN   int
+}
+
+func newSizeHistogram(topN int) *sizeHistogram {
+	return &sizeHistogram{
+		counts: make([]int64, len(sizeBuckets)),
+		topN:   topN,
+	}
+}
+
+// valueSize returns the size in bytes of value once encoded as JSON.
+func valueSize(value interface{}) int {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+func (h *sizeHistogram) observe(key string, size int) {
+	for i, bucket := range sizeBuckets {
+		if bucket.limit == 0 || size < bucket.limit {
+			h.counts[i]++
+			break
+		}
+	}
+
+	if h.topN <= 0 {
+		return
+	}
+	if len(h.top) == h.topN && size <= h.top[len(h.top)-1].Size {
+		return
+	}
+
+	i := sort.Search(len(h.top), func(i int) bool { return h.top[i].Size < size })
+	h.top = append(h.top, keySize{})
+	copy(h.top[i+1:], h.top[i:])
+	h.top[i] = keySize{Key: key, Size: size}
+	if len(h.top) > h.topN {
+		h.top = h.top[:h.topN]
+	}
+}
+
+// log writes the bucket counts and largest keys.
+func (h *sizeHistogram) log() {
+	fields := logrus.Fields{}
+	for i, bucket := range sizeBuckets {
+		fields[bucket.label] = h.counts[i]
+	}
+	logrus.WithFields(fields).Info("Value size distribution")
+
+	for rank, ks := range h.top {
+		logrus.WithFields(logrus.Fields{
+			"rank":       rank + 1,
+			"key":        ks.Key,
+			"size_bytes": ks.Size,
+		}).Info("Largest key")
+	}
+}
+
+// ttlBucket is a histogram bucket holding TTLs shorter than limit. The last
+// bucket has no limit.
+type ttlBucket struct {
+	label string
+	limit time.Duration
+}
+
+var ttlBuckets = []ttlBucket{
+	{label: "<1m", limit: time.Minute},
+	{label: "<1h", limit: time.Hour},
+	{label: "<1d", limit: 24 * time.Hour},
+	{label: ">1d"},
+}
+
+// ttlHistogram buckets keys by remaining TTL, counting keys without an
+// expiry apart from the rest.
+type ttlHistogram struct {
+	persistent int64
+	counts     []int64
+}
+
+func newTTLHistogram() *ttlHistogram {
+	return &ttlHistogram{counts: make([]int64, len(ttlBuckets))}
+}
+
+func (h *ttlHistogram) observe(entry *RedisEntry) {
+	ttl := time.Duration(entry.TTL) * time.Second
+	if entry.TTLMillis > 0 {
+		ttl = time.Duration(entry.TTLMillis) * time.Millisecond
+	}
+	if ttl <= 0 {
+		h.persistent++
+		return
+	}
+	for i, bucket := range ttlBuckets {
+		if bucket.limit == 0 || ttl < bucket.limit {
+			h.counts[i]++
+			break
+		}
+	}
+}
+
+// log writes the bucket counts.
+func (h *ttlHistogram) log() {
+	fields := logrus.Fields{"no_expiry": h.persistent}
+	for i, bucket := range ttlBuckets {
+		fields[bucket.label] = h.counts[i]
+	}
+	logrus.WithFields(fields).Info("TTL distribution")
+}
+
+// slowKey records how long a key took to process.
+type slowKey struct {
+	Key      string
+	Duration time.Duration
+}
+
+// slowKeys counts the keys slower than --slow-key-threshold and tracks the
+// slowest of them.
+type slowKeys struct {
+	count int64
+	top   []slowKey
+	topN  int
+}
+
+func newSlowKeys(topN int) *slowKeys {
+	return &slowKeys{topN: topN}
+}
+
+func (s *slowKeys) observe(key string, duration time.Duration) {
+	s.count++
+	if s.topN <= 0 {
+		return
+	}
+	if len(s.top) == s.topN && duration <= s.top[len(s.top)-1].Duration {
+		return
+	}
+
+	i := sort.Search(len(s.top), func(i int) bool { return s.top[i].Duration < duration })
+	s.top = append(s.top, slowKey{})
+	copy(s.top[i+1:], s.top[i:])
+	s.top[i] = slowKey{Key: key, Duration: duration}
+	if len(s.top) > s.topN {
+		s.top = s.top[:s.topN]
+	}
+}
+
+// log writes the number of slow keys and the slowest of them.
+func (s *slowKeys) log() {
+	logrus.WithField("slow_keys", s.count).Info("Keys slower than the threshold")
+	for rank, sk := range s.top {
+		logrus.WithFields(logrus.Fields{
+			"rank":     rank + 1,
+			"key":      sk.Key,
+			"duration": sk.Duration.Round(time.Millisecond),
+		}).Info("Slowest key")
+	}
+}
+
+// workerStat counts the keys one worker exported and the time it spent
+// fetching them.
+type workerStat struct {
+	keys  atomic.Int64
+	fetch atomic.Int64 // nanoseconds
+}
+
+// workerStats holds the --worker-stats counters, indexed by worker ID.
+type workerStats []workerStat
+
+func newWorkerStats(workers int) workerStats {
+	return make(workerStats, workers)
+}
+
+func (s workerStats) observe(id int, fetch time.Duration) {
+	s[id].keys.Add(1)
+	s[id].fetch.Add(int64(fetch))
+}
+
+// total returns the number of keys exported by all workers.
+func (s workerStats) total() int64 {
+	var total int64
+	for i := range s {
+		total += s[i].keys.Load()
+	}
+	return total
+}
+
+// log writes each worker's keys, share of the export and fetch time. A
+// worker with a small share but a long fetch time was stuck on large keys.
+func (s workerStats) log() {
+	total := s.total()
+	for id := range s {
+		keys := s[id].keys.Load()
+		fetch := time.Duration(s[id].fetch.Load())
+		fields := logrus.Fields{
+			"worker":     id,
+			"keys":       keys,
+			"fetch_time": fetch.Round(time.Millisecond),
+		}
+		if total > 0 {
+			fields["share_pct"] = math.Round(float64(keys)*1000/float64(total)) / 10
+		}
+		if keys > 0 {
+			fields["avg_fetch"] = (fetch / time.Duration(keys)).Round(time.Microsecond)
+		}
+		logrus.WithFields(fields).Info("Worker statistics")
+	}
+}
+
+// RunStats are the final statistics of an export, written as JSON with
+// --stats-json so CI pipelines can check the results of a run.
+type RunStats struct {
+	StartedAt       time.Time        `json:"started_at"`
+	CompletedAt     time.Time        `json:"completed_at"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	KeysPerSec      float64          `json:"keys_per_sec"`
+	TotalKeys       int64            `json:"total_keys"`
+	Types           map[string]int64 `json:"types"` // keys exported per type
+	Errors          int64            `json:"errors"`
+	VanishedKeys    int64            `json:"vanished_keys"`
+	PoolRetries     int64            `json:"pool_retries"`
+	Reconnects      int64            `json:"reconnects"`
+	Pool            ManifestPool     `json:"pool"`
+}
+
+// writeRunStats writes the --stats-json file of a completed export.
+func (e *Exporter) writeRunStats(startedAt time.Time, elapsed time.Duration, keys int64, types map[string]int64, pool *redis.PoolStats) error {
+	stats := &RunStats{
+		StartedAt:       startedAt.UTC(),
+		CompletedAt:     timeNow().UTC(),
+		DurationSeconds: elapsed.Seconds(),
+		TotalKeys:       keys,
+		Types:           types,
+		Errors:          e.failed.Load(),
+		VanishedKeys:    e.vanished.Load(),
+		PoolRetries:     e.poolRetries.Load(),
+		Reconnects:      e.reconnects.Load(),
+		Pool:            manifestPool(pool),
+	}
+	if elapsed > 0 {
+		stats.KeysPerSec = math.Round(float64(keys) / elapsed.Seconds())
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(e.config.StatsJSON, append(data, '\n'), e.config.FileMode); err != nil {
+		return fmt.Errorf("failed to write stats file: %w", err)
+	}
+	logrus.WithField("stats_json", e.config.StatsJSON).Info("Wrote export statistics")
+	return nil
+}
+
+// hasInvalidUTF8 reports whether any string in value is not valid UTF-8 and
+// so would be mangled by JSON encoding.
+func hasInvalidUTF8(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return !utf8.ValidString(v)
+	case []string:
+		for _, s := range v {
+			if !utf8.ValidString(s) {
+				return true
+			}
+		}
+	case map[string]string:
+		for field, s := range v {
+			if !utf8.ValidString(field) || !utf8.ValidString(s) {
+				return true
+			}
+		}
+	case []HashField:
+		for _, field := range v {
+			if !utf8.ValidString(field.Field) || !utf8.ValidString(field.Value) {
+				return true
+			}
+		}
+	case []redis.Z:
+		for _, z := range v {
+			if hasInvalidUTF8(z.Member) {
+				return true
+			}
+		}
+	case []redis.XMessage:
+		for _, message := range v {
+			for field, s := range message.Values {
+				if !utf8.ValidString(field) || hasInvalidUTF8(s) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// logBinaryKeys lists the keys whose values contain invalid UTF-8.
+func logBinaryKeys(keys []string) {
+	logrus.WithField("binary_keys", len(keys)).Info("Keys with non-UTF-8 values")
+	for _, key := range keys {
+		logrus.WithField("key", key).Info("Non-UTF-8 value")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/template"
+)
+
+// templateFormat is the output format of --template exports.
+const templateFormat = "template"
+
+// templateFuncs are available to --template in addition to the text/template
+// builtins.
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"quote": strconv.Quote,
+}
+
+// compileTemplate parses a --template.
+func compileTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("entry").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateWriter writes each entry through a text/template, one entry per
+// line.
+type templateWriter struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func newTemplateWriter(w io.Writer, tmpl *template.Template) *templateWriter {
+	return &templateWriter{w: w, tmpl: tmpl}
+}
+
+func (t *templateWriter) WriteEntry(entry *RedisEntry) error {
+	if err := t.tmpl.Execute(t.w, entry); err != nil {
+		return err
+	}
+	_, err := io.WriteString(t.w, "\n")
+	return err
+}
+
+// Close writes nothing: template output has no footer.
+func (t *templateWriter) Close() error {
+	return nil
+}
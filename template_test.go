@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileTemplate(t *testing.T) {
+	_, err := compileTemplate("{{.Key}}={{.Value}}")
+	assert.NoError(t, err)
+
+	_, err = compileTemplate("{{.Key")
+	assert.Error(t, err)
+}
+
+func TestTemplateWriter(t *testing.T) {
+	tmpl, err := compileTemplate(`{{.Key}} {{json .Value}}{{if .TTL}} ttl={{.TTL}}{{end}}`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := newTemplateWriter(&buf, tmpl)
+	require.NoError(t, w.WriteEntry(&RedisEntry{Key: "a", Type: "string", Value: "1", TTL: 30}))
+	require.NoError(t, w.WriteEntry(&RedisEntry{Key: "l", Type: "list", Value: []string{"x", "y"}}))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "a \"1\" ttl=30\nl [\"x\",\"y\"]\n", buf.String())
+}
+
+func TestTemplateWriter_ExecError(t *testing.T) {
+	tmpl, err := compileTemplate(`{{.Missing}}`)
+	require.NoError(t, err)
+
+	w := newTemplateWriter(&bytes.Buffer{}, tmpl)
+	assert.Error(t, w.WriteEntry(&RedisEntry{Key: "a"}))
+}
+
+func TestExporter_Export_Template(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: filepath.Join(t.TempDir(), "export.txt"),
+		Format:     templateFormat,
+		Template:   `SET {{quote .Key}} {{quote .Value}}`,
+		Workers:    1,
+		BatchSize:  10,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"user:1", "greeting"}, 0)
+	mock.ExpectType("user:1").SetVal("string")
+	mock.ExpectGet("user:1").SetVal("alice")
+	mock.ExpectTTL("user:1").SetVal(-1 * time.Second)
+	mock.ExpectType("greeting").SetVal("string")
+	mock.ExpectGet("greeting").SetVal(`say "hi"`)
+	mock.ExpectTTL("greeting").SetVal(-1 * time.Second)
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	assert.Equal(t, "SET \"user:1\" \"alice\"\nSET \"greeting\" \"say \\\"hi\\\"\"\n", string(data))
+}
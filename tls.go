@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// newTLSConfig returns the TLS settings for --tls, or nil to connect over
+// plain TCP. --tls-ca implies --tls.
+func newTLSConfig(enabled bool, caPath string) (*tls.Config, error) {
+	if !enabled && caPath == "" {
+		return nil, nil
+	}
+	roots, err := loadRootCAs(caPath)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    roots,
+	}, nil
+}
+
+// loadRootCAs returns the certificates a server's certificate is verified
+// against. path is a PEM bundle or a directory of .pem and .crt files, and
+// replaces the system roots, as needed for a private CA. Without a path the
+// system roots are used, which trust managed services with public
+// certificates.
+func loadRootCAs(path string) (*x509.CertPool, error) {
+	if path == "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system certificates: %w", err)
+		}
+		return pool, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --tls-ca: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --tls-ca: %w", err)
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".pem" || ext == ".crt") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no .pem or .crt files in --tls-ca directory %s", path)
+		}
+	}
+
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.New("no PEM certificates found in " + file)
+		}
+	}
+	return pool, nil
+}
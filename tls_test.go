@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfSignedCert returns a certificate for 127.0.0.1 that is its own CA,
+// and its PEM encoding.
+func selfSignedCert(t *testing.T, name string) (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestNewTLSConfig_SystemPool(t *testing.T) {
+	system, err := x509.SystemCertPool()
+	require.NoError(t, err)
+
+	tlsConfig, err := newTLSConfig(true, "")
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig.RootCAs)
+	assert.True(t, tlsConfig.RootCAs.Equal(system), "without --tls-ca the system roots are trusted")
+
+	tlsConfig, err = newTLSConfig(false, "")
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func TestLoadRootCAs_Directory(t *testing.T) {
+	dir := t.TempDir()
+	_, first := selfSignedCert(t, "first")
+	_, second := selfSignedCert(t, "second")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "first.pem"), first, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "second.crt"), second, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0o600))
+
+	pool, err := loadRootCAs(dir)
+	require.NoError(t, err)
+
+	expected := x509.NewCertPool()
+	expected.AppendCertsFromPEM(first)
+	expected.AppendCertsFromPEM(second)
+	assert.True(t, pool.Equal(expected), "every .pem and .crt file in the directory is loaded")
+
+	_, err = loadRootCAs(t.TempDir())
+	assert.Error(t, err, "an empty directory has no CAs")
+
+	bad := filepath.Join(dir, "bad.pem")
+	require.NoError(t, os.WriteFile(bad, []byte("garbage"), 0o600))
+	_, err = loadRootCAs(bad)
+	assert.Error(t, err)
+}
+
+func TestNewRedisOptions_DialerTLS(t *testing.T) {
+	cert, certPEM := selfSignedCert(t, "redis")
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+	tlsConfig, err := newTLSConfig(false, caFile)
+	require.NoError(t, err)
+
+	opts := newRedisOptions(Config{TLS: tlsConfig})
+	conn, err := opts.Dialer(context.Background(), "tcp", listener.Addr().String())
+	require.NoError(t, err)
+	assert.IsType(t, &tls.Conn{}, conn)
+	_ = conn.Close()
+
+	// The system roots don't trust the self-signed certificate
+	tlsConfig, err = newTLSConfig(true, "")
+	require.NoError(t, err)
+	opts = newRedisOptions(Config{TLS: tlsConfig})
+	_, err = opts.Dialer(context.Background(), "tcp", listener.Addr().String())
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "redis-export"
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// setupTracing installs a global tracer provider that sends spans to the OTLP
+// HTTP endpoint, and instruments client so every Redis command gets a span.
+// The returned function flushes and shuts down the provider.
+func setupTracing(ctx context.Context, endpoint string, client *redis.Client) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", tracerName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		_ = provider.Shutdown(ctx)
+		return nil, fmt.Errorf("failed to instrument Redis client: %w", err)
+	}
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useInMemoryTracer(t *testing.T) *tracetest.InMemoryExporter {
+	spans := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return spans
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func TestExporter_Export_Spans(t *testing.T) {
+	spans := useInMemoryTracer(t)
+
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_tracing.json",
+		Workers:    1,
+		BatchSize:  2,
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	mock.ExpectScan(0, "*", int64(2)).SetVal([]string{"key1", "key2", "key3"}, 0)
+	for _, key := range []string{"key1", "key2", "key3"} {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("value")
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+
+	names := spanNames(spans.GetSpans())
+	assert.Contains(t, names, "Export")
+	assert.Contains(t, names, "scan")
+
+	batches := 0
+	for _, name := range names {
+		if name == "write batch" {
+			batches++
+		}
+	}
+	assert.Equal(t, 2, batches, "three entries with a batch size of two make two batches")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export_SpansEndedOnCancel(t *testing.T) {
+	spans := useInMemoryTracer(t)
+
+	db, _ := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	config := Config{
+		OutputFile: "test_export_tracing_cancel.json",
+		Workers:    1,
+		BatchSize:  10,
+	}
+
+	exporter := &Exporter{
+		client: db,
+		config: config,
+	}
+
+	defer func() { _ = os.Remove(config.OutputFile) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := exporter.Export(ctx)
+
+	var root *tracetest.SpanStub
+	for i, span := range spans.GetSpans() {
+		if span.Name == "Export" {
+			root = &spans.GetSpans()[i]
+		}
+	}
+	require.NotNil(t, root, "Export span should be ended on cancellation")
+	if err != nil {
+		assert.Equal(t, codes.Error, root.Status.Code)
+	}
+}
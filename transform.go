@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// compileJQ parses and compiles a jq expression for --jq.
+func compileJQ(expr string) (*gojq.Code, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression: %w", err)
+	}
+	return code, nil
+}
+
+// transformValue runs value through the --jq expression and returns its
+// first result, or nil if the expression produced nothing. The value is
+// round-tripped through JSON first so it has the same shape jq would see in
+// the export file.
+func (e *Exporter) transformValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var input interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+
+	iter := e.jq.Run(input)
+	result, ok := iter.Next()
+	if !ok {
+		return nil, nil
+	}
+	if err, ok := result.(error); ok {
+		return nil, fmt.Errorf("jq: %w", err)
+	}
+	return result, nil
+}
+
+// embedJSONValues returns value with any string, or hash field value, that
+// holds a JSON object or array replaced by that JSON, so --parse-json-values
+// writes it nested rather than as a quoted string. Other strings, including
+// JSON scalars and text that fails to parse, are kept as they were.
+func embedJSONValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return embedJSON(v)
+	case map[string]string:
+		fields := make(map[string]interface{}, len(v))
+		for name, s := range v {
+			fields[name] = embedJSON(s)
+		}
+		return fields
+	case []HashField:
+		fields := make([]nestedHashField, len(v))
+		for i, field := range v {
+			fields[i] = nestedHashField{Field: field.Field, Value: embedJSON(field.Value)}
+		}
+		return fields
+	default:
+		return value
+	}
+}
+
+// nestedHashField is a HashField whose value may be embedded JSON.
+type nestedHashField struct {
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// embedJSON returns s as raw JSON when it holds an object or array, and s
+// itself otherwise.
+func embedJSON(s string) interface{} {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') || !json.Valid([]byte(trimmed)) {
+		return s
+	}
+	return json.RawMessage(trimmed)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileJQ_Invalid(t *testing.T) {
+	_, err := compileJQ(".foo |")
+	assert.Error(t, err)
+}
+
+func TestExporter_ProcessKey_JQ(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	code, err := compileJQ("fromjson | .name")
+	require.NoError(t, err)
+
+	exporter := &Exporter{
+		client: db,
+		jq:     code,
+	}
+
+	mock.ExpectType("user:1").SetVal("string")
+	mock.ExpectGet("user:1").SetVal(`{"name":"alice","age":30}`)
+	mock.ExpectTTL("user:1").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", entry.Value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_JQHash(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	code, err := compileJQ("{email}")
+	require.NoError(t, err)
+
+	exporter := &Exporter{
+		client: db,
+		jq:     code,
+	}
+
+	mock.ExpectType("user:1").SetVal("hash")
+	mock.ExpectHGetAll("user:1").SetVal(map[string]string{"email": "a@example.com", "password": "secret"})
+	mock.ExpectTTL("user:1").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"email": "a@example.com"}, entry.Value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_JQDropNull(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	code, err := compileJQ("fromjson | .missing")
+	require.NoError(t, err)
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{JQDropNull: true},
+		jq:     code,
+	}
+
+	mock.ExpectType("user:1").SetVal("string")
+	mock.ExpectGet("user:1").SetVal(`{"name":"alice"}`)
+
+	_, err = exporter.processKey(context.Background(), "user:1")
+	assert.ErrorIs(t, err, errKeySkipped)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_JQError(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	code, err := compileJQ("fromjson")
+	require.NoError(t, err)
+
+	exporter := &Exporter{
+		client: db,
+		jq:     code,
+	}
+
+	mock.ExpectType("plain").SetVal("string")
+	mock.ExpectGet("plain").SetVal("not json")
+
+	_, err = exporter.processKey(context.Background(), "plain")
+	var kerr *keyError
+	require.ErrorAs(t, err, &kerr)
+	assert.Equal(t, "string", kerr.keyType)
+}
+
+func TestExporter_ProcessKey_ParseJSONValues(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db, config: Config{ParseJSON: true}}
+
+	mock.ExpectType("user").SetVal("string")
+	mock.ExpectGet("user").SetVal(`{"name": "ada", "id": 12345678901234567890}`)
+	mock.ExpectTTL("user").SetVal(-1 * time.Second)
+	mock.ExpectType("profile").SetVal("hash")
+	mock.ExpectHGetAll("profile").SetVal(map[string]string{
+		"tags":  `["a","b"]`,
+		"count": "42",
+		"note":  "{not json",
+	})
+	mock.ExpectTTL("profile").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "user")
+	require.NoError(t, err)
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"user","type":"string","value":{"name":"ada","id":12345678901234567890}}`, string(data),
+		"the JSON string is embedded as a nested object")
+
+	entry, err = exporter.processKey(context.Background(), "profile")
+	require.NoError(t, err)
+	data, err = json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"profile","type":"hash","value":{"tags":["a","b"],"count":"42","note":"{not json"}}`, string(data),
+		"scalars and invalid JSON stay strings")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
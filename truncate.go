@@ -0,0 +1,71 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// truncateValue shrinks value so its JSON encoding fits in limit bytes,
+// reporting whether anything was cut. Strings are cut at a UTF-8 boundary;
+// collections keep their leading elements (hash fields in field order).
+// Module values are left whole since a partial payload is useless.
+func truncateValue(value interface{}, limit int) (interface{}, bool) {
+	if limit <= 0 || valueSize(value) <= limit {
+		return value, false
+	}
+
+	switch v := value.(type) {
+	case string:
+		return truncateString(v, limit), true
+	case []string:
+		return capElements(v, limit)
+	case []redis.Z:
+		return capElements(v, limit)
+	case []redis.XMessage:
+		return capElements(v, limit)
+	case []HashField:
+		return capElements(v, limit)
+	case map[string]string:
+		kept := make(map[string]string)
+		size := 2 // enclosing braces
+		for _, field := range sortedHashFields(v) {
+			size += valueSize(field.Field) + valueSize(field.Value) + 2
+			if size > limit {
+				break
+			}
+			kept[field.Field] = field.Value
+		}
+		return kept, true
+	default:
+		return value, false
+	}
+}
+
+// truncateString returns the longest prefix of s, cut at a UTF-8 boundary,
+// whose JSON encoding including quotes and escapes fits in limit bytes.
+func truncateString(s string, limit int) string {
+	size := 2 // enclosing quotes
+	for i := 0; i < len(s); {
+		_, width := utf8.DecodeRuneInString(s[i:])
+		size += valueSize(s[i:i+width]) - 2
+		if size > limit {
+			return s[:i]
+		}
+		i += width
+	}
+	return s
+}
+
+// capElements keeps the leading items whose combined encoded size fits in
+// limit bytes.
+func capElements[T any](items []T, limit int) ([]T, bool) {
+	size := 2 // enclosing brackets
+	for i, item := range items {
+		size += valueSize(item) + 1
+		if size > limit {
+			return items[:i], true
+		}
+	}
+	return items, false
+}
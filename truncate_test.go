@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateValue_String(t *testing.T) {
+	value, truncated := truncateValue(strings.Repeat("a", 100), 10)
+	assert.True(t, truncated)
+	assert.Equal(t, "aaaaaaaa", value, "the quotes count toward the limit")
+
+	value, truncated = truncateValue("short", 10)
+	assert.False(t, truncated)
+	assert.Equal(t, "short", value)
+
+	// Never cut a multi-byte character in half.
+	value, truncated = truncateValue(strings.Repeat("é", 10), 6)
+	assert.True(t, truncated)
+	assert.Equal(t, "éé", value)
+
+	value, truncated = truncateValue(strings.Repeat("é", 10), 5)
+	assert.True(t, truncated)
+	assert.Equal(t, "é", value)
+}
+
+func TestTruncateValue_StringAtLimit(t *testing.T) {
+	// "abcd" encodes to six bytes, so limits just around its raw length cut it
+	value, truncated := truncateValue("abcd", 6)
+	assert.False(t, truncated)
+	assert.Equal(t, "abcd", value)
+
+	value, truncated = truncateValue("abcd", 5)
+	assert.True(t, truncated)
+	assert.Equal(t, "abc", value)
+
+	value, truncated = truncateValue("abcd", 4)
+	assert.True(t, truncated)
+	assert.Equal(t, "ab", value)
+
+	value, truncated = truncateValue("abcd", 3)
+	assert.True(t, truncated)
+	assert.Equal(t, "a", value)
+}
+
+func TestTruncateValue_StringEscapes(t *testing.T) {
+	for _, s := range []string{`say "hi" now`, "<b>&</b>", "a\x00b\x01\nc", "\xff\xfeab"} {
+		for limit := 2; limit <= valueSize(s); limit++ {
+			value, _ := truncateValue(s, limit)
+			assert.LessOrEqual(t, valueSize(value), limit, "%q cut to %d bytes", s, limit)
+			assert.True(t, strings.HasPrefix(s, value.(string)))
+		}
+	}
+
+	value, truncated := truncateValue(`"abc"`, 4)
+	assert.True(t, truncated)
+	assert.Equal(t, `"`, value, "an escaped quote takes two bytes")
+}
+
+func TestTruncateValue_Collections(t *testing.T) {
+	list := []string{"aaaa", "bbbb", "cccc", "dddd"}
+	value, truncated := truncateValue(list, 20)
+	assert.True(t, truncated)
+	assert.Equal(t, []string{"aaaa", "bbbb"}, value)
+
+	hash := map[string]string{"c": "3", "a": "1", "b": "2"}
+	value, truncated = truncateValue(hash, 20)
+	assert.True(t, truncated)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, value)
+
+	value, truncated = truncateValue(list, 0)
+	assert.False(t, truncated, "a zero limit disables truncation")
+	assert.Equal(t, list, value)
+}
+
+func TestExporter_ProcessKey_MaxValueBytes(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{MaxValueBytes: 16},
+	}
+
+	mock.ExpectType("big").SetVal("string")
+	mock.ExpectGet("big").SetVal(strings.Repeat("x", 1024))
+	mock.ExpectTTL("big").SetVal(-1 * time.Second)
+	mock.ExpectType("biglist").SetVal("list")
+	mock.ExpectLRange("biglist", 0, -1).SetVal([]string{"one", "two", "three", "four", "five"})
+	mock.ExpectTTL("biglist").SetVal(-1 * time.Second)
+	mock.ExpectType("small").SetVal("string")
+	mock.ExpectGet("small").SetVal("ok")
+	mock.ExpectTTL("small").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "big")
+	require.NoError(t, err)
+	assert.True(t, entry.Truncated)
+	assert.Len(t, entry.Value, 14, "the value fits in 16 bytes once quoted")
+
+	entry, err = exporter.processKey(context.Background(), "biglist")
+	require.NoError(t, err)
+	assert.True(t, entry.Truncated)
+	assert.Equal(t, []string{"one", "two"}, entry.Value)
+
+	entry, err = exporter.processKey(context.Background(), "small")
+	require.NoError(t, err)
+	assert.False(t, entry.Truncated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_ListLimit(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{ListLimit: 3},
+	}
+
+	mock.ExpectType("queue").SetVal("list")
+	mock.ExpectLRange("queue", 0, 2).SetVal([]string{"e5", "e4", "e3"})
+	mock.ExpectLLen("queue").SetVal(5)
+	mock.ExpectTTL("queue").SetVal(-1 * time.Second)
+	mock.ExpectType("exact").SetVal("list")
+	mock.ExpectLRange("exact", 0, 2).SetVal([]string{"a", "b", "c"})
+	mock.ExpectLLen("exact").SetVal(3)
+	mock.ExpectTTL("exact").SetVal(-1 * time.Second)
+	mock.ExpectType("short").SetVal("list")
+	mock.ExpectLRange("short", 0, 2).SetVal([]string{"a"})
+	mock.ExpectTTL("short").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "queue")
+	require.NoError(t, err)
+	assert.True(t, entry.Truncated)
+	assert.Equal(t, []string{"e5", "e4", "e3"}, entry.Value)
+
+	entry, err = exporter.processKey(context.Background(), "exact")
+	require.NoError(t, err)
+	assert.False(t, entry.Truncated, "a list of exactly the limit is complete")
+
+	entry, err = exporter.processKey(context.Background(), "short")
+	require.NoError(t, err)
+	assert.False(t, entry.Truncated, "LLEN is skipped for lists under the limit")
+	assert.Equal(t, []string{"a"}, entry.Value)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_ProcessKey_SampleMembers(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{
+		client: db,
+		config: Config{SampleMembers: 2},
+	}
+
+	mock.ExpectType("tags").SetVal("set")
+	mock.ExpectSRandMemberN("tags", 2).SetVal([]string{"b", "d"})
+	mock.ExpectSCard("tags").SetVal(5)
+	mock.ExpectTTL("tags").SetVal(-1 * time.Second)
+	mock.ExpectType("board").SetVal("zset")
+	mock.ExpectZRandMemberWithScores("board", 2).SetVal([]redis.Z{{Score: 3, Member: "c"}, {Score: 1, Member: "a"}})
+	mock.ExpectZCard("board").SetVal(10)
+	mock.ExpectTTL("board").SetVal(-1 * time.Second)
+	mock.ExpectType("user").SetVal("hash")
+	mock.ExpectHRandFieldWithValues("user", 2).SetVal([]redis.KeyValue{{Key: "name", Value: "ada"}, {Key: "age", Value: "36"}})
+	mock.ExpectHLen("user").SetVal(2)
+	mock.ExpectTTL("user").SetVal(-1 * time.Second)
+	mock.ExpectType("small").SetVal("set")
+	mock.ExpectSRandMemberN("small", 2).SetVal([]string{"only"})
+	mock.ExpectTTL("small").SetVal(-1 * time.Second)
+
+	entry, err := exporter.processKey(context.Background(), "tags")
+	require.NoError(t, err)
+	assert.True(t, entry.Sampled)
+	assert.Equal(t, []string{"b", "d"}, entry.Value)
+
+	entry, err = exporter.processKey(context.Background(), "board")
+	require.NoError(t, err)
+	assert.True(t, entry.Sampled)
+	assert.Len(t, entry.Value, 2)
+
+	entry, err = exporter.processKey(context.Background(), "user")
+	require.NoError(t, err)
+	assert.False(t, entry.Sampled, "a hash of exactly the sample size is complete")
+	assert.Equal(t, map[string]string{"name": "ada", "age": "36"}, entry.Value)
+
+	entry, err = exporter.processKey(context.Background(), "small")
+	require.NoError(t, err)
+	assert.False(t, entry.Sampled, "SCARD is skipped for sets under the sample size")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
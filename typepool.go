@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// poolTypes are the types that can be given a dedicated worker pool with
+// --workers-<type>. Keys of other types, and of types without a pool, are
+// handled by the --workers pool.
+var poolTypes = []string{"string", "list", "set", "zset", "hash", "stream"}
+
+// typeWorkerFlags holds the value of each --workers-<type> flag.
+var typeWorkerFlags = make(map[string]*int, len(poolTypes))
+
+// typeWorkers returns the dedicated worker count of each type whose
+// --workers-<type> flag is above 0.
+func typeWorkers() (map[string]int, error) {
+	pools := make(map[string]int)
+	for _, t := range poolTypes {
+		n := *typeWorkerFlags[t]
+		if n < 0 {
+			return nil, fmt.Errorf("--workers-%s must not be negative", t)
+		}
+		if n > 0 {
+			pools[t] = n
+		}
+	}
+	return pools, nil
+}
+
+// totalWorkers returns the number of workers across the --workers pool and
+// any per-type pools.
+func (e *Exporter) totalWorkers() int {
+	total := e.config.Workers
+	for _, n := range e.config.TypeWorkers {
+		total += n
+	}
+	return total
+}
+
+// classifyKeys looks up the type of each key from keysChan and sends it to
+// the pool for that type, or to shared when the type has no pool of its own.
+// Types are looked up in pipelined batches of whatever keys are waiting, so
+// the lookup costs one round trip per batch rather than per key. Every
+// channel is closed once keysChan is drained or ctx is done.
+func (e *Exporter) classifyKeys(ctx context.Context, keysChan <-chan keyTask, pools map[string]chan keyTask, shared chan keyTask) {
+	defer func() {
+		for _, ch := range pools {
+			close(ch)
+		}
+		close(shared)
+	}()
+
+	batch := make([]keyTask, 0, e.config.BatchSize)
+	for task := range keysChan {
+		batch = append(batch[:0], task)
+	fill:
+		for len(batch) < cap(batch) {
+			select {
+			case task, ok := <-keysChan:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, task)
+			default:
+				break fill
+			}
+		}
+
+		e.lookupTypes(ctx, batch)
+		for _, task := range batch {
+			ch, ok := pools[task.keyType]
+			if !ok {
+				ch = shared
+			}
+			select {
+			case ch <- task:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// lookupTypes sets the type of each task with one pipelined TYPE per key.
+// Tasks whose lookup failed keep an empty type, so the worker looks it up
+// again and reports the error against the key.
+func (e *Exporter) lookupTypes(ctx context.Context, batch []keyTask) {
+	pipe := e.client.Pipeline()
+	cmds := make([]*redis.StatusCmd, len(batch))
+	for i, task := range batch {
+		cmds[i] = pipe.Type(ctx, task.key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithError(err).Debug("Failed to look up the type of some keys, leaving them to the workers")
+	}
+	for i, cmd := range cmds {
+		if keyType, err := cmd.Result(); err == nil {
+			batch[i].keyType = keyType
+		}
+	}
+}
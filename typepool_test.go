@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeWorkers(t *testing.T) {
+	t.Cleanup(func() {
+		for _, n := range typeWorkerFlags {
+			*n = 0
+		}
+	})
+
+	pools, err := typeWorkers()
+	require.NoError(t, err)
+	assert.Empty(t, pools)
+
+	*typeWorkerFlags["hash"] = 4
+	*typeWorkerFlags["stream"] = 1
+	pools, err = typeWorkers()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"hash": 4, "stream": 1}, pools)
+
+	*typeWorkerFlags["zset"] = -1
+	_, err = typeWorkers()
+	assert.ErrorContains(t, err, "--workers-zset")
+}
+
+func TestExporter_ClassifyKeys(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db, config: Config{BatchSize: 10}}
+
+	types := map[string]string{
+		"name":    "string",
+		"profile": "hash",
+		"queue":   "list",
+		"doc":     "ReJSON-RL",
+		"gone":    "none",
+	}
+	keys := []string{"name", "profile", "queue", "doc", "gone"}
+	keysChan := make(chan keyTask, len(keys))
+	for _, key := range keys {
+		keysChan <- keyTask{key: key}
+		mock.ExpectType(key).SetVal(types[key])
+	}
+	close(keysChan)
+
+	pools := map[string]chan keyTask{
+		"string": make(chan keyTask, len(keys)),
+		"hash":   make(chan keyTask, len(keys)),
+	}
+	shared := make(chan keyTask, len(keys))
+	exporter.classifyKeys(context.Background(), keysChan, pools, shared)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	collect := func(ch chan keyTask) map[string]string {
+		routed := make(map[string]string)
+		for task := range ch {
+			routed[task.key] = task.keyType
+		}
+		return routed
+	}
+	assert.Equal(t, map[string]string{"name": "string"}, collect(pools["string"]))
+	assert.Equal(t, map[string]string{"profile": "hash"}, collect(pools["hash"]))
+	assert.Equal(t, map[string]string{"queue": "list", "doc": "ReJSON-RL", "gone": "none"}, collect(shared),
+		"types without a pool go to the shared pool")
+}
+
+func TestExporter_ClassifyKeys_LookupFailure(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+
+	exporter := &Exporter{client: db, config: Config{BatchSize: 10}}
+
+	keysChan := make(chan keyTask, 1)
+	keysChan <- keyTask{key: "flaky"}
+	close(keysChan)
+	mock.ExpectType("flaky").SetErr(assert.AnError)
+
+	pools := map[string]chan keyTask{"string": make(chan keyTask, 1)}
+	shared := make(chan keyTask, 1)
+	exporter.classifyKeys(context.Background(), keysChan, pools, shared)
+
+	task, ok := <-shared
+	require.True(t, ok)
+	assert.Equal(t, "flaky", task.key)
+	assert.Empty(t, task.keyType, "the worker looks the type up again")
+}
+
+func TestExporter_Export_TypeWorkers(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	config := Config{
+		OutputFile:  filepath.Join(t.TempDir(), "export.json"),
+		Workers:     1,
+		BatchSize:   10,
+		TypeWorkers: map[string]int{"hash": 2},
+		WorkerStats: true,
+	}
+	exporter := &Exporter{client: db, config: config}
+
+	mock.ExpectScan(0, "*", int64(10)).SetVal([]string{"a", "b", "h1", "h2", "h3"}, 0)
+	// Each key's type is looked up once, by the classifier
+	for _, key := range []string{"a", "b"} {
+		mock.ExpectType(key).SetVal("string")
+		mock.ExpectGet(key).SetVal("value-" + key)
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+	for _, key := range []string{"h1", "h2", "h3"} {
+		mock.ExpectType(key).SetVal("hash")
+		mock.ExpectHGetAll(key).SetVal(map[string]string{"f": key})
+		mock.ExpectTTL(key).SetVal(-1 * time.Second)
+	}
+
+	require.NoError(t, exporter.Export(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// Worker 0 is the --workers pool, 1 and 2 are the hash pool
+	require.Len(t, exporter.workerStats, 3)
+	assert.Equal(t, int64(2), exporter.workerStats[0].keys.Load())
+	assert.Equal(t, int64(3), exporter.workerStats[1].keys.Load()+exporter.workerStats[2].keys.Load())
+
+	data, err := os.ReadFile(config.OutputFile)
+	require.NoError(t, err)
+	var entries []RedisEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	assert.Len(t, entries, 5)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// VerifyResult summarises a verified export file.
+type VerifyResult struct {
+	Entries int
+	Corrupt []CorruptRecord
+}
+
+// CorruptRecord describes an entry that failed verification.
+type CorruptRecord struct {
+	Index int
+	Key   string
+	Err   string
+}
+
+// verifyExport streams the JSON export in r, an array or one object per
+// line, and checks that every entry has a known type and a value of the
+// expected shape. A syntax error or truncation stops verification and is
+// returned as an error alongside the partial result.
+func verifyExport(r io.Reader) (*VerifyResult, error) {
+	result := &VerifyResult{}
+	dec, err := newJSONEntryReader(r)
+	if err != nil {
+		return result, err
+	}
+
+	for index := 0; dec.More(); index++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return result, fmt.Errorf("failed to decode entry %d: %w", index, err)
+		}
+
+		var entry RedisEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			result.Corrupt = append(result.Corrupt, CorruptRecord{Index: index, Err: err.Error()})
+			continue
+		}
+
+		if err := verifyEntry(&entry); err != nil {
+			result.Corrupt = append(result.Corrupt, CorruptRecord{Index: index, Key: entry.Key, Err: err.Error()})
+			continue
+		}
+
+		result.Entries++
+	}
+
+	if err := dec.Close(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// verifyEntry checks that entry.Value has the shape produced for entry.Type.
+func verifyEntry(entry *RedisEntry) error {
+	if entry.Key == "" {
+		return fmt.Errorf("missing key")
+	}
+
+	if entry.Value == nil {
+		// Metadata-only entry from --keys-only
+		return nil
+	}
+
+	switch entry.Type {
+	case "string":
+		if _, ok := stringValue(entry.Value); !ok {
+			return fmt.Errorf("string value is not a string")
+		}
+	case "list", "set":
+		if !isStringArray(entry.Value) {
+			return fmt.Errorf("%s value is not an array of strings", entry.Type)
+		}
+	case "hash":
+		if ordered, ok := entry.Value.([]interface{}); ok {
+			// --ordered-hashes exports an array of {field, value} objects
+			for _, f := range ordered {
+				field, ok := f.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("hash field is not an object")
+				}
+				if _, ok := field["field"].(string); !ok {
+					return fmt.Errorf("hash field has no name")
+				}
+				if _, ok := stringValue(field["value"]); !ok {
+					return fmt.Errorf("hash field %v is not a string", field["field"])
+				}
+			}
+			return nil
+		}
+		fields, ok := entry.Value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("hash value is not an object")
+		}
+		for field, value := range fields {
+			if _, ok := stringValue(value); !ok {
+				return fmt.Errorf("hash field %q is not a string", field)
+			}
+		}
+	case "zset":
+		members, ok := entry.Value.([]interface{})
+		if !ok {
+			return fmt.Errorf("zset value is not an array")
+		}
+		for _, m := range members {
+			member, ok := m.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("zset member is not an object")
+			}
+			if _, ok := member["Score"].(float64); !ok {
+				return fmt.Errorf("zset member has no numeric score")
+			}
+			if _, ok := member["Member"]; !ok {
+				return fmt.Errorf("zset member has no member")
+			}
+		}
+	case "stream":
+		messages, ok := entry.Value.([]interface{})
+		if !ok {
+			return fmt.Errorf("stream value is not an array")
+		}
+		for _, m := range messages {
+			message, ok := m.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("stream message is not an object")
+			}
+			if _, ok := message["ID"].(string); !ok {
+				return fmt.Errorf("stream message has no ID")
+			}
+		}
+	case rejsonType:
+		// Any JSON document is valid
+	default:
+		// Other module types are exported as base64-encoded DUMP payloads
+		dump, ok := entry.Value.(string)
+		if !ok {
+			return fmt.Errorf("unknown key type: %s", entry.Type)
+		}
+		if _, err := base64.StdEncoding.DecodeString(dump); err != nil {
+			return fmt.Errorf("%s value is not a base64 DUMP payload", entry.Type)
+		}
+	}
+
+	return nil
+}
+
+func isStringArray(value interface{}) bool {
+	items, ok := value.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if _, ok := item.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Validate an export file without connecting to Redis",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open export file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+
+		result, err := verifyExport(file)
+		for _, record := range result.Corrupt {
+			logrus.WithFields(logrus.Fields{
+				"index": record.Index,
+				"key":   record.Key,
+			}).Error("Corrupt record: ", record.Err)
+		}
+		if err != nil {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"valid_entries":   result.Entries,
+			"corrupt_entries": len(result.Corrupt),
+		}).Info("Verification finished")
+
+		if len(result.Corrupt) > 0 {
+			return fmt.Errorf("%d corrupt records found", len(result.Corrupt))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
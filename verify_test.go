@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyExport_Valid(t *testing.T) {
+	export := `[
+{"key":"s","type":"string","value":"hello","ttl":60}
+,
+{"key":"l","type":"list","value":["a","b"]}
+,
+{"key":"h","type":"hash","value":{"f":"v"}}
+,
+{"key":"z","type":"zset","value":[{"Score":1,"Member":"m"}]}
+,
+{"key":"x","type":"stream","value":[{"ID":"1-0","Values":{"f":"v"}}]}
+]`
+
+	result, err := verifyExport(strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Entries)
+	assert.Empty(t, result.Corrupt)
+}
+
+func TestVerifyExport_CorruptRecords(t *testing.T) {
+	export := `[
+{"key":"ok","type":"string","value":"hello"},
+{"key":"bad-list","type":"list","value":"not a list"},
+{"key":"bad-type","type":"bogus","value":1}
+]`
+
+	result, err := verifyExport(strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Entries)
+	require.Len(t, result.Corrupt, 2)
+	assert.Equal(t, "bad-list", result.Corrupt[0].Key)
+	assert.Equal(t, 1, result.Corrupt[0].Index)
+	assert.Equal(t, "bad-type", result.Corrupt[1].Key)
+	assert.Contains(t, result.Corrupt[1].Err, "unknown key type")
+}
+
+func TestVerifyExport_Truncated(t *testing.T) {
+	export := `[
+{"key":"ok","type":"string","value":"hello"}
+,
+{"key":"cut","type":"stri`
+
+	result, err := verifyExport(strings.NewReader(export))
+	assert.Error(t, err)
+	assert.Equal(t, 1, result.Entries)
+}
+
+func TestVerifyExport_Unwrapped(t *testing.T) {
+	export := `
+{"key":"s","type":"string","value":"hello","ttl":60}
+{"key":"bad-list","type":"list","value":"not a list"}
+{"key":"h","type":"hash","value":{"f":"v"}}
+`
+
+	result, err := verifyExport(strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Entries)
+	require.Len(t, result.Corrupt, 1)
+	assert.Equal(t, "bad-list", result.Corrupt[0].Key)
+
+	_, err = verifyExport(strings.NewReader(`{"key":"s","type":"string","value":"hello"}` + "\n" + `{"key":"cut","ty`))
+	assert.Error(t, err, "a cut last line is reported")
+
+	_, err = verifyExport(strings.NewReader(`"not an export"`))
+	assert.ErrorContains(t, err, "not a JSON array or one JSON object per line")
+}
+
+func TestVerifyExport_KeysOnly(t *testing.T) {
+	export := `[
+{"key":"s","type":"string","ttl":60},
+{"key":"h","type":"hash"}
+]`
+
+	result, err := verifyExport(strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Entries)
+	assert.Empty(t, result.Corrupt)
+}
+
+func TestVerifyExport_OrderedHash(t *testing.T) {
+	export := `[
+{"key":"h","type":"hash","value":[{"field":"a","value":"1"},{"field":"b","value":"2"}]},
+{"key":"bad","type":"hash","value":[{"field":"a"}]}
+]`
+
+	result, err := verifyExport(strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Entries)
+	require.Len(t, result.Corrupt, 1)
+	assert.Equal(t, "bad", result.Corrupt[0].Key)
+}
+
+func TestVerifyExport_TTLFormats(t *testing.T) {
+	export := `[
+{"key":"a","type":"string","value":"x","ttl":60},
+{"key":"b","type":"string","value":"x","ttl":"1h0m0s"},
+{"key":"c","type":"string","value":"x","ttl":"2099-01-01T00:00:00Z"},
+{"key":"d","type":"string","value":"x","ttl":"soon"}
+]`
+
+	result, err := verifyExport(strings.NewReader(export))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Entries)
+	require.Len(t, result.Corrupt, 1)
+	assert.Equal(t, 3, result.Corrupt[0].Index)
+}